@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"craft-launcher/launcher/auth"
+	"fmt"
+
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// ListAccounts returns the signed-in accounts
+func (a *App) ListAccounts() ([]auth.Profile, error) {
+	accounts, err := a.accounts.List()
+	if err != nil {
+		return nil, err
+	}
+	profiles := make([]auth.Profile, 0, len(accounts))
+	for _, acc := range accounts {
+		profiles = append(profiles, acc.Profile)
+	}
+	return profiles, nil
+}
+
+// LoginMicrosoft signs in with a Microsoft account and plays with it from
+// now on. The code the player must enter is sent as an "auth-prompt" event.
+func (a *App) LoginMicrosoft() (auth.Profile, error) {
+	ctx, cancel := context.WithCancel(a.ctx)
+	a.loginLock.Lock()
+	if a.loginCancel != nil {
+		a.loginCancel() // Only one sign in at a time
+	}
+	a.loginCancel = cancel
+	a.loginLock.Unlock()
+	defer cancel()
+
+	acc, err := a.microsoft.Login(ctx, func(p auth.Prompt) {
+		wailsruntime.EventsEmit(a.ctx, "auth-prompt", p)
+	})
+	if err != nil {
+		return auth.Profile{}, err
+	}
+	return a.addAccount(acc)
+}
+
+// LoginYggdrasil signs in to a community auth server and plays with that
+// account from now on. server may be the site address or its API root.
+func (a *App) LoginYggdrasil(server, username, password string) (auth.Profile, error) {
+	acc, err := a.yggdrasil.Login(a.ctx, server, username, password)
+	if err != nil {
+		return auth.Profile{}, err
+	}
+	return a.addAccount(acc)
+}
+
+// addAccount stores a signed-in account and selects it
+func (a *App) addAccount(acc *auth.Account) (auth.Profile, error) {
+	if err := a.accounts.Put(*acc); err != nil {
+		return auth.Profile{}, err
+	}
+	return acc.Profile, a.SelectAccount(acc.ID)
+}
+
+// CancelLogin stops a sign in that is waiting for the player
+func (a *App) CancelLogin() {
+	a.loginLock.Lock()
+	defer a.loginLock.Unlock()
+	if a.loginCancel != nil {
+		a.loginCancel()
+		a.loginCancel = nil
+	}
+}
+
+// SelectAccount chooses the account to play with, "" plays offline
+func (a *App) SelectAccount(id string) error {
+	if id != "" {
+		if _, err := a.accounts.Get(id); err != nil {
+			return err
+		}
+	}
+	prefs, err := a.settings.Load()
+	if err != nil {
+		return fmt.Errorf("can't update settings: %w", err)
+	}
+	prefs.Account = id
+	return a.settings.Save(prefs)
+}
+
+// RemoveAccount signs out and forgets an account's tokens
+func (a *App) RemoveAccount(id string) error {
+	if err := a.accounts.Remove(id); err != nil {
+		return err
+	}
+	if a.prefs().Account == id {
+		return a.SelectAccount("")
+	}
+	return nil
+}
+
+// account loads an account for launching, renewing its token if needed
+func (a *App) account(id string) (*auth.Account, error) {
+	acc, err := a.accounts.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !acc.Expired() {
+		return acc, nil
+	}
+
+	authenticator, ok := a.authenticators[acc.Type]
+	if !ok {
+		return nil, fmt.Errorf("unsupported account type %q", acc.Type)
+	}
+	refreshed, err := authenticator.Refresh(a.ctx, acc)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.accounts.Put(*refreshed); err != nil {
+		fmt.Printf("Warning: Failed to save refreshed account: %v\n", err)
+	}
+	return refreshed, nil
+}
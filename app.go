@@ -3,182 +3,576 @@ package main
 import (
 	"context"
 	"craft-launcher/launcher"
+	"craft-launcher/launcher/auth"
+	"craft-launcher/launcher/cache"
+	"craft-launcher/launcher/crash"
+	"craft-launcher/launcher/diagnostics"
+	"craft-launcher/launcher/display"
+	"craft-launcher/launcher/gamelog"
+	"craft-launcher/launcher/instance"
 	"craft-launcher/launcher/integrity"
+	"craft-launcher/launcher/ram"
+	"craft-launcher/launcher/repair"
+	"craft-launcher/launcher/session"
+	"craft-launcher/launcher/settings"
+	"craft-launcher/launcher/share"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"sync"
+	"time"
 
-	"github.com/pbnjay/memory"
 	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
 // App struct
 type App struct {
-	ctx     context.Context
-	cmd     *exec.Cmd
-	cmdLock sync.Mutex
+	ctx       context.Context
+	sessions  *session.Registry
+	settings  *settings.Store
+	instances *instance.Manager
+	cache     *cache.Store
+	logs      *gamelog.Manager
+	history   *diagnostics.History
+	accounts  *auth.Store
+
+	microsoft      *auth.Microsoft
+	yggdrasil      *auth.Yggdrasil
+	authenticators map[string]auth.Authenticator
+	loginCancel    context.CancelFunc
+	loginLock      sync.Mutex
+
+	prepareLocks map[string]*sync.Mutex // Per instance, see gameRun
+	prepareMu    sync.Mutex
 }
 
 // NewApp creates a new App application struct
 func NewApp() *App {
-	return &App{}
+	a := &App{
+		sessions:     session.NewRegistry(),
+		microsoft:    auth.NewMicrosoft(),
+		yggdrasil:    auth.NewYggdrasil(),
+		prepareLocks: make(map[string]*sync.Mutex),
+	}
+	a.authenticators = map[string]auth.Authenticator{
+		auth.TypeMicrosoft: a.microsoft,
+		auth.TypeYggdrasil: a.yggdrasil,
+	}
+	return a
 }
 
 // SystemInfo holds system information for the frontend
 type SystemInfo struct {
-	TotalRAM   uint64 `json:"totalRAM"`   // Total RAM in MiB
-	Is32Bit    bool   `json:"is32Bit"`    // Whether running 32-bit
-	DefaultRAM int    `json:"defaultRAM"` // Default RAM allocation in MiB
-	MinRAM     int    `json:"minRAM"`     // Minimum RAM in MiB
-	MaxRAM     int    `json:"maxRAM"`     // Maximum RAM in MiB
+	TotalRAM       uint64        `json:"totalRAM"`       // Total RAM in MiB
+	FreeRAM        uint64        `json:"freeRAM"`        // RAM available right now in MiB
+	Is32Bit        bool          `json:"is32Bit"`        // Whether the game's Java is 32-bit
+	DefaultRAM     int           `json:"defaultRAM"`     // Default RAM allocation in MiB
+	MinRAM         int           `json:"minRAM"`         // Minimum RAM in MiB
+	MaxRAM         int           `json:"maxRAM"`         // Maximum RAM in MiB
+	RecommendedRAM int           `json:"recommendedRAM"` // What the pack runs well with in MiB
+	Warnings       []ram.Warning `json:"warnings"`
 }
 
 // startup is called when the app starts. The context is saved
 // so we can call the runtime methods
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
+	a.openStores()
 }
 
-// GetSystemInfo returns system information for RAM configuration
-func (a *App) GetSystemInfo() SystemInfo {
-	totalRAM := memory.TotalMemory() / (1024 * 1024) // Convert to MiB
-	is32Bit := runtime.GOARCH == "386"
+// openStores opens the settings, instances, logs and accounts under the
+// data dir
+func (a *App) openStores() {
+	gameDir, err := a.gameDir()
+	if err != nil {
+		fmt.Printf("Warning: Error getting exe path: %v, storing settings in the user config dir\n", err)
+	}
+	a.settings = settings.NewStore(settings.Dir(gameDir))
+	a.instances = instance.NewManager(settings.Dir(gameDir), gamelog.DirName, diagnostics.HistoryFile, repair.QuarantineDir, launcher.AuthlibInjectorDir)
+	a.cache = cache.NewStore(cache.Dir())
+	a.logs = gamelog.NewManager(filepath.Join(settings.Dir(gameDir), gamelog.DirName))
+	a.history = diagnostics.NewHistory(settings.Dir(gameDir))
+	a.accounts = auth.NewStore(settings.Dir(gameDir), auth.KeyDir())
+}
 
-	var defaultRAM, minRAM, maxRAM int
+// gameDir returns the portable data directory next to the executable
+func (a *App) gameDir() (string, error) {
+	exePath, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(exePath), "data"), nil
+}
+
+// GetSettings returns the saved launcher settings (defaults on first run).
+// If the file can't be read, saving is refused until ResetSettings.
+func (a *App) GetSettings() (settings.Settings, error) {
+	return a.settings.Load()
+}
+
+// SaveSettings persists the launcher settings
+func (a *App) SaveSettings(s settings.Settings) error {
+	return a.settings.Save(s)
+}
 
-	if is32Bit {
-		// 32-bit Windows has strict limitations
-		defaultRAM = 1024 // 1 GiB
-		minRAM = 256      // 0.25 GiB
-		maxRAM = 1024     // 1 GiB
-	} else {
-		// 64-bit systems
-		defaultRAM = 2048      // 2 GiB
-		minRAM = 2048          // 2 GiB
-		maxRAM = int(totalRAM) // System max
+// ResetSettings replaces a settings file that can't be read with defaults
+func (a *App) ResetSettings() (settings.Settings, error) {
+	s := settings.Defaults()
+	return s, a.settings.Overwrite(s)
+}
+
+// prefs returns the settings to launch with, defaults if they can't be read
+func (a *App) prefs() settings.Settings {
+	s, err := a.settings.Load()
+	if err != nil {
+		fmt.Printf("Warning: Failed to load settings: %v\n", err)
+	}
+	return s
+}
+
+// GetSystemInfo returns system information for RAM configuration of the
+// default instance
+func (a *App) GetSystemInfo() SystemInfo {
+	inst, err := a.getInstance(instance.DefaultID)
+	if err != nil {
+		fmt.Printf("Warning: Failed to load default instance: %v\n", err)
 	}
+	return a.systemInfo(inst)
+}
 
+// systemInfo recommends RAM for an instance
+func (a *App) systemInfo(inst instance.Instance) SystemInfo {
+	in := a.ramInputs(inst)
+	r := ram.Recommend(in)
 	return SystemInfo{
-		TotalRAM:   totalRAM,
-		Is32Bit:    is32Bit,
-		DefaultRAM: defaultRAM,
-		MinRAM:     minRAM,
-		MaxRAM:     maxRAM,
+		TotalRAM:       in.TotalMB,
+		FreeRAM:        in.FreeMB,
+		Is32Bit:        !in.Java64Bit,
+		DefaultRAM:     r.DefaultMB,
+		MinRAM:         r.MinMB,
+		MaxRAM:         r.MaxMB,
+		RecommendedRAM: r.RecommendedMB,
+		Warnings:       r.Warnings,
 	}
 }
 
-// LaunchGame starts the game
-func (a *App) LaunchGame(username string, ramMB int, useFabric bool, serverURL string) string {
-	a.cmdLock.Lock()
-	if a.cmd != nil {
-		a.cmdLock.Unlock()
-		return "Game is already running!"
+// ramInputs gathers free memory, an instance's Java and its modpack's
+// requirements for a RAM recommendation
+func (a *App) ramInputs(inst instance.Instance) ram.Inputs {
+	in := ram.Inputs{
+		TotalMB:   ram.TotalMB(),
+		FreeMB:    ram.FreeMB(),
+		Java64Bit: runtime.GOARCH != "386", // The bundled JRE matches the launcher
 	}
-	a.cmdLock.Unlock()
+	javaPath := inst.JavaPath
+	if javaPath == "" {
+		javaPath = launcher.FindJava(a.instances.SharedDir())
+	}
+	if javaPath != "" {
+		if is64, err := launcher.Java64Bit(javaPath); err == nil {
+			in.Java64Bit = is64
+		}
+	}
+	if inst.GameDir != "" {
+		if manifest, err := integrity.LoadLocalManifest(inst.GameDir); err == nil {
+			in.PackMinMB = manifest.MinRAM
+			in.PackRecommended = manifest.RecommendedRAM
+		}
+	}
+	return in
+}
 
-	// Portable: Use the directory of the executable
-	exePath, err := os.Executable()
+// LaunchGame starts the default instance
+func (a *App) LaunchGame(username string, ramMB int, useFabric bool, serverURL string, window display.Window) string {
+	inst, err := a.instances.Get(instance.DefaultID)
 	if err != nil {
-		return fmt.Sprintf("Error getting exe path: %v", err)
+		return fmt.Sprintf("Error loading instance: %v", err)
 	}
-	exeDir := filepath.Dir(exePath)
 
-	gameDir := filepath.Join(exeDir, "data")
-	if err := os.MkdirAll(gameDir, 0755); err != nil {
-		return fmt.Sprintf("Error creating game dir: %v", err)
+	// Fill anything the caller left empty from the saved settings
+	prefs, loadErr := a.settings.Load()
+	if loadErr != nil {
+		fmt.Printf("Warning: Failed to load settings: %v\n", loadErr)
+	}
+	if username == "" {
+		username = prefs.Username
+	}
+	if ramMB <= 0 {
+		ramMB = prefs.RamMB
+	}
+	if serverURL == "" {
+		serverURL = prefs.ServerURL
+	}
+
+	// Don't remember a name the game would reject
+	if err := launcher.ValidateUsername(username); err != nil && prefs.Account == "" {
+		msg := fmt.Sprintf("Error: %v", err)
+		wailsruntime.EventsEmit(a.ctx, "update-status", msg, "", instance.DefaultID)
+		return msg
 	}
 
-	// Validate RAM allocation
-	sysInfo := a.GetSystemInfo()
-	if ramMB < sysInfo.MinRAM {
-		ramMB = sysInfo.MinRAM
+	// Remember what was used for next time, unless that would overwrite a
+	// newer or damaged settings file with defaults
+	prefs.Username = username
+	prefs.RamMB = ramMB
+	prefs.UseFabric = useFabric
+	prefs.ServerURL = serverURL
+	if loadErr == nil {
+		if err := a.settings.Save(prefs); err != nil {
+			fmt.Printf("Warning: Failed to save settings: %v\n", err)
+		}
 	}
-	if ramMB > sysInfo.MaxRAM {
-		ramMB = sysInfo.MaxRAM
+
+	if err := window.Check(); err != nil {
+		msg := fmt.Sprintf("Error: %v", err)
+		wailsruntime.EventsEmit(a.ctx, "update-status", msg, "", instance.DefaultID)
+		return msg
 	}
 
-	// Integrity Check & Remote Update
-	statusCallback := func(msg string) {
-		wailsruntime.EventsEmit(a.ctx, "update-status", msg)
+	inst.RamMB = ramMB
+	inst.ServerURL = serverURL
+	inst.Loader = instance.LoaderVanilla
+	if useFabric {
+		inst.Loader = instance.LoaderFabric
 	}
 
-	if err := integrity.CheckAndUpdate(gameDir, serverURL, statusCallback); err != nil {
-		wailsruntime.EventsEmit(a.ctx, "update-status", fmt.Sprintf("Update Error: %v", err))
-		return fmt.Sprintf("Update Error: %v", err)
+	// The size is only kept for next time if the player asked for that
+	stored, err := a.instances.Get(instance.DefaultID)
+	if err == nil {
+		stored.Window = window
+		if !window.RememberSize {
+			stored.Window.Width, stored.Window.Height = 0, 0
+		}
+		err = a.instances.Update(stored)
+	}
+	if err != nil {
+		fmt.Printf("Warning: Failed to save window settings: %v\n", err)
 	}
+	inst.Window = window
+	return a.launchInstance(inst, username)
+}
+
+// ExitEvent is emitted as "game-exited" when a session's process ends
+type ExitEvent struct {
+	SessionID  string `json:"sessionId"`
+	InstanceID string `json:"instanceId"`
+	ExitCode   int    `json:"exitCode"`
+	Error      string `json:"error"`   // Empty on a clean exit
+	Stopped    bool   `json:"stopped"` // Ended by StopGame or ForceStopGame
+	LogFile    string `json:"logFile"` // Session log, for ShareLog
+}
 
-	opts := launcher.LaunchOptions{
-		Username:  username,
-		GameDir:   gameDir,
-		RamMB:     ramMB,
-		VersionID: "1.8.9",
-		UseFabric: useFabric,
-		StatusCallback: func(status string) {
-			wailsruntime.EventsEmit(a.ctx, "update-status", status)
+// launchInstance runs the integrity check and starts the game for inst in a
+// new session. Several sessions may run at once, even of the same instance.
+func (a *App) launchInstance(inst instance.Instance, username string) string {
+	// Every event carries the session ID so the UI can tell clients apart,
+	// and status updates the instance, which has its own Launch/Stop button
+	run, err := a.prepareLaunch(inst, launchRequest{
+		Username:   username,
+		UseAccount: true,
+		Status: func(sessionID, msg string) {
+			wailsruntime.EventsEmit(a.ctx, "update-status", msg, sessionID, inst.ID)
 		},
-		LogCallback: func(data string) {
-			wailsruntime.EventsEmit(a.ctx, "log-data", data)
+		Output: func(sessionID, data string) {
+			wailsruntime.EventsEmit(a.ctx, "log-data", data, sessionID)
 		},
+		Record: func(sessionID string, rec gamelog.Record) {
+			wailsruntime.EventsEmit(a.ctx, "log-record", rec, sessionID)
+		},
+	})
+	if err != nil {
+		wailsruntime.EventsEmit(a.ctx, "update-status", err.Error(), "", inst.ID)
+		return err.Error()
 	}
 
 	go func() {
-		// Log platform information
-		wailsruntime.EventsEmit(a.ctx, "update-status", "=== PLATFORM INFO ===")
-		wailsruntime.EventsEmit(a.ctx, "update-status", fmt.Sprintf("OS: %s", runtime.GOOS))
-		wailsruntime.EventsEmit(a.ctx, "update-status", fmt.Sprintf("Architecture: %s", runtime.GOARCH))
-		wailsruntime.EventsEmit(a.ctx, "update-status", fmt.Sprintf("Username: %s", username))
-		wailsruntime.EventsEmit(a.ctx, "update-status", fmt.Sprintf("RAM Allocation: %d GiB (%d MiB)", ramMB/1024, ramMB))
-		wailsruntime.EventsEmit(a.ctx, "update-status", fmt.Sprintf("System RAM: %d GiB (%d MiB)", sysInfo.TotalRAM/1024, sysInfo.TotalRAM))
-		wailsruntime.EventsEmit(a.ctx, "update-status", "Version: 1.8.9")
-		wailsruntime.EventsEmit(a.ctx, "update-status", "=====================")
-
-		fmt.Printf("Starting launch for %s...\n", username)
-
-		// Launch and store command
-		cmd, err := launcher.Launch(opts)
+		defer run.close()
+		started, err := run.start()
 		if err != nil {
-			fmt.Printf("Error launching: %v\n", err)
-			wailsruntime.EventsEmit(a.ctx, "update-status", fmt.Sprintf("Error: %v", err))
+			wailsruntime.EventsEmit(a.ctx, "update-status", err.Error(), started.ID, inst.ID)
 			return
 		}
+		wailsruntime.EventsEmit(a.ctx, "game-started", started)
 
-		a.cmdLock.Lock()
-		a.cmd = cmd
-		a.cmdLock.Unlock()
-
-		wailsruntime.EventsEmit(a.ctx, "update-status", "Running")
-
-		// Wait for game to exit
-		if err := cmd.Wait(); err != nil {
-			fmt.Printf("Game process exited with error: %v\n", err)
-			wailsruntime.EventsEmit(a.ctx, "update-status", "Crashed")
+		result := run.wait()
+		exit := ExitEvent{
+			SessionID:  started.ID,
+			InstanceID: inst.ID,
+			ExitCode:   result.ExitCode,
+			Stopped:    result.Stopped,
+			LogFile:    run.logName(),
+		}
+		if result.Err != nil {
+			exit.Error = result.Err.Error()
+		}
+		wailsruntime.EventsEmit(a.ctx, "game-exited", exit)
+		if result.Crash != nil {
+			wailsruntime.EventsEmit(a.ctx, "game-crashed", result.Crash)
+			run.status("Crashed")
 		} else {
-			fmt.Printf("Game process exited normally\n")
-			wailsruntime.EventsEmit(a.ctx, "update-status", "Ready to Launch")
+			run.status("Ready to Launch")
 		}
-
-		// Cleanup
-		a.cmdLock.Lock()
-		a.cmd = nil
-		a.cmdLock.Unlock()
 	}()
 
 	return "Launching..."
 }
 
-// ForceStopGame kills the running game process
-func (a *App) ForceStopGame() string {
-	a.cmdLock.Lock()
-	defer a.cmdLock.Unlock()
+// OpenCrashFile opens a crash report or JVM error log from a crash event
+func (a *App) OpenCrashFile(path string) error {
+	name := filepath.Base(path)
+	isReport := filepath.Base(filepath.Dir(path)) == crash.CrashReportsDir && strings.HasPrefix(name, "crash-")
+	isJVMLog := strings.HasPrefix(name, "hs_err_pid")
+	if !filepath.IsAbs(path) || !(isReport || isJVMLog) {
+		return fmt.Errorf("not a crash file: %s", path)
+	}
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	url := filepath.ToSlash(path)
+	if !strings.HasPrefix(url, "/") {
+		url = "/" + url // Windows drive paths
+	}
+	wailsruntime.BrowserOpenURL(a.ctx, "file://"+url)
+	return nil
+}
+
+// ListLogs returns the saved session logs, newest first
+func (a *App) ListLogs() ([]gamelog.LogInfo, error) {
+	return a.logs.List()
+}
+
+// ReadLog returns the contents of a saved session log, including the parts
+// rotated out of it
+func (a *App) ReadLog(name string) (string, error) {
+	return a.logs.ReadSession(name)
+}
 
-	if a.cmd != nil && a.cmd.Process != nil {
-		if err := a.cmd.Process.Kill(); err != nil {
-			return fmt.Sprintf("Error killing process: %v", err)
+// ShareLog redacts a session log and the crash files written during it and
+// uploads them to the instance's modpack server. An empty log name shares
+// the newest log.
+func (a *App) ShareLog(instanceID, logName string) (share.Result, error) {
+	if instanceID == "" {
+		instanceID = instance.DefaultID
+	}
+	inst, err := a.getInstance(instanceID)
+	if err != nil {
+		return share.Result{}, err
+	}
+	prefs := a.prefs()
+	serverURL := inst.ServerURL
+	if serverURL == "" {
+		serverURL = prefs.ServerURL
+	}
+	if serverURL == "" {
+		return share.Result{}, fmt.Errorf("no modpack server to upload to")
+	}
+
+	var info gamelog.LogInfo
+	if logName == "" {
+		logs, err := a.logs.List()
+		if err != nil {
+			return share.Result{}, err
+		}
+		if len(logs) == 0 {
+			return share.Result{}, fmt.Errorf("no session logs yet")
+		}
+		info = logs[0]
+	} else if info, err = a.logs.Find(logName); err != nil {
+		return share.Result{}, err
+	}
+	text, err := a.logs.ReadSession(info.Name)
+	if err != nil {
+		return share.Result{}, err
+	}
+
+	files := []share.File{{Name: strings.TrimSuffix(info.Name, ".gz"), Data: text}}
+	if start, ok := gamelog.StartTime(info.Name); ok {
+		end := info.ModTime.Add(time.Minute) // Crash reports can land after the last log line
+		if info.Active {
+			end = time.Now()
+		}
+		for _, path := range share.CrashFiles(inst.GameDir, start, end) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				continue
+			}
+			files = append(files, share.File{Name: filepath.Base(path), Data: string(data)})
+		}
+	}
+
+	bundle, err := share.Bundle(share.NewRedactor(append(share.Usernames(text), prefs.Username)...), files)
+	if err != nil {
+		return share.Result{}, err
+	}
+	return share.Upload(serverURL, bundle)
+}
+
+// ExportDiagnostics asks where to save and writes a zip with everything
+// needed to triage a problem with an instance. It returns the saved path,
+// or "" if the player cancelled.
+func (a *App) ExportDiagnostics(instanceID string) (string, error) {
+	if instanceID == "" {
+		instanceID = instance.DefaultID
+	}
+	inst, err := a.getInstance(instanceID)
+	if err != nil {
+		return "", err
+	}
+
+	path, err := wailsruntime.SaveFileDialog(a.ctx, wailsruntime.SaveDialogOptions{
+		Title:           "Export Diagnostics",
+		DefaultFilename: fmt.Sprintf("diagnostics-%s.zip", time.Now().Format("2006-01-02_15-04-05")),
+		Filters:         []wailsruntime.FileFilter{{DisplayName: "Zip Files (*.zip)", Pattern: "*.zip"}},
+	})
+	if err != nil || path == "" {
+		return "", err
+	}
+
+	sysInfo := a.systemInfo(inst)
+	summary := diagnostics.Summary{
+		GeneratedAt: time.Now(),
+		System: diagnostics.System{
+			OS:       runtime.GOOS,
+			Arch:     runtime.GOARCH,
+			CPUs:     runtime.NumCPU(),
+			TotalRAM: sysInfo.TotalRAM,
+			Is32Bit:  sysInfo.Is32Bit,
+		},
+		Java:     diagnostics.Java{Path: inst.JavaPath},
+		Instance: inst,
+	}
+	if summary.Java.Path == "" {
+		summary.Java.Path = launcher.FindJava(a.instances.SharedDir())
+	}
+	if summary.Java.Path == "" {
+		summary.Java.Error = "bundled Java is not installed yet"
+	} else if summary.Java.Version, err = launcher.JavaVersion(summary.Java.Path); err != nil {
+		summary.Java.Error = err.Error()
+	}
+
+	summary.Manifest, summary.FailedFiles = diagnostics.CheckManifest(inst.GameDir)
+	if summary.Mods, err = diagnostics.ListMods(inst.GameDir); err != nil {
+		fmt.Printf("Warning: Failed to list mods: %v\n", err)
+	}
+	if summary.RecentLaunches, err = a.history.List(); err != nil {
+		fmt.Printf("Warning: Failed to read launch history: %v\n", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	if err := diagnostics.Write(f, summary); err != nil {
+		f.Close()
+		os.Remove(path)
+		return "", err
+	}
+	return path, f.Close()
+}
+
+// RepairInstance re-verifies every file an instance uses, moving bad ones
+// into a quarantine folder and downloading them again. Pack files the
+// player may edit are only checksummed if includeUserFiles is set.
+func (a *App) RepairInstance(instanceID string, includeUserFiles bool) (repair.Report, error) {
+	if instanceID == "" {
+		instanceID = instance.DefaultID
+	}
+	inst, err := a.getInstance(instanceID)
+	if err != nil {
+		return repair.Report{}, err
+	}
+	// Libraries, assets and the JRE are shared by every instance
+	if len(a.sessions.List()) > 0 {
+		return repair.Report{}, fmt.Errorf("close all running games before repairing")
+	}
+
+	report, err := repair.Run(repair.Options{
+		GameDir:          inst.GameDir,
+		SharedDir:        a.instances.SharedDir(),
+		VersionID:        inst.VersionID,
+		UseFabric:        inst.UseFabric(),
+		ServerURL:        inst.ServerURL,
+		JavaPath:         inst.JavaPath,
+		IncludeUserFiles: includeUserFiles,
+		Cache:            a.cache,
+		StatusCallback: func(msg string) {
+			wailsruntime.EventsEmit(a.ctx, "update-status", msg, "", inst.ID)
+		},
+	})
+	if report == nil {
+		wailsruntime.EventsEmit(a.ctx, "update-status", fmt.Sprintf("Repair Error: %v", err), "", inst.ID)
+		return repair.Report{}, err
+	}
+	return *report, err
+}
+
+// ListSessions returns the running and launching game sessions
+func (a *App) ListSessions() []session.Session {
+	return a.sessions.List()
+}
+
+// StopGame asks a session's game to quit so it can save, and kills it if
+// it's still running after the grace period from the settings
+func (a *App) StopGame(sessionID string) string {
+	sess, cmd, ok := a.sessions.Get(sessionID)
+	if !ok || cmd == nil || cmd.Process == nil {
+		return "No game running."
+	}
+	if sess.State == session.StateStopping {
+		return "Already stopping."
+	}
+	if err := a.sessions.MarkStopping(sess.ID); err != nil {
+		return fmt.Sprintf("Error stopping game: %v", err)
+	}
+
+	logs, cancel := a.sessions.Watch(sess.ID)
+	grace := time.Duration(a.prefs().StopGraceSeconds) * time.Second
+
+	go func() {
+		defer cancel()
+		killed, err := launcher.Stop(cmd.Process, launcher.StopOptions{
+			Grace:  grace,
+			Exited: a.sessions.Done(sess.ID),
+			Logs:   logs,
+			StatusCallback: func(msg string) {
+				a.sessions.Status(sess.ID, msg)
+				wailsruntime.EventsEmit(a.ctx, "update-status", msg, sess.ID, sess.InstanceID)
+			},
+		})
+		if err != nil {
+			fmt.Printf("Error stopping session %s: %v\n", sess.ID, err)
+			wailsruntime.EventsEmit(a.ctx, "update-status", fmt.Sprintf("Error stopping game: %v", err), sess.ID, sess.InstanceID)
+			return
 		}
-		return "Force stopped."
+		wailsruntime.EventsEmit(a.ctx, "game-stopped", sess.ID, killed)
+	}()
+
+	return "Stopping..."
+}
+
+// ForceStopGame kills the game process of a session right away
+func (a *App) ForceStopGame(sessionID string) string {
+	sess, cmd, ok := a.sessions.Get(sessionID)
+	if !ok || cmd == nil || cmd.Process == nil {
+		return "No game running."
+	}
+
+	a.sessions.MarkStopping(sess.ID)
+	if err := cmd.Process.Kill(); err != nil {
+		return fmt.Sprintf("Error killing process: %v", err)
 	}
-	return "No game running."
+	wailsruntime.EventsEmit(a.ctx, "game-stopped", sess.ID, true)
+	return "Force stopped."
+}
+
+// PingServer queries a Minecraft server's status (MOTD, version, players, latency)
+func (a *App) PingServer(address string) (*launcher.ServerStatus, error) {
+	return launcher.PingServer(address, 5*time.Second)
+}
+
+// CollectGarbage deletes cached libraries and assets no data dir links to anymore
+func (a *App) CollectGarbage() (cache.GCResult, error) {
+	return a.cache.GC()
 }
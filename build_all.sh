@@ -18,7 +18,15 @@ else
     echo "Warning: .server_url not found. Using default."
 fi
 
-LDFLAGS="-X 'craft-launcher/launcher/integrity.ServerURL=$SERVER_URL'"
+# Read the Azure app ID used for Microsoft sign in
+MS_CLIENT_ID=""
+if [ -f ".ms_client_id" ]; then
+    MS_CLIENT_ID=$(cat .ms_client_id | tr -d '\n\r')
+else
+    echo "Warning: .ms_client_id not found. Microsoft sign in will be disabled."
+fi
+
+LDFLAGS="-X 'craft-launcher/launcher/integrity.ServerURL=$SERVER_URL' -X 'craft-launcher/launcher/auth.ClientID=$MS_CLIENT_ID'"
 
 echo "==========================================="
 echo "Building $APP_NAME for all platforms"
@@ -0,0 +1,393 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+
+	"craft-launcher/launcher"
+	"craft-launcher/launcher/crash"
+	"craft-launcher/launcher/gamelog"
+	"craft-launcher/launcher/instance"
+	"craft-launcher/launcher/integrity"
+	"craft-launcher/launcher/repair"
+)
+
+// CLI exit codes
+const (
+	exitOK           = 0
+	exitError        = 1 // Anything else went wrong
+	exitUsage        = 2 // Unknown command or bad flags
+	exitVerifyFailed = 3 // Managed files are missing or modified
+	exitUpdateFailed = 4 // The modpack server couldn't be synced
+	exitGameCrashed  = 5 // The game exited with an error
+)
+
+const cliUsage = `Usage: craft-launcher <command> [flags]
+
+Commands:
+  launch     Update and start an instance, wait for the game to exit
+  update     Sync an instance with its modpack server
+  verify     Check an instance's modpack files without downloading
+  repair     Re-verify every file an instance uses and download bad ones
+             again, keeping the bad copies in quarantine/
+  java list  List the Java installations the launcher can use
+
+Run "craft-launcher <command> -h" for a command's flags. With -json every
+progress line and the result are printed as one JSON object per line.
+
+Exit codes: 0 ok, 1 error, 2 usage, 3 verification failed,
+4 update failed, 5 game crashed.
+`
+
+// cliCommands run without the GUI
+var cliCommands = map[string]func(*App, *cliOutput, []string) int{
+	"launch": cliLaunch,
+	"update": cliUpdate,
+	"verify": cliVerify,
+	"repair": cliRepair,
+	"java":   cliJava,
+}
+
+// isCLI reports whether the command line asks for a CLI command rather
+// than the GUI
+func isCLI(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	switch args[0] {
+	case "help", "-h", "-help", "--help":
+		return true
+	}
+	_, ok := cliCommands[args[0]]
+	return ok
+}
+
+// runCLI runs a command and returns the process exit code
+func runCLI(args []string) int {
+	run, ok := cliCommands[args[0]]
+	if !ok {
+		fmt.Print(cliUsage) // help, -h
+		return exitOK
+	}
+
+	out := &cliOutput{w: os.Stdout}
+	for _, arg := range args[1:] {
+		if arg == "-json" || arg == "--json" {
+			// The launcher packages print progress to stdout, keep it for JSON
+			out.json = true
+			os.Stdout = os.Stderr
+		}
+	}
+
+	app := NewApp()
+	app.ctx = context.Background()
+	app.openStores()
+	return run(app, out, args[1:])
+}
+
+// cliOutput prints progress and results as text or as JSON lines
+type cliOutput struct {
+	w    io.Writer
+	json bool
+}
+
+// cliEvent is one line of JSON output
+type cliEvent struct {
+	Event   string `json:"event"` // status, log, result or error
+	Message string `json:"message,omitempty"`
+	Data    any    `json:"data,omitempty"`
+}
+
+func (o *cliOutput) emit(e cliEvent) {
+	json.NewEncoder(o.w).Encode(e)
+}
+
+func (o *cliOutput) status(msg string) {
+	if o.json {
+		o.emit(cliEvent{Event: "status", Message: msg})
+		return
+	}
+	fmt.Fprintln(o.w, msg)
+}
+
+// result prints a command's outcome. Text output uses summary.
+func (o *cliOutput) result(data any, summary string) {
+	if o.json {
+		o.emit(cliEvent{Event: "result", Message: summary, Data: data})
+		return
+	}
+	fmt.Fprintln(o.w, summary)
+}
+
+// fail reports err and returns code
+func (o *cliOutput) fail(code int, err error) int {
+	if o.json {
+		o.emit(cliEvent{Event: "error", Message: err.Error(), Data: map[string]int{"exitCode": code}})
+	} else {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}
+	return code
+}
+
+// newFlags makes a flag set with the flags every command shares
+func newFlags(name string, out *cliOutput) (*flag.FlagSet, *string) {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	fs.Bool("json", out.json, "print JSON lines")
+	id := fs.String("instance", instance.DefaultID, "instance ID")
+	return fs, id
+}
+
+// cliInstance parses flags and loads the chosen instance
+func cliInstance(a *App, out *cliOutput, fs *flag.FlagSet, id *string, args []string) (instance.Instance, int) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return instance.Instance{}, exitOK
+		}
+		return instance.Instance{}, exitUsage
+	}
+	inst, err := a.getInstance(*id)
+	if err != nil {
+		return inst, out.fail(exitError, err)
+	}
+	return inst, -1
+}
+
+func cliUpdate(a *App, out *cliOutput, args []string) int {
+	fs, id := newFlags("update", out)
+	inst, code := cliInstance(a, out, fs, id, args)
+	if code >= 0 {
+		return code
+	}
+	if inst.ServerURL == "" {
+		return out.fail(exitError, fmt.Errorf("instance %q has no modpack server", inst.ID))
+	}
+	if err := integrity.CheckAndUpdate(inst.GameDir, inst.ServerURL, out.status); err != nil {
+		return out.fail(exitUpdateFailed, err)
+	}
+	out.result(map[string]string{"instance": inst.ID}, "Up to date.")
+	return exitOK
+}
+
+// verifyResult is the outcome of verify and repair
+type verifyResult struct {
+	Instance string                  `json:"instance"`
+	Files    int                     `json:"files"`
+	Problems []integrity.FileProblem `json:"problems"`
+}
+
+func verifyInstance(out *cliOutput, inst instance.Instance) int {
+	manifest, err := integrity.LoadLocalManifest(inst.GameDir)
+	if err != nil {
+		return out.fail(exitError, fmt.Errorf("instance %q has no modpack manifest, run update first", inst.ID))
+	}
+	problems := integrity.Verify(inst.GameDir, manifest)
+	res := verifyResult{Instance: inst.ID, Files: len(manifest.Files), Problems: problems}
+	if len(problems) == 0 {
+		out.result(res, fmt.Sprintf("All %d files OK.", res.Files))
+		return exitOK
+	}
+
+	if !out.json {
+		for _, p := range problems {
+			fmt.Fprintf(out.w, "%s: %s\n", p.Path, p.Problem)
+		}
+	}
+	out.result(res, fmt.Sprintf("%d of %d files failed verification.", len(problems), res.Files))
+	return exitVerifyFailed
+}
+
+func cliVerify(a *App, out *cliOutput, args []string) int {
+	fs, id := newFlags("verify", out)
+	inst, code := cliInstance(a, out, fs, id, args)
+	if code >= 0 {
+		return code
+	}
+	return verifyInstance(out, inst)
+}
+
+func cliRepair(a *App, out *cliOutput, args []string) int {
+	fs, id := newFlags("repair", out)
+	userFiles := fs.Bool("user-files", false, "also check pack files the player may edit")
+	inst, code := cliInstance(a, out, fs, id, args)
+	if code >= 0 {
+		return code
+	}
+
+	report, err := repair.Run(repair.Options{
+		GameDir:          inst.GameDir,
+		SharedDir:        a.instances.SharedDir(),
+		VersionID:        inst.VersionID,
+		UseFabric:        inst.UseFabric(),
+		ServerURL:        inst.ServerURL,
+		JavaPath:         inst.JavaPath,
+		IncludeUserFiles: *userFiles,
+		Cache:            a.cache,
+		StatusCallback:   out.status,
+	})
+	if report == nil {
+		return out.fail(exitError, err)
+	}
+	if err != nil {
+		out.status(fmt.Sprintf("Warning: %v", err))
+	}
+
+	if !out.json {
+		for _, item := range report.Items {
+			outcome := "repaired"
+			if !item.Repaired {
+				outcome = "FAILED: " + item.Error
+			}
+			fmt.Fprintf(out.w, "%-8s %s: %s, %s\n", item.Kind, item.Path, item.Problem, outcome)
+		}
+	}
+	failed := len(report.Failed())
+	out.result(report, fmt.Sprintf("%d problems found, %d left. Report: %s", len(report.Items), failed, filepath.Join(report.QuarantineDir, repair.ReportFile)))
+	if failed > 0 {
+		return exitVerifyFailed
+	}
+	return exitOK
+}
+
+// launchResult is the outcome of a launch
+type launchResult struct {
+	Instance  string        `json:"instance"`
+	SessionID string        `json:"sessionId"`
+	ExitCode  int           `json:"exitCode"`
+	Crash     *crash.Report `json:"crash,omitempty"`
+}
+
+func cliLaunch(a *App, out *cliOutput, args []string) int {
+	fs, id := newFlags("launch", out)
+	username := fs.String("username", "", "offline player name, defaults to the saved one")
+	ramMB := fs.Int("ram", 0, "heap size in MiB, defaults to the instance's")
+	skipUpdate := fs.Bool("skip-update", false, "don't sync with the modpack server first")
+	inst, code := cliInstance(a, out, fs, id, args)
+	if code >= 0 {
+		return code
+	}
+	if *ramMB > 0 {
+		inst.RamMB = *ramMB
+	}
+
+	// A signed-in account plays unless a player name is given
+	req := launchRequest{
+		Username:   *username,
+		UseAccount: *username == "",
+		SkipUpdate: *skipUpdate,
+		Status: func(_, msg string) {
+			out.status(msg)
+		},
+	}
+	// Launch echoes game output as text, JSON mode gets it as records
+	if out.json {
+		req.Record = func(_ string, rec gamelog.Record) {
+			out.emit(cliEvent{Event: "log", Message: rec.Message, Data: rec})
+		}
+	}
+
+	run, err := a.prepareLaunch(inst, req)
+	if err != nil {
+		var launchErr *launchError
+		if errors.As(err, &launchErr) && launchErr.Stage == stageUpdate {
+			return out.fail(exitUpdateFailed, err)
+		}
+		return out.fail(exitError, err)
+	}
+	defer run.close()
+	started, err := run.start()
+	if err != nil {
+		return out.fail(exitError, err)
+	}
+
+	exit := run.wait()
+	res := launchResult{Instance: inst.ID, SessionID: started.ID, ExitCode: exit.ExitCode, Crash: exit.Crash}
+	if exit.Crash == nil {
+		out.result(res, "Game exited normally.")
+		return exitOK
+	}
+	out.result(res, fmt.Sprintf("Game crashed: %s", exit.Crash.ExitReason))
+	return exitGameCrashed
+}
+
+// javaInstall is a Java executable the launcher found
+type javaInstall struct {
+	Path    string `json:"path"`
+	Source  string `json:"source"` // bundled, instance, JAVA_HOME or PATH
+	Version string `json:"version,omitempty"`
+	Is64Bit bool   `json:"is64Bit"`
+	Error   string `json:"error,omitempty"`
+}
+
+func cliJava(a *App, out *cliOutput, args []string) int {
+	fs := flag.NewFlagSet("java list", flag.ContinueOnError)
+	fs.Bool("json", out.json, "print JSON lines")
+	if len(args) == 0 || args[0] != "list" {
+		fmt.Fprint(os.Stderr, cliUsage)
+		return exitUsage
+	}
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+
+	var installs []javaInstall
+	seen := map[string]bool{}
+	add := func(path, source string) {
+		if path == "" || seen[path] {
+			return
+		}
+		seen[path] = true
+		j := javaInstall{Path: path, Source: source}
+		version, err := launcher.JavaVersion(path)
+		if err == nil {
+			j.Version = version
+			j.Is64Bit, err = launcher.Java64Bit(path)
+		}
+		if err != nil {
+			j.Error = err.Error()
+		}
+		installs = append(installs, j)
+	}
+
+	add(launcher.FindJava(a.instances.SharedDir()), "bundled")
+	if list, err := a.instances.List(); err == nil {
+		for _, inst := range list {
+			add(inst.JavaPath, "instance")
+		}
+	}
+	exe := "java"
+	if runtime.GOOS == "windows" {
+		exe = "java.exe"
+	}
+	if home := os.Getenv("JAVA_HOME"); home != "" {
+		add(filepath.Join(home, "bin", exe), "JAVA_HOME")
+	}
+	if path, err := exec.LookPath(exe); err == nil {
+		add(path, "PATH")
+	}
+
+	if !out.json {
+		for _, j := range installs {
+			detail := j.Version
+			if j.Error != "" {
+				detail = "error: " + j.Error
+			} else if !j.Is64Bit {
+				detail += " (32-bit)"
+			}
+			fmt.Fprintf(out.w, "%-9s %s  %s\n", j.Source, j.Path, detail)
+		}
+	}
+	out.result(installs, fmt.Sprintf("Found %d Java installations.", len(installs)))
+	return exitOK
+}
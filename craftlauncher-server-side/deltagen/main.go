@@ -0,0 +1,201 @@
+// deltagen precomputes binary patches between two releases of the modpack.
+//
+// Usage:
+//
+//	go run ./craftlauncher-server-side/deltagen -prev ./releases/v4 -files ./craftlauncher-server-side/modpack
+//
+// Patches are written to <files>/.deltas/ together with an index that
+// generate-manifest.sh reads to list them in manifest.json. Existing index
+// entries are kept, so clients several versions behind can still patch as
+// long as the file they hold was part of an earlier run.
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"craft-launcher/launcher/integrity"
+)
+
+const (
+	DeltaDirName = ".deltas"
+	IndexName    = "index"
+)
+
+// Files read by generate-manifest.sh that are not part of the pack
+var serverConfigFiles = map[string]bool{
+	".version":            true,
+	".manifest_overrides": true,
+	".offline_policy":     true,
+	".servers":            true,
+	".autojoin":           true,
+	".crash_rules.json":   true,
+	".jvm_presets.json":   true,
+	".ram":                true,
+}
+
+// A patch is only worth shipping if it is clearly smaller than the file
+const maxDeltaRatio = 0.8
+
+type indexEntry struct {
+	To   string
+	From string
+	Size int64
+	Name string
+	Path string
+}
+
+func main() {
+	prevDir := flag.String("prev", "", "directory holding the previous release of the modpack")
+	filesDir := flag.String("files", "", "directory holding the current release (served under /files/)")
+	flag.Parse()
+
+	if *prevDir == "" || *filesDir == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(*prevDir, *filesDir); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(prevDir, filesDir string) error {
+	deltaDir := filepath.Join(filesDir, DeltaDirName)
+	if err := os.MkdirAll(deltaDir, 0755); err != nil {
+		return err
+	}
+
+	indexPath := filepath.Join(deltaDir, IndexName)
+	entries, err := loadIndex(indexPath)
+	if err != nil {
+		return err
+	}
+
+	known := make(map[string]bool)
+	for _, e := range entries {
+		known[e.Name] = true
+	}
+
+	created := 0
+	err = filepath.WalkDir(filesDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(filesDir, path)
+		rel = filepath.ToSlash(rel)
+
+		if d.IsDir() {
+			if rel == DeltaDirName {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if serverConfigFiles[rel] {
+			return nil
+		}
+
+		oldData, err := os.ReadFile(filepath.Join(prevDir, filepath.FromSlash(rel)))
+		if err != nil {
+			// New file, nothing to patch from
+			return nil
+		}
+		newData, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		from, to := checksum(oldData), checksum(newData)
+		if from == to {
+			return nil
+		}
+
+		name := fmt.Sprintf("%s-%s.cldelta", from, to)
+		if known[name] {
+			return nil
+		}
+
+		delta, err := integrity.CreateDelta(oldData, newData)
+		if err != nil {
+			return fmt.Errorf("failed to diff %s: %w", rel, err)
+		}
+		if float64(len(delta)) > float64(len(newData))*maxDeltaRatio {
+			fmt.Printf("Skipping %s (delta %d bytes, file %d bytes)\n", rel, len(delta), len(newData))
+			return nil
+		}
+
+		if err := os.WriteFile(filepath.Join(deltaDir, name), delta, 0644); err != nil {
+			return err
+		}
+		entries = append(entries, indexEntry{To: to, From: from, Size: int64(len(delta)), Name: name, Path: rel})
+		known[name] = true
+		created++
+		fmt.Printf("Delta for %s: %d -> %d bytes\n", rel, len(newData), len(delta))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := saveIndex(indexPath, entries); err != nil {
+		return err
+	}
+	fmt.Printf("Created %d deltas (%d in index)\n", created, len(entries))
+	return nil
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Index format, one patch per line, tab separated with the path last so it
+// may contain spaces: <to checksum> <from checksum> <size> <patch name> <path>
+func loadIndex(path string) ([]indexEntry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []indexEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.SplitN(scanner.Text(), "\t", 5)
+		if len(fields) != 5 {
+			continue
+		}
+		var e indexEntry
+		e.To, e.From, e.Name, e.Path = fields[0], fields[1], fields[3], fields[4]
+		fmt.Sscanf(fields[2], "%d", &e.Size)
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+func saveIndex(path string, entries []indexEntry) error {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Path != entries[j].Path {
+			return entries[i].Path < entries[j].Path
+		}
+		return entries[i].Name < entries[j].Name
+	})
+
+	var sb strings.Builder
+	for _, e := range entries {
+		fmt.Fprintf(&sb, "%s\t%s\t%d\t%s\t%s\n", e.To, e.From, e.Size, e.Name, e.Path)
+	}
+	return os.WriteFile(path, []byte(sb.String()), 0644)
+}
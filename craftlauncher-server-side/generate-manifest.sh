@@ -7,6 +7,13 @@ MODPACK_DIR="/usr/share/nginx/html/files"
 MANIFEST_FILE="/usr/share/nginx/html/manifest.json"
 VERSION_FILE="/usr/share/nginx/html/files/.version"
 OVERRIDES_FILE="/usr/share/nginx/html/files/.manifest_overrides"
+POLICY_FILE="/usr/share/nginx/html/files/.offline_policy"
+SERVERS_FILE="/usr/share/nginx/html/files/.servers"
+AUTOJOIN_FILE="/usr/share/nginx/html/files/.autojoin"
+CRASH_RULES_FILE="/usr/share/nginx/html/files/.crash_rules.json"
+JVM_PRESETS_FILE="/usr/share/nginx/html/files/.jvm_presets.json"
+RAM_FILE="/usr/share/nginx/html/files/.ram"
+DELTA_INDEX="/usr/share/nginx/html/files/.deltas/index"
 
 # Create default overrides file if it doesn't exist
 if [ ! -f "$OVERRIDES_FILE" ] && [ -d "$MODPACK_DIR" ]; then
@@ -51,19 +58,136 @@ fi
 
 echo "Generating manifest.json for version $VERSION..."
 
+# Escape a value for a JSON string: backslashes and quotes are escaped,
+# control characters dropped
+json_escape() {
+    printf '%s' "$1" | sed -e 's/\\/\\\\/g' -e 's/"/\\"/g' | tr -d '\000-\037'
+}
+
+# Check a file holds a JSON array. nginx:alpine ships neither jq nor
+# python, so jq is installed on first use there.
+json_array_valid() {
+    if ! command -v jq >/dev/null 2>&1 && command -v apk >/dev/null 2>&1; then
+        apk add --no-cache jq >/dev/null 2>&1
+    fi
+    if command -v jq >/dev/null 2>&1; then
+        jq -e 'type == "array"' "$1" >/dev/null 2>&1
+    elif command -v python3 >/dev/null 2>&1; then
+        python3 -c 'import json, sys; sys.exit(not isinstance(json.load(open(sys.argv[1])), list))' "$1" 2>/dev/null
+    else
+        echo "Error: neither jq nor python3 is available to check $1" >&2
+        return 1
+    fi
+}
+
+# Everything copied into the manifest is checked before it's written, so a
+# typo keeps the previous manifest instead of breaking every client's update
+for JSON_FILE in "$CRASH_RULES_FILE" "$JVM_PRESETS_FILE"; do
+    if [ -f "$JSON_FILE" ] && ! json_array_valid "$JSON_FILE"; then
+        echo "Error: $JSON_FILE is not a valid JSON array, fix it and restart. The manifest was not updated." >&2
+        exit 1
+    fi
+done
+
+# Offline policy: a single line "<strict|grace|allow> [grace_hours]"
+POLICY_JSON=""
+if [ -f "$POLICY_FILE" ]; then
+    read -r POLICY_MODE POLICY_HOURS < "$POLICY_FILE"
+    case "$POLICY_HOURS" in
+        *[!0-9]*)
+            echo "Error: grace hours '$POLICY_HOURS' in $POLICY_FILE must be a whole number of hours. The manifest was not updated." >&2
+            exit 1
+            ;;
+    esac
+    # JSON numbers can't have leading zeros
+    POLICY_HOURS=$(echo "$POLICY_HOURS" | sed 's/^0*//')
+    case "$POLICY_MODE" in
+        strict|grace|allow)
+            POLICY_JSON="{\"mode\": \"$POLICY_MODE\", \"graceHours\": ${POLICY_HOURS:-0}}"
+            ;;
+        *)
+            echo "Ignoring unknown offline policy '$POLICY_MODE'"
+            ;;
+    esac
+fi
+
 # Start JSON
 echo "{" > "$MANIFEST_FILE"
 echo "  \"version\": $VERSION," >> "$MANIFEST_FILE"
+
+if [ -n "$POLICY_JSON" ]; then
+    echo "  \"offlinePolicy\": $POLICY_JSON," >> "$MANIFEST_FILE"
+fi
+
+# Server to join on launch: a single "host[:port]" line
+if [ -f "$AUTOJOIN_FILE" ]; then
+    read -r AUTOJOIN < "$AUTOJOIN_FILE"
+    if [ -n "$AUTOJOIN" ]; then
+        echo "  \"autoJoin\": \"$(json_escape "$AUTOJOIN")\"," >> "$MANIFEST_FILE"
+    fi
+fi
+
+# Pack-specific crash diagnoses: a JSON array of {id, title, pattern, fix}
+if [ -f "$CRASH_RULES_FILE" ]; then
+    printf '  "crashRules": ' >> "$MANIFEST_FILE"
+    cat "$CRASH_RULES_FILE" >> "$MANIFEST_FILE"
+    echo "," >> "$MANIFEST_FILE"
+fi
+
+# Pack-specific JVM argument presets: a JSON array of {id, name, description, jvmArgs, gameArgs}
+if [ -f "$JVM_PRESETS_FILE" ]; then
+    printf '  "jvmPresets": ' >> "$MANIFEST_FILE"
+    cat "$JVM_PRESETS_FILE" >> "$MANIFEST_FILE"
+    echo "," >> "$MANIFEST_FILE"
+fi
+
+# Memory the pack needs: a single "<min MiB> [recommended MiB]" line
+if [ -f "$RAM_FILE" ]; then
+    read -r RAM_MIN RAM_RECOMMENDED < "$RAM_FILE"
+    case "$RAM_MIN$RAM_RECOMMENDED" in
+        ''|*[!0-9]*)
+            echo "Ignoring invalid .ram '$RAM_MIN $RAM_RECOMMENDED'"
+            ;;
+        *)
+            echo "  \"minRamMB\": $RAM_MIN, \"recommendedRamMB\": ${RAM_RECOMMENDED:-$RAM_MIN}," >> "$MANIFEST_FILE"
+            ;;
+    esac
+fi
+
+# Required multiplayer servers: one "<ip> <icon.png or -> <name>" per line.
+# Icon paths are relative to the directory holding .servers.
+if [ -f "$SERVERS_FILE" ]; then
+    echo "  \"servers\": [" >> "$MANIFEST_FILE"
+    FIRST_SERVER=true
+    while read -r SERVER_IP SERVER_ICON SERVER_NAME; do
+        case "$SERVER_IP" in
+            \#*|"") continue ;;
+        esac
+        ICON_DATA=""
+        if [ "$SERVER_ICON" != "-" ] && [ -f "$(dirname "$SERVERS_FILE")/$SERVER_ICON" ]; then
+            ICON_DATA=$(base64 -w0 "$(dirname "$SERVERS_FILE")/$SERVER_ICON")
+        fi
+        if [ "$FIRST_SERVER" = true ]; then
+            FIRST_SERVER=false
+        else
+            echo "," >> "$MANIFEST_FILE"
+        fi
+        printf "    {\"name\": \"%s\", \"ip\": \"%s\", \"icon\": \"%s\"}" "$(json_escape "$SERVER_NAME")" "$(json_escape "$SERVER_IP")" "$ICON_DATA" >> "$MANIFEST_FILE"
+    done < "$SERVERS_FILE"
+    echo "" >> "$MANIFEST_FILE"
+    echo "  ]," >> "$MANIFEST_FILE"
+fi
+
 echo "  \"files\": [" >> "$MANIFEST_FILE"
 
 # Generate file list with checksums
 FIRST=true
-find "$MODPACK_DIR" -type f ! -name ".version" | sort | while read -r file; do
+find "$MODPACK_DIR" -type f ! -name ".version" ! -path "$MODPACK_DIR/.deltas/*" | sort | while read -r file; do
     # Get relative path
     REL_PATH=$(echo "$file" | sed "s|$MODPACK_DIR/||")
     
-    # Skip if it's the version file or overrides file
-    if [ "$REL_PATH" = ".version" ] || [ "$REL_PATH" = ".manifest_overrides" ]; then
+    # Skip if it's one of the server config files
+    if [ "$REL_PATH" = ".version" ] || [ "$REL_PATH" = ".manifest_overrides" ] || [ "$REL_PATH" = ".offline_policy" ] || [ "$REL_PATH" = ".servers" ] || [ "$REL_PATH" = ".autojoin" ] || [ "$REL_PATH" = ".crash_rules.json" ] || [ "$REL_PATH" = ".jvm_presets.json" ] || [ "$REL_PATH" = ".ram" ]; then
         continue
     fi
     
@@ -108,6 +232,16 @@ find "$MODPACK_DIR" -type f ! -name ".version" | sort | while read -r file; do
         esac
     fi
     
+    # Collect precomputed delta patches (see deltagen) that produce this exact file
+    DELTAS=""
+    if [ -f "$DELTA_INDEX" ]; then
+        DELTAS=$(awk -F'\t' -v path="$REL_PATH" -v sum="$CHECKSUM" '
+            $5 == path && $1 == sum {
+                if (n++) printf ","
+                printf "\n        {\"from\": \"%s\", \"path\": \".deltas/%s\", \"size\": %s}", $2, $4, $3
+            }' "$DELTA_INDEX")
+    fi
+
     # Add comma before each entry except the first
     if [ "$FIRST" = true ]; then
         FIRST=false
@@ -116,7 +250,11 @@ find "$MODPACK_DIR" -type f ! -name ".version" | sort | while read -r file; do
     fi
     
     # Write JSON entry (without trailing comma)
-    printf "    {\n      \"path\": \"%s\",\n      \"size\": %s,\n      \"checksum\": \"%s\",\n      \"override\": %s\n    }" "$REL_PATH" "$SIZE" "$CHECKSUM" "$OVERRIDE" >> "$MANIFEST_FILE"
+    printf "    {\n      \"path\": \"%s\",\n      \"size\": %s,\n      \"checksum\": \"%s\",\n      \"override\": %s" "$REL_PATH" "$SIZE" "$CHECKSUM" "$OVERRIDE" >> "$MANIFEST_FILE"
+    if [ -n "$DELTAS" ]; then
+        printf ",\n      \"deltas\": [%s\n      ]" "$DELTAS" >> "$MANIFEST_FILE"
+    fi
+    printf "\n    }" >> "$MANIFEST_FILE"
 done
 
 # Close JSON
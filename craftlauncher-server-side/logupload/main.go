@@ -0,0 +1,289 @@
+// logupload stores log bundles that players share from the launcher.
+//
+// Usage:
+//
+//	go run ./craftlauncher-server-side/logupload -dir ./craftlauncher-server-side/uploads -listen :8091
+//
+// POST /logs takes a zip (already redacted by the launcher) and answers with
+// {"id": "...", "url": "..."}. GET /logs/<id> downloads it again. nginx
+// proxies /logs to this service, see nginx.conf. Uploads are limited per
+// client address (X-Real-IP from nginx) and in total.
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxUploadSize matches share.MaxBundleSize in the launcher
+	MaxUploadSize = 10 * 1024 * 1024
+
+	idLength   = 8
+	idAlphabet = "abcdefghjkmnpqrstuvwxyz23456789" // No look-alike characters
+)
+
+var validID = regexp.MustCompile(`^[` + idAlphabet + `]{` + fmt.Sprint(idLength) + `}$`)
+
+type server struct {
+	dir      string
+	maxAge   time.Duration
+	maxTotal int64 // Bytes all stored uploads may take, 0 for no limit
+	perHour  int   // Uploads per client address and hour, 0 for no limit
+
+	mu     sync.Mutex
+	recent map[string][]time.Time // Upload times per client address
+}
+
+func main() {
+	dir := flag.String("dir", "uploads", "directory to store uploaded bundles in")
+	listen := flag.String("listen", ":8091", "address to listen on")
+	maxAge := flag.Duration("max-age", 30*24*time.Hour, "delete uploads older than this (0 keeps them)")
+	maxTotalMB := flag.Int64("max-total-mb", 2048, "refuse uploads once stored bundles take this many MiB (0 for no limit)")
+	perHour := flag.Int("per-ip-hour", 10, "uploads one address may make per hour (0 for no limit)")
+	flag.Parse()
+
+	if err := os.MkdirAll(*dir, 0755); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	s := &server{
+		dir:      *dir,
+		maxAge:   *maxAge,
+		maxTotal: *maxTotalMB * 1024 * 1024,
+		perHour:  *perHour,
+		recent:   make(map[string][]time.Time),
+	}
+	go s.expireLoop()
+
+	http.HandleFunc("/logs", s.upload)
+	http.HandleFunc("/logs/", s.download)
+	fmt.Printf("Accepting log uploads on %s, storing in %s\n", *listen, *dir)
+	if err := http.ListenAndServe(*listen, nil); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func (s *server) upload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !s.allow(clientAddr(r)) {
+		w.Header().Set("Retry-After", "3600")
+		http.Error(w, "too many uploads, try again later", http.StatusTooManyRequests)
+		return
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxUploadSize))
+	if err != nil {
+		http.Error(w, "upload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if !bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		http.Error(w, "expected a zip file", http.StatusBadRequest)
+		return
+	}
+
+	id, err := s.store(data)
+	if errors.Is(err, errFull) {
+		fmt.Fprintf(os.Stderr, "Error: refusing upload, %v\n", err)
+		http.Error(w, "log storage is full, ask the server admin", http.StatusInsufficientStorage)
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: storing upload: %v\n", err)
+		http.Error(w, "failed to store upload", http.StatusInternalServerError)
+		return
+	}
+	fmt.Printf("Stored log %s (%d bytes)\n", id, len(data))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]string{
+		"id":  id,
+		"url": publicURL(r) + "/logs/" + id,
+	})
+}
+
+func (s *server) download(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	id := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/logs/"), ".zip")
+	if !validID.MatchString(id) {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="logs-%s.zip"`, id))
+	http.ServeFile(w, r, filepath.Join(s.dir, id+".zip"))
+}
+
+var errFull = errors.New("stored uploads reached the total size limit")
+
+// allow records an upload from addr and reports whether it's within the
+// hourly limit
+func (s *server) allow(addr string) bool {
+	if s.perHour <= 0 {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := time.Now().Add(-time.Hour)
+	var kept []time.Time
+	for _, t := range s.recent[addr] {
+		if t.After(cutoff) {
+			kept = append(kept, t)
+		}
+	}
+	if len(kept) >= s.perHour {
+		s.recent[addr] = kept
+		return false
+	}
+	s.recent[addr] = append(kept, time.Now())
+	return true
+}
+
+// forgetOld drops addresses that made no upload in the last hour
+func (s *server) forgetOld() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := time.Now().Add(-time.Hour)
+	for addr, times := range s.recent {
+		if len(times) == 0 || !times[len(times)-1].After(cutoff) {
+			delete(s.recent, addr)
+		}
+	}
+}
+
+// storedSize returns how many bytes the stored uploads take
+func (s *server) storedSize() int64 {
+	var total int64
+	entries, _ := os.ReadDir(s.dir)
+	for _, e := range entries {
+		if info, err := e.Info(); err == nil && info.Mode().IsRegular() {
+			total += info.Size()
+		}
+	}
+	return total
+}
+
+// store writes data under a fresh random ID
+func (s *server) store(data []byte) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.maxTotal > 0 && s.storedSize()+int64(len(data)) > s.maxTotal {
+		return "", errFull
+	}
+	for attempt := 0; attempt < 5; attempt++ {
+		id, err := newID()
+		if err != nil {
+			return "", err
+		}
+		f, err := os.OpenFile(filepath.Join(s.dir, id+".zip"), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		if _, err := f.Write(data); err != nil {
+			f.Close()
+			os.Remove(f.Name())
+			return "", err
+		}
+		return id, f.Close()
+	}
+	return "", fmt.Errorf("no free upload ID")
+}
+
+// newID picks idLength characters uniformly from idAlphabet. Bytes past the
+// last whole multiple of the alphabet size are drawn again, so no character
+// is more likely than another.
+func newID() (string, error) {
+	limit := 256 - 256%len(idAlphabet)
+	id := make([]byte, 0, idLength)
+	buf := make([]byte, idLength)
+	for len(id) < idLength {
+		if _, err := rand.Read(buf); err != nil {
+			return "", err
+		}
+		for _, b := range buf {
+			if int(b) < limit && len(id) < idLength {
+				id = append(id, idAlphabet[int(b)%len(idAlphabet)])
+			}
+		}
+	}
+	return string(id), nil
+}
+
+// expireLoop deletes uploads older than maxAge and forgets old upload
+// counts once an hour
+func (s *server) expireLoop() {
+	for {
+		s.forgetOld()
+		if s.maxAge <= 0 {
+			time.Sleep(time.Hour)
+			continue
+		}
+		entries, _ := os.ReadDir(s.dir)
+		for _, e := range entries {
+			info, err := e.Info()
+			if err != nil || !strings.HasSuffix(e.Name(), ".zip") {
+				continue
+			}
+			if time.Since(info.ModTime()) > s.maxAge {
+				os.Remove(filepath.Join(s.dir, e.Name()))
+			}
+		}
+		time.Sleep(time.Hour)
+	}
+}
+
+// clientAddr identifies who uploads. nginx passes the player's address in
+// X-Real-IP; without it, the connection's address is used.
+func clientAddr(r *http.Request) string {
+	if ip := r.Header.Get("X-Real-IP"); ip != "" {
+		return ip
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// publicURL is the address players reach the server at, as seen through
+// the nginx proxy
+func publicURL(r *http.Request) string {
+	scheme := "http"
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	host := r.Header.Get("X-Forwarded-Host")
+	if host == "" {
+		host = r.Host
+	}
+	return scheme + "://" + host
+}
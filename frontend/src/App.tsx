@@ -1,35 +1,136 @@
 import { useState, useEffect } from 'react';
 import './App.css';
-import { LaunchGame, GetSystemInfo, ForceStopGame } from "../wailsjs/go/main/App";
-import { EventsOn } from "../wailsjs/runtime";
-import { Console } from "./components/Console";
-import { main } from "../wailsjs/go/models";
+import { LaunchGame, LaunchInstance, GetSystemInfo, StopGame, ForceStopGame, GetSettings, SaveSettings, ResetSettings, OpenCrashFile, ShareLog, ExportDiagnostics, ListAccounts, LoginMicrosoft, LoginYggdrasil, CancelLogin, RemoveAccount, ListInstances, RepairInstance } from "../wailsjs/go/main/App";
+import { EventsOn, ClipboardSetText, BrowserOpenURL } from "../wailsjs/runtime";
+import { Console, LogRecord } from "./components/Console";
+import { auth, display, instance, main, settings } from "../wailsjs/go/models";
+
+// Payload of the "game-crashed" event (crash.Report)
+interface CrashInfo {
+    sessionId: string;
+    kind: string;
+    exitReason: string;
+    exception: string;
+    crashReport: string;
+    jvmErrorLog: string;
+    diagnoses: { ruleId: string; title: string; fix: string }[] | null;
+}
+
+// Payload of the "game-exited" event (ExitInfo)
+interface ExitInfo {
+    sessionId: string;
+    instanceId: string;
+    exitCode: number;
+    logFile: string;
+}
+
+// Latest session and status of an instance, from "update-status" events
+interface InstanceState {
+    sessionId: string;
+    status: string;
+}
+
+const idleState: InstanceState = { sessionId: "", status: "Ready to Launch" };
+
+const isStoppingStatus = (status: string) => status.startsWith("Stopping");
+const isRunningStatus = (status: string) => status === "Running" || isStoppingStatus(status);
+const isLaunchingStatus = (status: string) =>
+    status === "Launching..." || status.startsWith("Downloading") || status.startsWith("Checking");
+
+// Payload of the "auth-prompt" event (auth.Prompt)
+interface AuthPrompt {
+    userCode: string;
+    verificationUri: string;
+    message: string;
+}
 
 function App() {
-    const [status, setStatus] = useState("Ready to Launch");
+    const [instanceStates, setInstanceStates] = useState<Record<string, InstanceState>>({});
+    const [instances, setInstances] = useState<instance.Instance[]>([]);
     const [username, setUsername] = useState("Player");
     const [showLogWhileRunning, setShowLogWhileRunning] = useState(false);
-    const [logs, setLogs] = useState<string[]>([]);
+    const [logs, setLogs] = useState<LogRecord[]>([]);
     const [statusHistory, setStatusHistory] = useState<string[]>([]);
     const [isConsoleOpen, setIsConsoleOpen] = useState(false);
     const [useFabric, setUseFabric] = useState(false);
     const [ramMB, setRamMB] = useState(2048);
+    const [gameWindow, setGameWindow] = useState<display.Window>({ width: 0, height: 0, fullscreen: false, rememberSize: false });
     const [serverURL, setServerURL] = useState("http://127.0.0.1:8090");
     const [systemInfo, setSystemInfo] = useState<main.SystemInfo | null>(null);
+    const [crash, setCrash] = useState<CrashInfo | null>(null);
+    const [lastExit, setLastExit] = useState<ExitInfo | null>(null);
+    const [shareStatus, setShareStatus] = useState("");
+    const [repairUserFiles, setRepairUserFiles] = useState(false);
+    const [account, setAccount] = useState<auth.Profile | null>(null);
+    const [authPrompt, setAuthPrompt] = useState<AuthPrompt | null>(null);
+    const [authError, setAuthError] = useState("");
+    const [showAuthServer, setShowAuthServer] = useState(false);
+    const [authServer, setAuthServer] = useState("");
+    const [authUser, setAuthUser] = useState("");
+    const [authPassword, setAuthPassword] = useState("");
+    const [settingsError, setSettingsError] = useState("");
 
     // Derived state
-    const isRunning = status === "Running";
-    const isLaunching = status === "Launching..." || status.startsWith("Downloading") || status.startsWith("Checking");
+    // Same rules as launcher.ValidateUsername
+    const isValidUsername = account !== null || /^[A-Za-z0-9_]{3,16}$/.test(username);
+    const playerName = account ? account.username : username;
+    // The form drives the default instance, the others are listed below it
+    const stateOf = (id: string) => instanceStates[id] ?? idleState;
+    const { sessionId, status } = stateOf("default");
+    const isStopping = isStoppingStatus(status);
+    const isRunning = isRunningStatus(status);
+    const isLaunching = isLaunchingStatus(status);
+
+    const applySettings = (saved: settings.Settings) => {
+        setUsername(saved.username);
+        setUseFabric(saved.useFabric);
+        setServerURL(saved.serverURL);
+        setShowLogWhileRunning(saved.showLog);
+        if (saved.account) {
+            ListAccounts().then((accounts) => {
+                setAccount(accounts.find((a) => a.id === saved.account) ?? null);
+            });
+        }
+        if (saved.ramMB > 0) {
+            setRamMB(saved.ramMB);
+        }
+    };
+
+    const setStatus = (id: string, status: string) => {
+        setInstanceStates(prev => ({ ...prev, [id]: { ...(prev[id] ?? idleState), status } }));
+    };
+
+    const resetSettings = () => {
+        ResetSettings().then((saved) => {
+            setSettingsError("");
+            applySettings(saved);
+        }).catch((err) => setSettingsError(String(err)));
+    };
 
     useEffect(() => {
-        // Fetch system info on startup
+        // Fetch system info on startup, then restore saved settings
         GetSystemInfo().then((info) => {
             setSystemInfo(info);
             setRamMB(info.defaultRAM);
+            return GetSettings();
+        }).then(applySettings).catch((err) => {
+            // Unreadable settings aren't saved over until the user resets them
+            setSettingsError(String(err));
+        });
+        ListInstances().then((instances) => {
+            setInstances(instances);
+            const inst = instances.find((i) => i.id === "default");
+            if (inst?.window) {
+                setGameWindow(inst.window);
+            }
         });
 
-        const unsubscribeStatus = EventsOn("update-status", (msg: string) => {
-            setStatus(msg);
+        const unsubscribeStatus = EventsOn("update-status", (msg: string, id?: string, instanceId?: string) => {
+            const key = instanceId || "default";
+            setInstanceStates(prev => {
+                const state = prev[key] ?? idleState;
+                return { ...prev, [key]: { sessionId: id || state.sessionId, status: msg } };
+            });
             setStatusHistory(prev => [...prev, `[LAUNCHER] ${msg}`]);
             if (msg === "Crashed") {
                 setIsConsoleOpen(true);
@@ -39,35 +140,124 @@ function App() {
             }
         });
 
-        const unsubscribeLogs = EventsOn("log-data", (msg: string) => {
-            setLogs(prev => [...prev, msg]);
+        const unsubscribeLogs = EventsOn("log-record", (rec: LogRecord) => {
+            setLogs(prev => [...prev, rec]);
+        });
+
+        const unsubscribeCrash = EventsOn("game-crashed", (report: CrashInfo) => {
+            setCrash(report);
+        });
+
+        const unsubscribeAuth = EventsOn("auth-prompt", (prompt: AuthPrompt) => {
+            setAuthPrompt(prompt);
+        });
+
+        const unsubscribeExit = EventsOn("game-exited", (exit: ExitInfo) => {
+            setLastExit(exit);
         });
 
         return () => {
             unsubscribeStatus();
             unsubscribeLogs();
+            unsubscribeCrash();
+            unsubscribeExit();
+            unsubscribeAuth();
         };
     }, []);
 
-    const launch = () => {
-        if (isRunning) {
-            ForceStopGame().then((res: string) => {
-                setStatusHistory(prev => [...prev, `[LAUNCHER] ${res}`]);
-            });
-            return;
-        }
+    // First click asks an instance's game to quit, a second one kills it
+    const stop = (id: string) => {
+        const state = stateOf(id);
+        const stopGame = isStoppingStatus(state.status) ? ForceStopGame : StopGame;
+        stopGame(state.sessionId).then((res: string) => {
+            setStatusHistory(prev => [...prev, `[LAUNCHER] ${res}`]);
+        });
+    };
 
-        setStatus("Launching...");
+    const startLaunch = (id: string) => {
+        setStatus(id, "Launching...");
+        setCrash(null);
+        setShareStatus("");
         setLogs([]); // Clear logs on new launch
         setStatusHistory([]); // Clear status history on new launch
         if (showLogWhileRunning) {
             setIsConsoleOpen(true);
         }
-        LaunchGame(username, ramMB, useFabric, serverURL).then((res: string) => {
-            if (res === "Game is already running!") {
-                // Revert status if we failed to launch
-                setStatus("Running");
+    };
+
+    const launch = () => {
+        if (isRunning) {
+            stop("default");
+            return;
+        }
+        startLaunch("default");
+        LaunchGame(username, ramMB, useFabric, serverURL, gameWindow);
+    };
+
+    // Other instances use their own settings and the form's player
+    const launchInstance = (id: string) => {
+        startLaunch(id);
+        LaunchInstance(id, username);
+    };
+
+    // Upload the redacted session log and crash files, and copy the link
+    const shareLog = () => {
+        setShareStatus("Uploading...");
+        ShareLog(lastExit?.instanceId ?? "", lastExit?.logFile ?? "").then((res) => {
+            ClipboardSetText(res.url);
+            setShareStatus(`Log ID: ${res.id} (link copied)`);
+        }).catch((err) => {
+            setShareStatus(`Upload failed: ${err}`);
+        });
+    };
+
+    const signIn = () => {
+        setAuthError("");
+        LoginMicrosoft().then((profile) => {
+            setAccount(profile);
+        }).catch((err) => {
+            setAuthError(`${err}`);
+        }).finally(() => {
+            setAuthPrompt(null);
+        });
+    };
+
+    const signInAuthServer = () => {
+        setAuthError("");
+        LoginYggdrasil(authServer, authUser, authPassword).then((profile) => {
+            setAccount(profile);
+            setShowAuthServer(false);
+        }).catch((err) => {
+            setAuthError(`${err}`);
+        }).finally(() => {
+            setAuthPassword("");
+        });
+    };
+
+    const signOut = () => {
+        if (account) {
+            RemoveAccount(account.id).then(() => setAccount(null));
+        }
+    };
+
+    const exportDiagnostics = () => {
+        ExportDiagnostics(lastExit?.instanceId ?? "").then((path) => {
+            if (path) {
+                setShareStatus(`Diagnostics saved to ${path}`);
             }
+        }).catch((err) => {
+            setShareStatus(`Export failed: ${err}`);
+        });
+    };
+
+    // Re-check every file the default instance uses and fetch bad ones again
+    const repairFiles = () => {
+        setStatus("default", "Checking files...");
+        RepairInstance("default", repairUserFiles).then((report) => {
+            const left = report.items.filter((item) => !item.repaired).length;
+            setStatus("default", `Repair finished: ${report.items.length} problems, ${left} left`);
+        }).catch((err) => {
+            setStatus("default", `Repair Error: ${err}`);
         });
     };
 
@@ -77,15 +267,63 @@ function App() {
                 <h1 className="title">MINECRAFT 1.8.9</h1>
 
                 <div className="input-group">
-                    <label>USERNAME</label>
+                    <label>
+                        USERNAME
+                        {account && (
+                            <span className="ram-info"> ({account.type === "msa" ? "Microsoft account" : "auth server account"})</span>
+                        )}
+                        {!isValidUsername && (
+                            <span className="ram-info"> (3-16 letters, digits or _)</span>
+                        )}
+                    </label>
                     <input
                         type="text"
-                        value={username}
+                        value={playerName}
                         onChange={(e) => setUsername(e.target.value)}
                         placeholder="Offline Username"
                         className="username-input"
-                        disabled={isRunning || isLaunching}
+                        disabled={isRunning || isLaunching || account !== null}
                     />
+                    {account ? (
+                        <button className="btn-show-log" onClick={signOut} disabled={isRunning || isLaunching}>
+                            SIGN OUT
+                        </button>
+                    ) : authPrompt ? (
+                        <div className="ram-info">
+                            Go to {authPrompt.verificationUri} and enter <b>{authPrompt.userCode}</b>
+                            <button className="btn-show-log" onClick={() => BrowserOpenURL(authPrompt.verificationUri)}>
+                                OPEN
+                            </button>
+                            <button className="btn-show-log" onClick={() => CancelLogin()}>
+                                CANCEL
+                            </button>
+                        </div>
+                    ) : showAuthServer ? (
+                        <div>
+                            <input type="text" className="username-input" placeholder="Auth server (e.g. skins.example.com)"
+                                value={authServer} onChange={(e) => setAuthServer(e.target.value)} />
+                            <input type="text" className="username-input" placeholder="Email or username"
+                                value={authUser} onChange={(e) => setAuthUser(e.target.value)} />
+                            <input type="password" className="username-input" placeholder="Password"
+                                value={authPassword} onChange={(e) => setAuthPassword(e.target.value)} />
+                            <button className="btn-show-log" onClick={signInAuthServer}>
+                                SIGN IN
+                            </button>
+                            <button className="btn-show-log" onClick={() => setShowAuthServer(false)}>
+                                CANCEL
+                            </button>
+                        </div>
+                    ) : (
+                        <div>
+                            <button className="btn-show-log" onClick={signIn} disabled={isRunning || isLaunching}>
+                                SIGN IN WITH MICROSOFT
+                            </button>
+                            <button className="btn-show-log" onClick={() => setShowAuthServer(true)} disabled={isRunning || isLaunching}>
+                                AUTH SERVER
+                            </button>
+                        </div>
+                    )}
+                    {authError && <div className="ram-info">{authError}</div>}
                 </div>
 
                 <div className="input-group">
@@ -93,8 +331,8 @@ function App() {
                         RAM ALLOCATION (GiB)
                         {systemInfo && (
                             <span className="ram-info">
-                                {systemInfo.is32Bit && " (32-bit limited to 1 GiB)"}
-                                {!systemInfo.is32Bit && ` (System: ${Math.floor(systemInfo.totalRAM / 1024)} GiB)`}
+                                {systemInfo.is32Bit && " (32-bit Java limited to 1 GiB)"}
+                                {!systemInfo.is32Bit && ` (System: ${Math.floor(systemInfo.totalRAM / 1024)} GiB, ${(systemInfo.freeRAM / 1024).toFixed(1)} GiB free, recommended ${Math.round(systemInfo.recommendedRAM / 1024)} GiB)`}
                             </span>
                         )}
                     </label>
@@ -117,6 +355,59 @@ function App() {
                         className="ram-input"
                         placeholder="2"
                     />
+                    {systemInfo?.warnings?.map((w) => (
+                        <div key={w.code} className="ram-info">{w.message}</div>
+                    ))}
+                </div>
+
+                <div className="input-group">
+                    <label>
+                        WINDOW SIZE
+                        <span className="ram-info"> (empty for 854 x 480)</span>
+                    </label>
+                    <div className="window-size">
+                        <input
+                            type="number"
+                            value={gameWindow.width || ""}
+                            onChange={(e) => setGameWindow({ ...gameWindow, width: parseInt(e.target.value) || 0 })}
+                            min={320}
+                            max={7680}
+                            placeholder="854"
+                            className="ram-input"
+                            disabled={gameWindow.fullscreen || isRunning || isLaunching}
+                        />
+                        <span>x</span>
+                        <input
+                            type="number"
+                            value={gameWindow.height || ""}
+                            onChange={(e) => setGameWindow({ ...gameWindow, height: parseInt(e.target.value) || 0 })}
+                            min={240}
+                            max={4320}
+                            placeholder="480"
+                            className="ram-input"
+                            disabled={gameWindow.fullscreen || isRunning || isLaunching}
+                        />
+                    </div>
+                    <div className="options">
+                        <label className="checkbox-label">
+                            <input
+                                type="checkbox"
+                                checked={gameWindow.fullscreen}
+                                onChange={(e) => setGameWindow({ ...gameWindow, fullscreen: e.target.checked })}
+                                disabled={isRunning || isLaunching}
+                            />
+                            Fullscreen
+                        </label>
+                        <label className="checkbox-label">
+                            <input
+                                type="checkbox"
+                                checked={gameWindow.rememberSize}
+                                onChange={(e) => setGameWindow({ ...gameWindow, rememberSize: e.target.checked })}
+                                disabled={isRunning || isLaunching}
+                            />
+                            Remember size
+                        </label>
+                    </div>
                 </div>
 
                 <div className="input-group">
@@ -137,7 +428,13 @@ function App() {
                             <input
                                 type="checkbox"
                                 checked={showLogWhileRunning}
-                                onChange={(e) => setShowLogWhileRunning(e.target.checked)}
+                                onChange={(e) => {
+                                    const checked = e.target.checked;
+                                    setShowLogWhileRunning(checked);
+                                    GetSettings()
+                                        .then((saved) => SaveSettings({ ...saved, showLog: checked }))
+                                        .catch((err) => setSettingsError(String(err)));
+                                }}
                             />
                             Show Log
                         </label>
@@ -163,19 +460,86 @@ function App() {
                     <button className="btn secondary" disabled>
                         CHECK FOR UPDATES
                     </button>
+                    <div className="options">
+                        <button className="btn-show-log" onClick={repairFiles} disabled={isRunning || isLaunching}>
+                            REPAIR FILES
+                        </button>
+                        <label className="checkbox-label">
+                            <input
+                                type="checkbox"
+                                checked={repairUserFiles}
+                                onChange={(e) => setRepairUserFiles(e.target.checked)}
+                                disabled={isRunning || isLaunching}
+                            />
+                            Also reset my edited pack files
+                        </label>
+                    </div>
                     <button
                         className={`btn ${isRunning ? 'danger' : 'primary'}`}
                         onClick={launch}
-                        disabled={isLaunching && !isRunning}
+                        disabled={(isLaunching && !isRunning) || (!isRunning && !isValidUsername)}
                         style={isRunning ? { backgroundColor: '#e74c3c' } : {}}
                     >
-                        {isRunning ? "FORCE STOP" : (isLaunching ? "LAUNCHING..." : `PLAY (${username})`)}
+                        {isStopping ? "FORCE STOP" : isRunning ? "STOP" : (isLaunching ? "LAUNCHING..." : `PLAY (${playerName})`)}
                     </button>
                 </div>
 
                 <div className="status-bar">
                     STATUS: {status}
                 </div>
+
+                {instances.filter((inst) => inst.id !== "default").map((inst) => {
+                    const state = stateOf(inst.id);
+                    const running = isRunningStatus(state.status);
+                    const launching = isLaunchingStatus(state.status);
+                    return (
+                        <div className="status-bar" key={inst.id}>
+                            {inst.name}: {state.status}
+                            <button
+                                className="btn-show-log"
+                                onClick={() => running ? stop(inst.id) : launchInstance(inst.id)}
+                                disabled={(launching && !running) || (!running && !isValidUsername)}
+                            >
+                                {isStoppingStatus(state.status) ? "FORCE STOP" : running ? "STOP" : (launching ? "LAUNCHING..." : "PLAY")}
+                            </button>
+                        </div>
+                    );
+                })}
+
+                {settingsError && (
+                    <div className="status-bar">
+                        Settings can't be loaded and won't be saved: {settingsError}
+                        <button className="btn-show-log" onClick={resetSettings}>
+                            RESET SETTINGS
+                        </button>
+                    </div>
+                )}
+
+                {crash && Object.values(instanceStates).some((s) => s.sessionId === crash.sessionId && s.status === "Crashed") && (
+                    <div className="status-bar">
+                        {crash.exitReason}
+                        {crash.diagnoses?.map((d) => (
+                            <div key={d.ruleId}>{d.title}: {d.fix}</div>
+                        ))}
+                        {crash.crashReport && (
+                            <button className="btn-show-log" onClick={() => OpenCrashFile(crash.crashReport)}>
+                                CRASH REPORT
+                            </button>
+                        )}
+                        {crash.jvmErrorLog && (
+                            <button className="btn-show-log" onClick={() => OpenCrashFile(crash.jvmErrorLog)}>
+                                JVM LOG
+                            </button>
+                        )}
+                        <button className="btn-show-log" onClick={shareLog} disabled={shareStatus === "Uploading..."}>
+                            SHARE LOG
+                        </button>
+                        <button className="btn-show-log" onClick={exportDiagnostics}>
+                            EXPORT DIAGNOSTICS
+                        </button>
+                        {shareStatus && <div>{shareStatus}</div>}
+                    </div>
+                )}
             </div>
 
             {isConsoleOpen && (
@@ -1,15 +1,43 @@
 import { useEffect, useRef, useState } from 'react';
 import { ClipboardSetText } from "../../wailsjs/runtime";
 
+// Payload of the "log-record" event (gamelog.Record)
+export interface LogRecord {
+    stream: string;
+    time: string;
+    level: string;
+    thread: string;
+    logger: string;
+    message: string;
+}
+
 interface ConsoleProps {
     statusHistory: string[];
-    logs: string[];
+    logs: LogRecord[];
     onClose: () => void;
 }
 
+// Levels from least to most severe; the filter shows a level and above
+const LEVELS = ["TRACE", "DEBUG", "INFO", "WARN", "ERROR", "FATAL"];
+
+const severity = (level: string) => Math.max(LEVELS.indexOf(level), 0);
+
+// Renders a record like the game's plain log format
+const formatRecord = (rec: LogRecord) => {
+    if (!rec.level || !rec.time) {
+        return rec.message;
+    }
+    const logger = rec.logger ? ` (${rec.logger})` : ":";
+    return `[${rec.time}] [${rec.thread}/${rec.level}]${logger} ${rec.message}`;
+};
+
 export function Console({ statusHistory, logs, onClose }: ConsoleProps) {
     const endRef = useRef<HTMLDivElement>(null);
     const [autoScroll, setAutoScroll] = useState(true);
+    const [minLevel, setMinLevel] = useState("INFO");
+
+    // Lines without a level (plain output) are always shown
+    const visible = logs.filter((rec) => !rec.level || severity(rec.level) >= severity(minLevel));
 
     useEffect(() => {
         if (autoScroll) {
@@ -22,7 +50,7 @@ export function Console({ statusHistory, logs, onClose }: ConsoleProps) {
         if (statusHistory.length > 0 && logs.length > 0) {
             allLogs += "\n--- GAME OUTPUT ---\n";
         }
-        allLogs += logs.join("");
+        allLogs += logs.map(formatRecord).join("\n");
         ClipboardSetText(allLogs);
     };
 
@@ -32,6 +60,15 @@ export function Console({ statusHistory, logs, onClose }: ConsoleProps) {
                 <div className="console-header">
                     <span>GAME LOGS</span>
                     <div className="console-actions">
+                        <select
+                            value={minLevel}
+                            onChange={(e) => setMinLevel(e.target.value)}
+                            className="level-filter"
+                        >
+                            {LEVELS.map((level) => (
+                                <option key={level} value={level}>{level}</option>
+                            ))}
+                        </select>
                         <label className="checkbox-label" style={{ marginRight: '15px', color: '#ccc', fontSize: '0.9em' }}>
                             <input
                                 type="checkbox"
@@ -51,8 +88,13 @@ export function Console({ statusHistory, logs, onClose }: ConsoleProps) {
                     {statusHistory.length > 0 && logs.length > 0 && (
                         <div className="log-separator">--- GAME OUTPUT ---</div>
                     )}
-                    {logs.map((line, i) => (
-                        <div key={`log-${i}`} className="log-line">{line}</div>
+                    {visible.map((rec, i) => (
+                        <div
+                            key={`log-${i}`}
+                            className={`log-line ${rec.level ? `level-${rec.level.toLowerCase()}` : ""} stream-${rec.stream}`}
+                        >
+                            {formatRecord(rec)}
+                        </div>
                     ))}
                     <div ref={endRef} />
                 </div>
@@ -1,9 +1,74 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
+import {auth} from '../models';
+import {cache} from '../models';
 import {main} from '../models';
+import {display} from '../models';
+import {gamelog} from '../models';
+import {instance} from '../models';
+import {jvmargs} from '../models';
+import {launcher} from '../models';
+import {session} from '../models';
+import {settings} from '../models';
+import {repair} from '../models';
+import {share} from '../models';
 
-export function ForceStopGame():Promise<string>;
+export function CancelLogin():Promise<void>;
+
+export function CloneInstance(arg1:string,arg2:string):Promise<instance.Instance>;
+
+export function CollectGarbage():Promise<cache.GCResult>;
+
+export function CreateInstance(arg1:string,arg2:string,arg3:string,arg4:string):Promise<instance.Instance>;
+
+export function DeleteInstance(arg1:string):Promise<void>;
+
+export function ExportDiagnostics(arg1:string):Promise<string>;
+
+export function ForceStopGame(arg1:string):Promise<string>;
+
+export function GetSettings():Promise<settings.Settings>;
 
 export function GetSystemInfo():Promise<main.SystemInfo>;
 
-export function LaunchGame(arg1:string,arg2:number,arg3:boolean,arg4:string):Promise<string>;
+export function LaunchGame(arg1:string,arg2:number,arg3:boolean,arg4:string,arg5:display.Window):Promise<string>;
+
+export function LaunchInstance(arg1:string,arg2:string):Promise<string>;
+
+export function ListAccounts():Promise<Array<auth.Profile>>;
+
+export function ListInstances():Promise<Array<instance.Instance>>;
+
+export function ListJVMPresets(arg1:string):Promise<Array<jvmargs.Preset>>;
+
+export function ListLogs():Promise<Array<gamelog.LogInfo>>;
+
+export function ListSessions():Promise<Array<session.Session>>;
+
+export function LoginMicrosoft():Promise<auth.Profile>;
+
+export function LoginYggdrasil(arg1:string,arg2:string,arg3:string):Promise<auth.Profile>;
+
+export function OpenCrashFile(arg1:string):Promise<void>;
+
+export function PingServer(arg1:string):Promise<launcher.ServerStatus>;
+
+export function ReadLog(arg1:string):Promise<string>;
+
+export function RemoveAccount(arg1:string):Promise<void>;
+
+export function RenameInstance(arg1:string,arg2:string):Promise<void>;
+
+export function RepairInstance(arg1:string,arg2:boolean):Promise<repair.Report>;
+
+export function ResetSettings():Promise<settings.Settings>;
+
+export function SaveSettings(arg1:settings.Settings):Promise<void>;
+
+export function SelectAccount(arg1:string):Promise<void>;
+
+export function ShareLog(arg1:string,arg2:string):Promise<share.Result>;
+
+export function StopGame(arg1:string):Promise<string>;
+
+export function UpdateInstance(arg1:instance.Instance):Promise<void>;
@@ -2,14 +2,122 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
-export function ForceStopGame() {
-  return window['go']['main']['App']['ForceStopGame']();
+export function CancelLogin() {
+  return window['go']['main']['App']['CancelLogin']();
+}
+
+export function CloneInstance(arg1, arg2) {
+  return window['go']['main']['App']['CloneInstance'](arg1, arg2);
+}
+
+export function CollectGarbage() {
+  return window['go']['main']['App']['CollectGarbage']();
+}
+
+export function CreateInstance(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['CreateInstance'](arg1, arg2, arg3, arg4);
+}
+
+export function DeleteInstance(arg1) {
+  return window['go']['main']['App']['DeleteInstance'](arg1);
+}
+
+export function ExportDiagnostics(arg1) {
+  return window['go']['main']['App']['ExportDiagnostics'](arg1);
+}
+
+export function ForceStopGame(arg1) {
+  return window['go']['main']['App']['ForceStopGame'](arg1);
+}
+
+export function GetSettings() {
+  return window['go']['main']['App']['GetSettings']();
 }
 
 export function GetSystemInfo() {
   return window['go']['main']['App']['GetSystemInfo']();
 }
 
-export function LaunchGame(arg1, arg2, arg3, arg4) {
-  return window['go']['main']['App']['LaunchGame'](arg1, arg2, arg3, arg4);
+export function LaunchGame(arg1, arg2, arg3, arg4, arg5) {
+  return window['go']['main']['App']['LaunchGame'](arg1, arg2, arg3, arg4, arg5);
+}
+
+export function LaunchInstance(arg1, arg2) {
+  return window['go']['main']['App']['LaunchInstance'](arg1, arg2);
+}
+
+export function ListAccounts() {
+  return window['go']['main']['App']['ListAccounts']();
+}
+
+export function ListInstances() {
+  return window['go']['main']['App']['ListInstances']();
+}
+
+export function ListJVMPresets(arg1) {
+  return window['go']['main']['App']['ListJVMPresets'](arg1);
+}
+
+export function ListLogs() {
+  return window['go']['main']['App']['ListLogs']();
+}
+
+export function ListSessions() {
+  return window['go']['main']['App']['ListSessions']();
+}
+
+export function LoginMicrosoft() {
+  return window['go']['main']['App']['LoginMicrosoft']();
+}
+
+export function LoginYggdrasil(arg1, arg2, arg3) {
+  return window['go']['main']['App']['LoginYggdrasil'](arg1, arg2, arg3);
+}
+
+export function OpenCrashFile(arg1) {
+  return window['go']['main']['App']['OpenCrashFile'](arg1);
+}
+
+export function PingServer(arg1) {
+  return window['go']['main']['App']['PingServer'](arg1);
+}
+
+export function ReadLog(arg1) {
+  return window['go']['main']['App']['ReadLog'](arg1);
+}
+
+export function RemoveAccount(arg1) {
+  return window['go']['main']['App']['RemoveAccount'](arg1);
+}
+
+export function RenameInstance(arg1, arg2) {
+  return window['go']['main']['App']['RenameInstance'](arg1, arg2);
+}
+
+export function RepairInstance(arg1, arg2) {
+  return window['go']['main']['App']['RepairInstance'](arg1, arg2);
+}
+
+export function ResetSettings() {
+  return window['go']['main']['App']['ResetSettings']();
+}
+
+export function SaveSettings(arg1) {
+  return window['go']['main']['App']['SaveSettings'](arg1);
+}
+
+export function SelectAccount(arg1) {
+  return window['go']['main']['App']['SelectAccount'](arg1);
+}
+
+export function ShareLog(arg1, arg2) {
+  return window['go']['main']['App']['ShareLog'](arg1, arg2);
+}
+
+export function StopGame(arg1) {
+  return window['go']['main']['App']['StopGame'](arg1);
+}
+
+export function UpdateInstance(arg1) {
+  return window['go']['main']['App']['UpdateInstance'](arg1);
 }
@@ -1,11 +1,239 @@
+export namespace auth {
+	
+	export class Profile {
+	    id: string;
+	    username: string;
+	    type: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Profile(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.username = source["username"];
+	        this.type = source["type"];
+	    }
+	}
+
+}
+
+export namespace cache {
+	
+	export class GCResult {
+	    kept: number;
+	    removed: number;
+	    freedBytes: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new GCResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.kept = source["kept"];
+	        this.removed = source["removed"];
+	        this.freedBytes = source["freedBytes"];
+	    }
+	}
+
+}
+
+export namespace display {
+	
+	export class Window {
+	    width: number;
+	    height: number;
+	    fullscreen: boolean;
+	    rememberSize: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Window(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.width = source["width"];
+	        this.height = source["height"];
+	        this.fullscreen = source["fullscreen"];
+	        this.rememberSize = source["rememberSize"];
+	    }
+	}
+
+}
+
+export namespace gamelog {
+	
+	export class LogInfo {
+	    name: string;
+	    size: number;
+	    modTime: any;
+	    compressed: boolean;
+	    active: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new LogInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.size = source["size"];
+	        this.modTime = this.convertValues(source["modTime"], null);
+	        this.compressed = source["compressed"];
+	        this.active = source["active"];
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace instance {
+	
+	export class Instance {
+	    id: string;
+	    name: string;
+	    gameDir: string;
+	    versionId: string;
+	    loader: string;
+	    serverURL: string;
+	    javaPath: string;
+	    ramMB: number;
+	    jvmArgs: string[];
+	    jvmPreset: string;
+	    gameArgs: string[];
+	    window: display.Window;
+	    createdAt: any;
+	    lastPlayed: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new Instance(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.gameDir = source["gameDir"];
+	        this.versionId = source["versionId"];
+	        this.loader = source["loader"];
+	        this.serverURL = source["serverURL"];
+	        this.javaPath = source["javaPath"];
+	        this.ramMB = source["ramMB"];
+	        this.jvmArgs = source["jvmArgs"];
+	        this.jvmPreset = source["jvmPreset"];
+	        this.gameArgs = source["gameArgs"];
+	        this.window = this.convertValues(source["window"], display.Window);
+	        this.createdAt = this.convertValues(source["createdAt"], null);
+	        this.lastPlayed = this.convertValues(source["lastPlayed"], null);
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace jvmargs {
+	
+	export class Preset {
+	    id: string;
+	    name: string;
+	    description: string;
+	    jvmArgs: string[];
+	    gameArgs?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Preset(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.jvmArgs = source["jvmArgs"];
+	        this.gameArgs = source["gameArgs"];
+	    }
+	}
+
+}
+
+export namespace launcher {
+	
+	export class ServerStatus {
+	    motd: string;
+	    versionName: string;
+	    protocol: number;
+	    onlinePlayers: number;
+	    maxPlayers: number;
+	    playerSample: string[];
+	    latencyMs: number;
+	    legacy: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ServerStatus(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.motd = source["motd"];
+	        this.versionName = source["versionName"];
+	        this.protocol = source["protocol"];
+	        this.onlinePlayers = source["onlinePlayers"];
+	        this.maxPlayers = source["maxPlayers"];
+	        this.playerSample = source["playerSample"];
+	        this.latencyMs = source["latencyMs"];
+	        this.legacy = source["legacy"];
+	    }
+	}
+
+}
+
 export namespace main {
 	
 	export class SystemInfo {
 	    totalRAM: number;
+	    freeRAM: number;
 	    is32Bit: boolean;
 	    defaultRAM: number;
 	    minRAM: number;
 	    maxRAM: number;
+	    recommendedRAM: number;
+	    warnings: ram.Warning[];
 	
 	    static createFrom(source: any = {}) {
 	        return new SystemInfo(source);
@@ -14,12 +242,219 @@ export namespace main {
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
 	        this.totalRAM = source["totalRAM"];
+	        this.freeRAM = source["freeRAM"];
 	        this.is32Bit = source["is32Bit"];
 	        this.defaultRAM = source["defaultRAM"];
 	        this.minRAM = source["minRAM"];
 	        this.maxRAM = source["maxRAM"];
+	        this.recommendedRAM = source["recommendedRAM"];
+	        this.warnings = this.convertValues(source["warnings"], ram.Warning);
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace ram {
+	
+	export class Warning {
+	    code: string;
+	    message: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Warning(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.code = source["code"];
+	        this.message = source["message"];
+	    }
+	}
+
+}
+
+export namespace repair {
+	
+	export class Item {
+	    kind: string;
+	    path: string;
+	    problem: string;
+	    expected?: string;
+	    actual?: string;
+	    quarantined?: string;
+	    repaired: boolean;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Item(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.kind = source["kind"];
+	        this.path = source["path"];
+	        this.problem = source["problem"];
+	        this.expected = source["expected"];
+	        this.actual = source["actual"];
+	        this.quarantined = source["quarantined"];
+	        this.repaired = source["repaired"];
+	        this.error = source["error"];
+	    }
+	}
+	export class Report {
+	    startedAt: any;
+	    finishedAt: any;
+	    quarantineDir: string;
+	    checked: {[key: string]: number};
+	    items: Item[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Report(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.startedAt = this.convertValues(source["startedAt"], null);
+	        this.finishedAt = this.convertValues(source["finishedAt"], null);
+	        this.quarantineDir = source["quarantineDir"];
+	        this.checked = source["checked"];
+	        this.items = this.convertValues(source["items"], Item);
+	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace session {
+	
+	export class Session {
+	    id: string;
+	    instanceId: string;
+	    username: string;
+	    state: string;
+	    pid: number;
+	    startedAt: any;
+	    logFile: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Session(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.instanceId = source["instanceId"];
+	        this.username = source["username"];
+	        this.state = source["state"];
+	        this.pid = source["pid"];
+	        this.startedAt = this.convertValues(source["startedAt"], null);
+	        this.logFile = source["logFile"];
 	    }
+
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 
 }
 
+export namespace settings {
+	
+	export class Settings {
+	    version: number;
+	    username: string;
+	    ramMB: number;
+	    useFabric: boolean;
+	    serverURL: string;
+	    autoJoin: string;
+	    showLog: boolean;
+	    account: string;
+	    stopGraceSeconds: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Settings(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.version = source["version"];
+	        this.username = source["username"];
+	        this.ramMB = source["ramMB"];
+	        this.useFabric = source["useFabric"];
+	        this.serverURL = source["serverURL"];
+	        this.autoJoin = source["autoJoin"];
+	        this.showLog = source["showLog"];
+	        this.account = source["account"];
+	        this.stopGraceSeconds = source["stopGraceSeconds"];
+	    }
+	}
+
+}
+
+export namespace share {
+	
+	export class Result {
+	    id: string;
+	    url: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Result(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.url = source["url"];
+	    }
+	}
+
+}
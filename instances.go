@@ -0,0 +1,120 @@
+package main
+
+import (
+	"craft-launcher/launcher/instance"
+	"craft-launcher/launcher/integrity"
+	"craft-launcher/launcher/jvmargs"
+	"encoding/json"
+	"fmt"
+
+	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// ListInstances returns all game instances
+func (a *App) ListInstances() ([]instance.Instance, error) {
+	return a.instances.List()
+}
+
+// CreateInstance adds a new instance with its own game directory
+func (a *App) CreateInstance(name string, versionID string, loader string, serverURL string) (instance.Instance, error) {
+	return a.instances.Create(name, versionID, loader, serverURL)
+}
+
+// CloneInstance copies an instance and its game directory
+func (a *App) CloneInstance(id string, name string) (instance.Instance, error) {
+	return a.instances.Clone(id, name)
+}
+
+// RenameInstance changes an instance's display name
+func (a *App) RenameInstance(id string, name string) error {
+	return a.instances.Rename(id, name)
+}
+
+// UpdateInstance saves an instance's version, loader, Java and JVM settings.
+// The arguments are checked together with the preset's, as they'll launch.
+func (a *App) UpdateInstance(inst instance.Instance) error {
+	jvmArgs, gameArgs, _ := presetArgs(inst, a.packPresets(inst))
+	if err := jvmargs.Check(jvmArgs, gameArgs, inst.RamMB); err != nil {
+		return err
+	}
+	return a.instances.Update(inst)
+}
+
+// getInstance loads an instance. The default instance syncs with the
+// launcher's modpack server, as LaunchGame does, unless it has its own.
+func (a *App) getInstance(id string) (instance.Instance, error) {
+	inst, err := a.instances.Get(id)
+	if err == nil && inst.ID == instance.DefaultID && inst.ServerURL == "" {
+		inst.ServerURL = a.prefs().ServerURL
+	}
+	return inst, err
+}
+
+// ListJVMPresets returns the argument presets an instance can use: the
+// built-in ones and those its modpack ships
+func (a *App) ListJVMPresets(id string) ([]jvmargs.Preset, error) {
+	inst, err := a.getInstance(id)
+	if err != nil {
+		return nil, err
+	}
+	return jvmargs.MergePresets(a.packPresets(inst)), nil
+}
+
+// packPresets returns the presets an instance's modpack ships
+func (a *App) packPresets(inst instance.Instance) []jvmargs.Preset {
+	if inst.ServerURL == "" {
+		return nil
+	}
+	manifest, err := integrity.LoadLocalManifest(inst.GameDir)
+	if err != nil {
+		return nil
+	}
+	return manifestPresets(manifest)
+}
+
+// manifestPresets decodes the presets a modpack manifest ships
+func manifestPresets(manifest *integrity.Manifest) []jvmargs.Preset {
+	var presets []jvmargs.Preset
+	if len(manifest.JVMPresets) > 0 {
+		if err := json.Unmarshal(manifest.JVMPresets, &presets); err != nil {
+			fmt.Printf("Warning: Ignoring the modpack's JVM presets: %v\n", err)
+			return nil
+		}
+	}
+	return presets
+}
+
+// presetArgs returns an instance's JVM and game arguments, with its
+// preset's coming first unless the instance overrides them. ok is false if
+// the preset doesn't exist.
+func presetArgs(inst instance.Instance, packPresets []jvmargs.Preset) (jvmArgs, gameArgs []string, ok bool) {
+	jvmArgs, gameArgs = inst.JVMArgs, inst.GameArgs
+	if inst.JVMPreset == "" {
+		return jvmArgs, gameArgs, true
+	}
+	preset, ok := jvmargs.Find(jvmargs.MergePresets(packPresets), inst.JVMPreset)
+	if !ok {
+		return jvmArgs, gameArgs, false
+	}
+	jvmArgs, gameArgs = jvmargs.Merge(preset, jvmArgs, gameArgs)
+	return jvmArgs, gameArgs, true
+}
+
+// DeleteInstance removes an instance and its game directory
+func (a *App) DeleteInstance(id string) error {
+	if len(a.sessions.ForInstance(id)) > 0 {
+		return fmt.Errorf("instance is running")
+	}
+	return a.instances.Delete(id)
+}
+
+// LaunchInstance starts the game for an instance
+func (a *App) LaunchInstance(id string, username string) string {
+	inst, err := a.getInstance(id)
+	if err != nil {
+		msg := fmt.Sprintf("Error loading instance: %v", err)
+		wailsruntime.EventsEmit(a.ctx, "update-status", msg, "", id)
+		return msg
+	}
+	return a.launchInstance(inst, username)
+}
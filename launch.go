@@ -0,0 +1,350 @@
+package main
+
+import (
+	"craft-launcher/launcher"
+	"craft-launcher/launcher/auth"
+	"craft-launcher/launcher/crash"
+	"craft-launcher/launcher/diagnostics"
+	"craft-launcher/launcher/gamelog"
+	"craft-launcher/launcher/instance"
+	"craft-launcher/launcher/integrity"
+	"craft-launcher/launcher/jvmargs"
+	"craft-launcher/launcher/ram"
+	"craft-launcher/launcher/session"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Stages a launch can fail at before the game starts
+const (
+	stageSignIn = "Sign In Error"
+	stageUpdate = "Update Error"
+	stageLaunch = "Error"
+)
+
+// launchError is a launch that failed before the game started
+type launchError struct {
+	Stage string
+	Err   error
+}
+
+func (e *launchError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Stage, e.Err)
+}
+
+func (e *launchError) Unwrap() error {
+	return e.Err
+}
+
+// launchRequest says how to launch an instance and where its progress goes.
+// The callbacks get the session ID so the GUI can tell clients apart.
+type launchRequest struct {
+	Username   string // Empty uses the saved player name
+	UseAccount bool   // Play as the selected signed-in account, if any
+	SkipUpdate bool   // Don't sync with the modpack server first
+
+	Status func(sessionID, msg string)
+	Output func(sessionID, data string)               // Raw game output, optional
+	Record func(sessionID string, rec gamelog.Record) // Parsed game output, optional
+}
+
+// gameRun is one launch of an instance, shared by the GUI and the CLI
+type gameRun struct {
+	a         *App
+	req       launchRequest
+	inst      instance.Instance
+	sess      session.Session
+	account   *auth.Account
+	memory    ram.Inputs
+	ram       ram.Recommendation
+	opts      launcher.LaunchOptions
+	packRules []crash.Rule
+	logFile   *gamelog.Writer
+	cmd       *exec.Cmd
+	unlock    func() // Releases the instance's prepare lock
+}
+
+// gameExit is how a game run ended
+type gameExit struct {
+	Session  session.Session // Final state, with the tail of the output
+	ExitCode int
+	Stopped  bool          // Ended by StopGame or ForceStopGame
+	Err      error         // Set if the process failed and wasn't stopped
+	Crash    *crash.Report // Set with Err
+}
+
+// prepareLaunch signs in, syncs the instance with its modpack server and
+// works out the launch options in a new session
+func (a *App) prepareLaunch(inst instance.Instance, req launchRequest) (*gameRun, error) {
+	if err := os.MkdirAll(inst.GameDir, 0755); err != nil {
+		return nil, &launchError{stageLaunch, fmt.Errorf("creating game dir: %w", err)}
+	}
+
+	prefs := a.prefs()
+	username := req.Username
+	if username == "" {
+		username = prefs.Username
+	}
+	r := &gameRun{a: a, req: req, inst: inst}
+
+	// A signed-in account plays under its own name
+	if req.UseAccount && prefs.Account != "" {
+		var err error
+		if r.account, err = a.account(prefs.Account); err != nil {
+			return nil, &launchError{stageSignIn, err}
+		}
+		username = r.account.Username
+	} else if err := launcher.ValidateUsername(username); err != nil {
+		return nil, &launchError{stageLaunch, err}
+	}
+
+	ramMB := inst.RamMB
+	if ramMB <= 0 {
+		ramMB = prefs.RamMB
+	}
+	r.memory = a.ramInputs(inst)
+	r.ram = ram.Recommend(r.memory)
+	ramMB = r.ram.Clamp(ramMB)
+
+	r.sess = a.sessions.Begin(inst.ID, username)
+
+	// Keep the session's status and game output after the launcher closes
+	logFile, err := a.logs.Open()
+	if err != nil {
+		fmt.Printf("Warning: Failed to open session log: %v\n", err)
+	} else {
+		r.logFile = logFile
+		a.sessions.SetLog(r.sess.ID, logFile.Name(), logFile)
+	}
+
+	// Sessions of one instance share its game dir and natives, so one at a
+	// time updates them and extracts natives, until its game has started
+	lock := a.prepareLock(inst.ID)
+	if !lock.TryLock() {
+		r.status("Waiting for another launch of this instance...")
+		lock.Lock()
+	}
+	r.unlock = lock.Unlock
+
+	if inst.ServerURL != "" && !req.SkipUpdate {
+		if err := integrity.CheckAndUpdate(inst.GameDir, inst.ServerURL, r.status); err != nil {
+			return nil, r.fail(&launchError{stageUpdate, err})
+		}
+	}
+
+	// The user's own choice wins over the modpack's auto-join server
+	autoJoin := prefs.AutoJoin
+	var packPresets []jvmargs.Preset
+	if inst.ServerURL != "" {
+		if manifest, err := integrity.LoadLocalManifest(inst.GameDir); err == nil {
+			if autoJoin == "" {
+				autoJoin = manifest.AutoJoin
+			}
+			r.packRules = manifestCrashRules(manifest)
+			packPresets = manifestPresets(manifest)
+		}
+	}
+
+	jvmArgs, gameArgs, ok := presetArgs(inst, packPresets)
+	if !ok {
+		r.status(fmt.Sprintf("Warning: JVM preset %q not found, launching without it", inst.JVMPreset))
+	}
+
+	r.opts = launcher.LaunchOptions{
+		Username:       username,
+		Account:        r.account,
+		GameDir:        inst.GameDir,
+		SharedDir:      a.instances.SharedDir(),
+		RamMB:          ramMB,
+		VersionID:      inst.VersionID,
+		UseFabric:      inst.UseFabric(),
+		ServerAddress:  autoJoin,
+		JavaPath:       inst.JavaPath,
+		JVMArgs:        jvmArgs,
+		GameArgs:       gameArgs,
+		Window:         inst.Window,
+		Cache:          a.cache,
+		StatusCallback: r.status,
+		LogCallback: func(data string) {
+			a.sessions.Log(r.sess.ID, data)
+			if req.Output != nil {
+				req.Output(r.sess.ID, data)
+			}
+		},
+	}
+	if req.Record != nil {
+		r.opts.RecordCallback = func(rec gamelog.Record) {
+			req.Record(r.sess.ID, rec)
+		}
+	}
+
+	err = a.history.Add(diagnostics.LaunchRecord{
+		Time:          time.Now(),
+		SessionID:     r.sess.ID,
+		InstanceID:    inst.ID,
+		VersionID:     inst.VersionID,
+		Loader:        inst.Loader,
+		RamMB:         ramMB,
+		JavaPath:      inst.JavaPath,
+		JVMPreset:     inst.JVMPreset,
+		JVMArgs:       jvmArgs,
+		GameArgs:      gameArgs,
+		ServerAddress: autoJoin,
+	})
+	if err != nil {
+		fmt.Printf("Warning: Failed to record launch: %v\n", err)
+	}
+	return r, nil
+}
+
+// manifestCrashRules decodes the known-issue rules a modpack manifest ships
+func manifestCrashRules(manifest *integrity.Manifest) []crash.Rule {
+	var rules []crash.Rule
+	if len(manifest.CrashRules) > 0 {
+		if err := json.Unmarshal(manifest.CrashRules, &rules); err != nil {
+			fmt.Printf("Warning: Ignoring the modpack's crash rules: %v\n", err)
+			return nil
+		}
+	}
+	return rules
+}
+
+// status reports a launcher message and keeps it in the session log
+func (r *gameRun) status(msg string) {
+	if r.logFile != nil {
+		fmt.Fprintf(r.logFile, "[LAUNCHER] %s\n", msg)
+	}
+	r.req.Status(r.sess.ID, msg)
+}
+
+// fail ends a run that never started its game. The caller reports err.
+func (r *gameRun) fail(err error) error {
+	if r.logFile != nil {
+		fmt.Fprintf(r.logFile, "[LAUNCHER] %v\n", err)
+	}
+	r.a.sessions.End(r.sess.ID)
+	r.release()
+	r.close()
+	return err
+}
+
+// release lets the next launch of the instance prepare
+func (r *gameRun) release() {
+	if r.unlock != nil {
+		r.unlock()
+		r.unlock = nil
+	}
+}
+
+// prepareLock returns the lock launches of an instance prepare under
+func (a *App) prepareLock(instanceID string) *sync.Mutex {
+	a.prepareMu.Lock()
+	defer a.prepareMu.Unlock()
+	lock := a.prepareLocks[instanceID]
+	if lock == nil {
+		lock = &sync.Mutex{}
+		a.prepareLocks[instanceID] = lock
+	}
+	return lock
+}
+
+// close closes the session log. Status messages after it aren't logged.
+func (r *gameRun) close() {
+	if r.logFile != nil {
+		r.logFile.Close()
+		r.logFile = nil
+	}
+}
+
+// logName returns the session log's path, empty without one
+func (r *gameRun) logName() string {
+	if r.logFile == nil {
+		return ""
+	}
+	return r.logFile.Name()
+}
+
+// start downloads what the game needs and starts it
+func (r *gameRun) start() (session.Session, error) {
+	r.status("=== PLATFORM INFO ===")
+	r.status(fmt.Sprintf("OS: %s", runtime.GOOS))
+	r.status(fmt.Sprintf("Architecture: %s", runtime.GOARCH))
+	r.status(fmt.Sprintf("Username: %s", r.opts.Username))
+	if r.account != nil {
+		r.status(fmt.Sprintf("Account: %s (%s)", r.account.UUID(), r.account.Type))
+	} else {
+		r.status(fmt.Sprintf("UUID: %s (offline)", launcher.OfflineUUID(r.opts.Username)))
+	}
+	r.status(fmt.Sprintf("RAM Allocation: %d GiB (%d MiB)", r.opts.RamMB/1024, r.opts.RamMB))
+	r.status(fmt.Sprintf("System RAM: %d GiB (%d MiB, %d MiB free)", r.memory.TotalMB/1024, r.memory.TotalMB, r.memory.FreeMB))
+	for _, w := range r.ram.Warnings {
+		r.status(fmt.Sprintf("Warning: %s", w.Message))
+	}
+	r.status(fmt.Sprintf("Instance: %s", r.inst.Name))
+	r.status(fmt.Sprintf("Version: %s", r.inst.VersionID))
+	r.status(fmt.Sprintf("Session: %s", r.sess.ID))
+	r.status("=====================")
+
+	fmt.Printf("Starting launch for %s (session %s)...\n", r.opts.Username, r.sess.ID)
+
+	cmd, err := launcher.Launch(r.opts)
+	r.release()
+	if err != nil {
+		fmt.Printf("Error launching: %v\n", err)
+		return r.sess, r.fail(&launchError{stageLaunch, err})
+	}
+	r.cmd = cmd
+
+	started, err := r.a.sessions.Attach(r.sess.ID, cmd)
+	if err != nil {
+		fmt.Printf("Warning: %v\n", err)
+	}
+	if err := r.a.instances.MarkPlayed(r.inst.ID); err != nil {
+		fmt.Printf("Warning: Failed to update instance: %v\n", err)
+	}
+	r.status("Running")
+	return started, nil
+}
+
+// wait waits for the game to exit and diagnoses a crash
+func (r *gameRun) wait() gameExit {
+	waitErr := r.cmd.Wait()
+	launcher.FlushOutput(r.cmd)
+	final, _ := r.a.sessions.End(r.sess.ID)
+
+	exit := gameExit{Session: final, Stopped: final.State == session.StateStopping}
+	if r.cmd.ProcessState != nil {
+		exit.ExitCode = r.cmd.ProcessState.ExitCode()
+	}
+	if waitErr == nil || exit.Stopped {
+		fmt.Printf("Game process %s exited normally\n", r.sess.ID)
+		return exit
+	}
+
+	fmt.Printf("Game process %s exited with error: %v\n", r.sess.ID, waitErr)
+	exit.Err = waitErr
+	report := crash.Analyze(r.inst.GameDir, r.sess.StartedAt, r.cmd.ProcessState)
+	report.SessionID = r.sess.ID
+	for _, err := range report.Diagnose(crash.MergeRules(r.packRules), final.Output()) {
+		fmt.Printf("Warning: %v\n", err)
+	}
+	exit.Crash = report
+
+	r.status(fmt.Sprintf("Exit: %s", report.ExitReason))
+	if report.Exception != "" {
+		r.status(fmt.Sprintf("Cause: %s", report.Exception))
+	}
+	if len(report.SuspectedMods) > 0 {
+		r.status(fmt.Sprintf("Suspected mods: %s", strings.Join(report.SuspectedMods, ", ")))
+	}
+	for _, d := range report.Diagnoses {
+		r.status(fmt.Sprintf("%s: %s", d.Title, d.Fix))
+	}
+	return exit
+}
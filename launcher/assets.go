@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+
+	"craft-launcher/launcher/cache"
 )
 
 const AssetBaseURL = "https://resources.download.minecraft.net"
@@ -20,16 +22,17 @@ type Assets struct {
 	Objects map[string]AssetObject `json:"objects"`
 }
 
-// DownloadAssets downloads the asset index and all referenced assets
-func DownloadAssets(assetIndexDetails AssetIndex, gameDir string) error {
+// DownloadAssets downloads the asset index and all referenced assets into
+// the shared dir, through the object store when one is given
+func DownloadAssets(assetIndexDetails AssetIndex, sharedDir string, store *cache.Store) error {
 	// 1. Download Asset Index
-	indexesDir := filepath.Join(gameDir, "assets", "indexes")
+	indexesDir := filepath.Join(sharedDir, "assets", "indexes")
 	if err := os.MkdirAll(indexesDir, 0755); err != nil {
 		return err
 	}
 
 	indexPath := filepath.Join(indexesDir, assetIndexDetails.ID+".json")
-	if err := downloadFile(assetIndexDetails.URL, indexPath); err != nil {
+	if err := fetchFile(store, assetIndexDetails.Sha1, assetIndexDetails.URL, indexPath); err != nil {
 		return fmt.Errorf("failed to download asset index: %w", err)
 	}
 
@@ -46,13 +49,20 @@ func DownloadAssets(assetIndexDetails AssetIndex, gameDir string) error {
 	}
 
 	// 3. Download Objects
-	objectsDir := filepath.Join(gameDir, "assets", "objects")
+	objectsDir := filepath.Join(sharedDir, "assets", "objects")
 	for _, obj := range assets.Objects {
 		// Object path structure: /hash_prefix_2chars/full_hash
 		prefix := obj.Hash[:2]
 		objPath := filepath.Join(objectsDir, prefix, obj.Hash)
 		objURL := fmt.Sprintf("%s/%s/%s", AssetBaseURL, prefix, obj.Hash)
 
+		if store != nil {
+			if err := store.Fetch(obj.Hash, objURL, objPath); err != nil {
+				fmt.Printf("Failed to download asset %s: %v\n", obj.Hash, err)
+			}
+			continue
+		}
+
 		// Simple check if exists (should verify hash in production, keeping simple for now)
 		if _, err := os.Stat(objPath); os.IsNotExist(err) {
 			if err := os.MkdirAll(filepath.Dir(objPath), 0755); err != nil {
@@ -69,6 +79,15 @@ func DownloadAssets(assetIndexDetails AssetIndex, gameDir string) error {
 	return nil
 }
 
+// fetchFile downloads url to dest, going through the object store when one
+// is given so the file is only stored once per machine
+func fetchFile(store *cache.Store, sha1, url, dest string) error {
+	if store == nil {
+		return downloadFile(url, dest)
+	}
+	return store.Fetch(sha1, url, dest)
+}
+
 func downloadFile(url, dest string) error {
 	// Ensure parent directory exists
 	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
@@ -0,0 +1,87 @@
+// Package auth signs players in to online accounts and keeps their tokens
+// fresh. Offline play doesn't need it.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Account types
+const (
+	TypeMicrosoft = "msa"
+	TypeYggdrasil = "yggdrasil" // Community auth server, played through authlib-injector
+)
+
+// refreshMargin renews tokens this long before they expire, so a token
+// doesn't run out while the game starts
+const refreshMargin = 5 * time.Minute
+
+var (
+	ErrNotConfigured = errors.New("microsoft sign in is not configured in this build")
+	ErrDeclined      = errors.New("sign in was declined")
+	ErrCodeExpired   = errors.New("sign in code expired, try again")
+	ErrNoXboxAccount = errors.New("this Microsoft account has no Xbox profile, create one at xbox.com first")
+	ErrChildAccount  = errors.New("this is a child account, it must be added to a family by an adult")
+	ErrNotOwned      = errors.New("this account doesn't own Minecraft")
+)
+
+// Profile is the public part of an account, safe to show in the UI
+type Profile struct {
+	ID       string `json:"id"`       // Minecraft profile UUID without dashes
+	Username string `json:"username"` // Minecraft player name
+	Type     string `json:"type"`
+}
+
+// Account is a signed-in player with the tokens needed to play online
+type Account struct {
+	Profile
+	AccessToken  string    `json:"accessToken"` // Passed to the game
+	ExpiresAt    time.Time `json:"expiresAt"`
+	RefreshToken string    `json:"refreshToken"` // Renews AccessToken without the player
+
+	// Yggdrasil only
+	Server      string `json:"server,omitempty"` // API root
+	ClientToken string `json:"clientToken,omitempty"`
+}
+
+// UserType is passed to the game as ${user_type}
+func (a *Account) UserType() string {
+	if a.Type == TypeYggdrasil {
+		return "mojang"
+	}
+	return a.Type
+}
+
+// Expired reports whether the access token should be renewed before use
+func (a *Account) Expired() bool {
+	return time.Now().Add(refreshMargin).After(a.ExpiresAt)
+}
+
+// UUID returns the profile ID in dashed form
+func (p Profile) UUID() string {
+	id := p.ID
+	if len(id) != 32 {
+		return id
+	}
+	return fmt.Sprintf("%s-%s-%s-%s-%s", id[0:8], id[8:12], id[12:16], id[16:20], id[20:32])
+}
+
+// Prompt tells the player how to finish signing in on another device
+type Prompt struct {
+	UserCode        string    `json:"userCode"`
+	VerificationURI string    `json:"verificationUri"`
+	Message         string    `json:"message"`
+	ExpiresAt       time.Time `json:"expiresAt"`
+}
+
+// Authenticator keeps one type of account signed in. Each has its own
+// Login, as they need different things from the player.
+type Authenticator interface {
+	// Type is the Account.Type this authenticator creates
+	Type() string
+	// Refresh renews an account's tokens without asking the player
+	Refresh(ctx context.Context, acc *Account) (*Account, error)
+}
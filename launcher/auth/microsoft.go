@@ -0,0 +1,340 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ClientID is the Azure app registration the launcher signs in with.
+// It is injected at build time via -ldflags.
+var ClientID = ""
+
+const microsoftScope = "XboxLive.signin offline_access"
+
+// Endpoints are the URLs of each step of the Microsoft sign in chain
+type Endpoints struct {
+	DeviceCode     string
+	Token          string
+	XboxLive       string
+	XSTS           string
+	MinecraftLogin string
+	Profile        string
+}
+
+// MicrosoftEndpoints are the production endpoints
+var MicrosoftEndpoints = Endpoints{
+	DeviceCode:     "https://login.microsoftonline.com/consumers/oauth2/v2.0/devicecode",
+	Token:          "https://login.microsoftonline.com/consumers/oauth2/v2.0/token",
+	XboxLive:       "https://user.auth.xboxlive.com/user/authenticate",
+	XSTS:           "https://xsts.auth.xboxlive.com/xsts/authorize",
+	MinecraftLogin: "https://api.minecraftservices.com/authentication/login_with_xbox",
+	Profile:        "https://api.minecraftservices.com/minecraft/profile",
+}
+
+// XSTS error codes with a known cause
+var xstsErrors = map[int64]error{
+	2148916233: ErrNoXboxAccount,
+	2148916238: ErrChildAccount,
+}
+
+// Microsoft signs players in with the device code flow: the player enters
+// a code at microsoft.com/link, then the Microsoft token is exchanged for
+// Xbox Live, XSTS and finally Minecraft services tokens.
+type Microsoft struct {
+	ClientID  string
+	Endpoints Endpoints
+	Client    *http.Client
+
+	// sleep waits between token polls, overridable in tests
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+// NewMicrosoft creates an authenticator for the production endpoints
+func NewMicrosoft() *Microsoft {
+	return &Microsoft{
+		ClientID:  ClientID,
+		Endpoints: MicrosoftEndpoints,
+		Client:    &http.Client{Timeout: 30 * time.Second},
+		sleep:     sleepContext,
+	}
+}
+
+// Type implements Authenticator
+func (m *Microsoft) Type() string {
+	return TypeMicrosoft
+}
+
+// msToken is a Microsoft OAuth token response
+type msToken struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	Error        string `json:"error"`
+	Description  string `json:"error_description"`
+}
+
+// Login signs a player in, calling prompt with the code to enter
+func (m *Microsoft) Login(ctx context.Context, prompt func(Prompt)) (*Account, error) {
+	if m.ClientID == "" {
+		return nil, ErrNotConfigured
+	}
+
+	var code struct {
+		DeviceCode      string `json:"device_code"`
+		UserCode        string `json:"user_code"`
+		VerificationURI string `json:"verification_uri"`
+		ExpiresIn       int    `json:"expires_in"`
+		Interval        int    `json:"interval"`
+		Message         string `json:"message"`
+	}
+	form := url.Values{"client_id": {m.ClientID}, "scope": {microsoftScope}}
+	if err := m.postForm(ctx, m.Endpoints.DeviceCode, form, &code); err != nil {
+		return nil, fmt.Errorf("device code request failed: %w", err)
+	}
+
+	expires := time.Now().Add(time.Duration(code.ExpiresIn) * time.Second)
+	if prompt != nil {
+		prompt(Prompt{
+			UserCode:        code.UserCode,
+			VerificationURI: code.VerificationURI,
+			Message:         code.Message,
+			ExpiresAt:       expires,
+		})
+	}
+
+	// Poll until the player has entered the code
+	interval := time.Duration(code.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	form = url.Values{
+		"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+		"client_id":   {m.ClientID},
+		"device_code": {code.DeviceCode},
+	}
+	for {
+		if err := m.sleep(ctx, interval); err != nil {
+			return nil, err
+		}
+
+		token, err := m.requestToken(ctx, form)
+		if err != nil {
+			return nil, err
+		}
+		switch token.Error {
+		case "":
+			return m.signIn(ctx, token)
+		case "authorization_pending":
+			continue
+		case "slow_down":
+			interval += 5 * time.Second
+			continue
+		case "authorization_declined":
+			return nil, ErrDeclined
+		case "expired_token":
+			return nil, ErrCodeExpired
+		default:
+			return nil, fmt.Errorf("sign in failed: %s %s", token.Error, token.Description)
+		}
+	}
+}
+
+// Refresh implements Authenticator
+func (m *Microsoft) Refresh(ctx context.Context, acc *Account) (*Account, error) {
+	if m.ClientID == "" {
+		return nil, ErrNotConfigured
+	}
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"client_id":     {m.ClientID},
+		"scope":         {microsoftScope},
+		"refresh_token": {acc.RefreshToken},
+	}
+	token, err := m.requestToken(ctx, form)
+	if err != nil {
+		return nil, err
+	}
+	if token.Error != "" {
+		return nil, fmt.Errorf("session expired, sign in again: %s", token.Error)
+	}
+	// The refresh token isn't always rotated
+	if token.RefreshToken == "" {
+		token.RefreshToken = acc.RefreshToken
+	}
+	return m.signIn(ctx, token)
+}
+
+// requestToken posts to the token endpoint. OAuth errors come back as a
+// 400 with an error field, which is returned in the token for the caller.
+func (m *Microsoft) requestToken(ctx context.Context, form url.Values) (*msToken, error) {
+	var token msToken
+	err := m.postForm(ctx, m.Endpoints.Token, form, &token)
+	if err != nil && token.Error == "" {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	return &token, nil
+}
+
+// signIn exchanges a Microsoft token for a Minecraft account
+func (m *Microsoft) signIn(ctx context.Context, token *msToken) (*Account, error) {
+	// Xbox Live
+	var xbl xboxToken
+	err := m.postJSON(ctx, m.Endpoints.XboxLive, map[string]any{
+		"Properties": map[string]any{
+			"AuthMethod": "RPS",
+			"SiteName":   "user.auth.xboxlive.com",
+			"RpsTicket":  "d=" + token.AccessToken,
+		},
+		"RelyingParty": "http://auth.xboxlive.com",
+		"TokenType":    "JWT",
+	}, &xbl)
+	if err != nil {
+		return nil, fmt.Errorf("xbox live sign in failed: %w", err)
+	}
+
+	// XSTS
+	var xsts xboxToken
+	err = m.postJSON(ctx, m.Endpoints.XSTS, map[string]any{
+		"Properties": map[string]any{
+			"SandboxId":  "RETAIL",
+			"UserTokens": []string{xbl.Token},
+		},
+		"RelyingParty": "rp://api.minecraftservices.com/",
+		"TokenType":    "JWT",
+	}, &xsts)
+	if err != nil {
+		if known, ok := xstsErrors[xsts.XErr]; ok {
+			return nil, known
+		}
+		return nil, fmt.Errorf("xsts authorization failed: %w", err)
+	}
+	uhs := xsts.userHash()
+	if uhs == "" {
+		return nil, fmt.Errorf("xsts authorization failed: missing user hash")
+	}
+
+	// Minecraft services
+	var mc struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	err = m.postJSON(ctx, m.Endpoints.MinecraftLogin, map[string]any{
+		"identityToken": fmt.Sprintf("XBL3.0 x=%s;%s", uhs, xsts.Token),
+	}, &mc)
+	if err != nil {
+		return nil, fmt.Errorf("minecraft sign in failed: %w", err)
+	}
+
+	var profile struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, m.Endpoints.Profile, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+mc.AccessToken)
+	status, err := m.do(req, &profile)
+	if status == http.StatusNotFound {
+		return nil, ErrNotOwned
+	}
+	if err != nil {
+		return nil, fmt.Errorf("profile request failed: %w", err)
+	}
+
+	return &Account{
+		Profile: Profile{
+			ID:       strings.ReplaceAll(profile.ID, "-", ""),
+			Username: profile.Name,
+			Type:     TypeMicrosoft,
+		},
+		AccessToken:  mc.AccessToken,
+		ExpiresAt:    time.Now().Add(time.Duration(mc.ExpiresIn) * time.Second),
+		RefreshToken: token.RefreshToken,
+	}, nil
+}
+
+// xboxToken is an Xbox Live or XSTS response
+type xboxToken struct {
+	Token         string `json:"Token"`
+	DisplayClaims struct {
+		Xui []struct {
+			Uhs string `json:"uhs"`
+		} `json:"xui"`
+	} `json:"DisplayClaims"`
+	XErr int64 `json:"XErr"` // Set on XSTS errors
+}
+
+func (t *xboxToken) userHash() string {
+	if len(t.DisplayClaims.Xui) == 0 {
+		return ""
+	}
+	return t.DisplayClaims.Xui[0].Uhs
+}
+
+func (m *Microsoft) postForm(ctx context.Context, endpoint string, form url.Values, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	_, err = m.do(req, out)
+	return err
+}
+
+func (m *Microsoft) postJSON(ctx context.Context, endpoint string, body any, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	_, err = m.do(req, out)
+	return err
+}
+
+// do sends req and decodes the JSON response into out. Error responses
+// are decoded too when they carry JSON, so callers can read error fields.
+func (m *Microsoft) do(req *http.Request, out any) (int, error) {
+	req.Header.Set("Accept", "application/json")
+	resp, err := m.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, out); err != nil && resp.StatusCode < 300 {
+			return resp.StatusCode, fmt.Errorf("invalid response: %w", err)
+		}
+	}
+	if resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("%s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,213 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeMicrosoft stands in for every endpoint of the sign in chain
+type fakeMicrosoft struct {
+	mu          sync.Mutex
+	polls       int
+	xstsErr     int64
+	ownsGame    bool
+	refreshUsed string
+	keepRefresh bool // Leave the refresh token out of refresh responses
+}
+
+func (f *fakeMicrosoft) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	writeJSON := func(w http.ResponseWriter, status int, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(v)
+	}
+
+	mux.HandleFunc("/devicecode", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("client_id") != "test-client" || !strings.Contains(r.Form.Get("scope"), "offline_access") {
+			t.Errorf("unexpected device code request %v", r.Form)
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"device_code":      "device-123",
+			"user_code":        "ABCD-EFGH",
+			"verification_uri": "https://microsoft.com/link",
+			"expires_in":       900,
+			"interval":         5,
+			"message":          "Enter ABCD-EFGH",
+		})
+	})
+
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		switch r.Form.Get("grant_type") {
+		case "urn:ietf:params:oauth:grant-type:device_code":
+			if r.Form.Get("device_code") != "device-123" {
+				t.Errorf("unexpected device code %q", r.Form.Get("device_code"))
+			}
+			f.polls++
+			if f.polls < 2 {
+				writeJSON(w, http.StatusBadRequest, map[string]string{"error": "authorization_pending"})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"access_token": "ms-access", "refresh_token": "ms-refresh-1", "expires_in": 3600})
+		case "refresh_token":
+			f.refreshUsed = r.Form.Get("refresh_token")
+			if f.keepRefresh {
+				writeJSON(w, http.StatusOK, map[string]any{"access_token": "ms-access-2", "expires_in": 3600})
+				return
+			}
+			writeJSON(w, http.StatusOK, map[string]any{"access_token": "ms-access-2", "refresh_token": "ms-refresh-2", "expires_in": 3600})
+		default:
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		}
+	})
+
+	mux.HandleFunc("/xbl", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Properties struct{ RpsTicket string }
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if !strings.HasPrefix(body.Properties.RpsTicket, "d=ms-access") {
+			t.Errorf("unexpected RPS ticket %q", body.Properties.RpsTicket)
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"Token":         "xbl-token",
+			"DisplayClaims": map[string]any{"xui": []map[string]string{{"uhs": "hash"}}},
+		})
+	})
+
+	mux.HandleFunc("/xsts", func(w http.ResponseWriter, r *http.Request) {
+		if f.xstsErr != 0 {
+			writeJSON(w, http.StatusUnauthorized, map[string]any{"XErr": f.xstsErr})
+			return
+		}
+		var body struct {
+			Properties struct{ UserTokens []string }
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if len(body.Properties.UserTokens) != 1 || body.Properties.UserTokens[0] != "xbl-token" {
+			t.Errorf("unexpected user tokens %v", body.Properties.UserTokens)
+		}
+		writeJSON(w, http.StatusOK, map[string]any{
+			"Token":         "xsts-token",
+			"DisplayClaims": map[string]any{"xui": []map[string]string{{"uhs": "hash"}}},
+		})
+	})
+
+	mux.HandleFunc("/login_with_xbox", func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ IdentityToken string }
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.IdentityToken != "XBL3.0 x=hash;xsts-token" {
+			t.Errorf("unexpected identity token %q", body.IdentityToken)
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"access_token": "mc-token", "expires_in": 86400})
+	})
+
+	mux.HandleFunc("/profile", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer mc-token" {
+			t.Errorf("unexpected authorization %q", r.Header.Get("Authorization"))
+		}
+		if !f.ownsGame {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "NOT_FOUND"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]string{"id": "069a79f444e94726a5befca90e38aaf5", "name": "Notch"})
+	})
+	return mux
+}
+
+func newTestMicrosoft(t *testing.T, fake *fakeMicrosoft) *Microsoft {
+	srv := httptest.NewServer(fake.handler(t))
+	t.Cleanup(srv.Close)
+
+	m := NewMicrosoft()
+	m.ClientID = "test-client"
+	m.Endpoints = Endpoints{
+		DeviceCode:     srv.URL + "/devicecode",
+		Token:          srv.URL + "/token",
+		XboxLive:       srv.URL + "/xbl",
+		XSTS:           srv.URL + "/xsts",
+		MinecraftLogin: srv.URL + "/login_with_xbox",
+		Profile:        srv.URL + "/profile",
+	}
+	m.sleep = func(ctx context.Context, d time.Duration) error { return ctx.Err() }
+	return m
+}
+
+func TestMicrosoft_LoginAndRefresh(t *testing.T) {
+	fake := &fakeMicrosoft{ownsGame: true}
+	m := newTestMicrosoft(t, fake)
+
+	var prompt Prompt
+	acc, err := m.Login(context.Background(), func(p Prompt) { prompt = p })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prompt.UserCode != "ABCD-EFGH" || prompt.VerificationURI != "https://microsoft.com/link" {
+		t.Errorf("unexpected prompt %+v", prompt)
+	}
+	if fake.polls != 2 {
+		t.Errorf("expected to poll until authorized, polled %d times", fake.polls)
+	}
+	if acc.Username != "Notch" || acc.Type != TypeMicrosoft || acc.AccessToken != "mc-token" || acc.RefreshToken != "ms-refresh-1" {
+		t.Errorf("unexpected account %+v", acc)
+	}
+	if acc.UUID() != "069a79f4-44e9-4726-a5be-fca90e38aaf5" {
+		t.Errorf("unexpected UUID %s", acc.UUID())
+	}
+	if acc.Expired() {
+		t.Error("expected a fresh token")
+	}
+
+	refreshed, err := m.Refresh(context.Background(), acc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.refreshUsed != "ms-refresh-1" || refreshed.RefreshToken != "ms-refresh-2" {
+		t.Errorf("expected the refresh token to rotate, used %q, got %+v", fake.refreshUsed, refreshed)
+	}
+
+	// A response without a new refresh token keeps the old one
+	fake.keepRefresh = true
+	again, err := m.Refresh(context.Background(), refreshed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.RefreshToken != "ms-refresh-2" {
+		t.Errorf("expected the refresh token to be kept, got %q", again.RefreshToken)
+	}
+}
+
+func TestMicrosoft_Errors(t *testing.T) {
+	m := newTestMicrosoft(t, &fakeMicrosoft{xstsErr: 2148916233, ownsGame: true})
+	if _, err := m.Login(context.Background(), nil); !errors.Is(err, ErrNoXboxAccount) {
+		t.Errorf("expected ErrNoXboxAccount, got %v", err)
+	}
+
+	m = newTestMicrosoft(t, &fakeMicrosoft{})
+	if _, err := m.Login(context.Background(), nil); !errors.Is(err, ErrNotOwned) {
+		t.Errorf("expected ErrNotOwned, got %v", err)
+	}
+
+	m.ClientID = ""
+	if _, err := m.Login(context.Background(), nil); !errors.Is(err, ErrNotConfigured) {
+		t.Errorf("expected ErrNotConfigured, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	m = newTestMicrosoft(t, &fakeMicrosoft{ownsGame: true})
+	if _, err := m.Login(ctx, nil); err == nil {
+		t.Error("expected a cancelled login to fail")
+	}
+}
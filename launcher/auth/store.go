@@ -0,0 +1,252 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	// AccountsFile holds the encrypted accounts, stored in the data dir
+	AccountsFile = "accounts.dat"
+	// KeyFile holds the encryption key. It lives in the user's config dir,
+	// not the data dir, so a copied portable install carries no usable
+	// tokens.
+	KeyFile = "accounts.key"
+)
+
+// errUnreadable means the accounts file exists but can't be decrypted,
+// usually because the key was lost or the install was copied
+var errUnreadable = errors.New("accounts are unreadable, sign in again")
+
+var fileMagic = []byte("CLACCT1\n")
+
+// KeyDir returns where the accounts key is kept
+func KeyDir() string {
+	if configDir, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(configDir, "craft-launcher")
+	}
+	return "."
+}
+
+// Store keeps accounts on disk, encrypted with AES-GCM. Both files are only
+// readable by the current user.
+type Store struct {
+	path    string
+	keyPath string
+	mu      sync.Mutex
+}
+
+// NewStore creates a store backed by dir/AccountsFile and keyDir/KeyFile
+func NewStore(dir, keyDir string) *Store {
+	return &Store{
+		path:    filepath.Join(dir, AccountsFile),
+		keyPath: filepath.Join(keyDir, KeyFile),
+	}
+}
+
+// List returns the stored accounts
+func (s *Store) List() ([]Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load()
+}
+
+// Get returns the account with the given profile ID
+func (s *Store) Get(id string) (*Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	accounts, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	for i := range accounts {
+		if accounts[i].ID == id {
+			return &accounts[i], nil
+		}
+	}
+	return nil, fmt.Errorf("account %q not found", id)
+}
+
+// Put adds or replaces an account
+func (s *Store) Put(acc Account) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	accounts, err := s.loadWritable()
+	if err != nil {
+		return err
+	}
+	replaced := false
+	for i := range accounts {
+		if accounts[i].ID == acc.ID {
+			accounts[i] = acc
+			replaced = true
+		}
+	}
+	if !replaced {
+		accounts = append(accounts, acc)
+	}
+	return s.save(accounts)
+}
+
+// Remove deletes an account and its tokens
+func (s *Store) Remove(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	accounts, err := s.loadWritable()
+	if err != nil {
+		return err
+	}
+	kept := accounts[:0]
+	for _, acc := range accounts {
+		if acc.ID != id {
+			kept = append(kept, acc)
+		}
+	}
+	return s.save(kept)
+}
+
+func (s *Store) load() ([]Account, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, fileMagic) {
+		return nil, fmt.Errorf("%w: corrupt accounts file", errUnreadable)
+	}
+
+	key, err := s.key(false)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	data = data[len(fileMagic):]
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("%w: corrupt accounts file", errUnreadable)
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], fileMagic)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errUnreadable, err)
+	}
+
+	var accounts []Account
+	if err := json.Unmarshal(plain, &accounts); err != nil {
+		return nil, fmt.Errorf("%w: corrupt accounts file: %v", errUnreadable, err)
+	}
+	return accounts, nil
+}
+
+// loadWritable loads the accounts before changing them. An unreadable file
+// is moved aside and the store starts empty, so signing in again works.
+func (s *Store) loadWritable() ([]Account, error) {
+	accounts, err := s.load()
+	if !errors.Is(err, errUnreadable) {
+		return accounts, err
+	}
+	if err := os.Rename(s.path, s.path+".unreadable"); err != nil {
+		return nil, err
+	}
+	return nil, nil
+}
+
+func (s *Store) save(accounts []Account) error {
+	plain, err := json.Marshal(accounts)
+	if err != nil {
+		return err
+	}
+	key, err := s.key(true)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	data := append([]byte{}, fileMagic...)
+	data = append(data, nonce...)
+	data = gcm.Seal(data, nonce, plain, fileMagic)
+	return writePrivate(s.path, data)
+}
+
+// key reads the encryption key, creating it if allowed
+func (s *Store) key(create bool) ([]byte, error) {
+	key, err := os.ReadFile(s.keyPath)
+	if err == nil && len(key) == 32 {
+		return key, nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if !create {
+		return nil, fmt.Errorf("%w: the key is missing", errUnreadable)
+	}
+
+	key = make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err := writePrivate(s.keyPath, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// writePrivate atomically writes a file only the owner can read
+func writePrivate(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	if err := tmp.Chmod(0600); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStore_EncryptsAccounts(t *testing.T) {
+	dir, keyDir := t.TempDir(), t.TempDir()
+	s := NewStore(dir, keyDir)
+
+	acc := Account{
+		Profile:      Profile{ID: "069a79f444e94726a5befca90e38aaf5", Username: "Notch", Type: TypeMicrosoft},
+		AccessToken:  "secret-access",
+		RefreshToken: "secret-refresh",
+		ExpiresAt:    time.Now().Add(time.Hour).Round(0),
+	}
+	if err := s.Put(acc); err != nil {
+		t.Fatal(err)
+	}
+	acc.AccessToken = "secret-access-2"
+	if err := s.Put(acc); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, AccountsFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("secret")) || bytes.Contains(data, []byte("Notch")) {
+		t.Error("accounts file is not encrypted")
+	}
+	if info, err := os.Stat(filepath.Join(keyDir, KeyFile)); err != nil || (info.Mode().Perm()&0077 != 0 && os.PathSeparator == '/') {
+		t.Errorf("expected a private key file, got %v, %v", info, err)
+	}
+
+	accounts, err := NewStore(dir, keyDir).List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(accounts) != 1 || accounts[0].AccessToken != "secret-access-2" || !accounts[0].ExpiresAt.Equal(acc.ExpiresAt) {
+		t.Errorf("unexpected accounts %+v", accounts)
+	}
+
+	// Without the key the tokens are unreadable
+	if _, err := NewStore(dir, t.TempDir()).List(); err == nil {
+		t.Error("expected loading without the key to fail")
+	}
+
+	// Signing in again replaces the unreadable accounts with a new key
+	lost := NewStore(dir, t.TempDir())
+	if err := lost.Put(acc); err != nil {
+		t.Fatalf("expected signing in after losing the key to work: %v", err)
+	}
+	if accounts, err := lost.List(); err != nil || len(accounts) != 1 {
+		t.Errorf("expected the new sign in to be stored, got %+v, %v", accounts, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, AccountsFile+".unreadable")); err != nil {
+		t.Errorf("expected the unreadable accounts to be kept aside: %v", err)
+	}
+	s = lost
+
+	if err := s.Remove(acc.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get(acc.ID); err == nil {
+		t.Error("expected the account to be removed")
+	}
+}
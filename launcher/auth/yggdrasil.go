@@ -0,0 +1,252 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// apiLocationHeader points from a server's home page to its Yggdrasil API
+// root, so players can enter the short address
+const apiLocationHeader = "X-Authlib-Injector-API-Location"
+
+// Yggdrasil signs players in to a community auth server implementing the
+// Yggdrasil API, as used by authlib-injector
+type Yggdrasil struct {
+	Client *http.Client
+}
+
+// NewYggdrasil creates an authenticator for any Yggdrasil server
+func NewYggdrasil() *Yggdrasil {
+	return &Yggdrasil{Client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// Type implements Authenticator
+func (y *Yggdrasil) Type() string {
+	return TypeYggdrasil
+}
+
+// YggdrasilError is an error response from the auth server
+type YggdrasilError struct {
+	Status  int
+	Code    string `json:"error"`
+	Message string `json:"errorMessage"`
+}
+
+func (e *YggdrasilError) Error() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	if e.Code != "" {
+		return e.Code
+	}
+	return fmt.Sprintf("auth server returned %d", e.Status)
+}
+
+type yggdrasilProfile struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type yggdrasilSession struct {
+	AccessToken       string             `json:"accessToken"`
+	ClientToken       string             `json:"clientToken"`
+	AvailableProfiles []yggdrasilProfile `json:"availableProfiles"`
+	SelectedProfile   *yggdrasilProfile  `json:"selectedProfile"`
+}
+
+// ResolveAPIRoot follows the server's API location header, so either the
+// site address or the API root may be entered
+func (y *Yggdrasil) ResolveAPIRoot(ctx context.Context, server string) (string, error) {
+	if !strings.Contains(server, "://") {
+		server = "https://" + server
+	}
+	u, err := url.Parse(server)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid auth server %q", server)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := y.Client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("auth server unreachable: %w", err)
+	}
+	resp.Body.Close()
+
+	if location := resp.Header.Get(apiLocationHeader); location != "" {
+		ref, err := url.Parse(location)
+		if err != nil {
+			return "", fmt.Errorf("invalid API location %q", location)
+		}
+		u = resp.Request.URL.ResolveReference(ref)
+	}
+	return strings.TrimRight(u.String(), "/"), nil
+}
+
+// Metadata returns the server's API metadata, which authlib-injector is
+// given up front so the game doesn't have to fetch it
+func (y *Yggdrasil) Metadata(ctx context.Context, apiRoot string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, apiRoot+"/", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := y.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("metadata request failed: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid(data) {
+		return nil, fmt.Errorf("invalid metadata from %s", apiRoot)
+	}
+	return data, nil
+}
+
+// Login signs a player in with the auth server's username (usually an
+// email) and password. The password is not stored.
+func (y *Yggdrasil) Login(ctx context.Context, server, username, password string) (*Account, error) {
+	apiRoot, err := y.ResolveAPIRoot(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+	clientToken, err := newClientToken()
+	if err != nil {
+		return nil, err
+	}
+
+	var session yggdrasilSession
+	err = y.post(ctx, apiRoot+"/authserver/authenticate", map[string]any{
+		"agent":       map[string]any{"name": "Minecraft", "version": 1},
+		"username":    username,
+		"password":    password,
+		"clientToken": clientToken,
+		"requestUser": true,
+	}, &session)
+	if err != nil {
+		return nil, err
+	}
+
+	// Players with several characters use the first until they pick one
+	if session.SelectedProfile == nil {
+		if len(session.AvailableProfiles) == 0 {
+			return nil, fmt.Errorf("this account has no character on the auth server")
+		}
+		acc := y.account(apiRoot, &session)
+		return y.refresh(ctx, acc, &session.AvailableProfiles[0])
+	}
+	return y.account(apiRoot, &session), nil
+}
+
+// Refresh checks the access token and renews it if the server no longer
+// accepts it
+func (y *Yggdrasil) Refresh(ctx context.Context, acc *Account) (*Account, error) {
+	err := y.post(ctx, acc.Server+"/authserver/validate", map[string]any{
+		"accessToken": acc.AccessToken,
+		"clientToken": acc.ClientToken,
+	}, nil)
+	if err == nil {
+		return acc, nil
+	}
+	if _, ok := err.(*YggdrasilError); !ok {
+		return nil, err
+	}
+	return y.refresh(ctx, acc, nil)
+}
+
+func (y *Yggdrasil) refresh(ctx context.Context, acc *Account, selectProfile *yggdrasilProfile) (*Account, error) {
+	body := map[string]any{
+		"accessToken": acc.AccessToken,
+		"clientToken": acc.ClientToken,
+		"requestUser": true,
+	}
+	if selectProfile != nil {
+		body["selectedProfile"] = selectProfile
+	}
+
+	var session yggdrasilSession
+	if err := y.post(ctx, acc.Server+"/authserver/refresh", body, &session); err != nil {
+		return nil, fmt.Errorf("session expired, sign in again: %w", err)
+	}
+	if session.SelectedProfile == nil {
+		session.SelectedProfile = selectProfile
+	}
+	if session.SelectedProfile == nil {
+		session.SelectedProfile = &yggdrasilProfile{ID: acc.ID, Name: acc.Username}
+	}
+	return y.account(acc.Server, &session), nil
+}
+
+func (y *Yggdrasil) account(apiRoot string, session *yggdrasilSession) *Account {
+	acc := &Account{
+		Profile: Profile{Type: TypeYggdrasil},
+		// Tokens carry no expiry, Refresh validates them before each launch
+		AccessToken: session.AccessToken,
+		Server:      apiRoot,
+		ClientToken: session.ClientToken,
+	}
+	if p := session.SelectedProfile; p != nil {
+		acc.ID = strings.ReplaceAll(p.ID, "-", "")
+		acc.Username = p.Name
+	}
+	return acc
+}
+
+// post sends a JSON request. Successful responses without a body (validate
+// answers 204) leave out untouched.
+func (y *Yggdrasil) post(ctx context.Context, endpoint string, body any, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := y.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("auth server unreachable: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 1024*1024))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		yerr := &YggdrasilError{Status: resp.StatusCode}
+		json.Unmarshal(respBody, yerr)
+		return yerr
+	}
+	if out != nil && len(respBody) > 0 {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return fmt.Errorf("invalid response: %w", err)
+		}
+	}
+	return nil
+}
+
+func newClientToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// fakeYggdrasil stands in for a community auth server
+type fakeYggdrasil struct {
+	token      string
+	refreshes  int
+	validToken bool
+}
+
+func (f *fakeYggdrasil) handler(t *testing.T) http.Handler {
+	mux := http.NewServeMux()
+	writeJSON := func(w http.ResponseWriter, status int, v any) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(v)
+	}
+	steve := map[string]string{"id": "8667ba71b85a4004af54457a9734eed7", "name": "Steve"}
+
+	// The site points at its API
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(apiLocationHeader, "/api/yggdrasil/")
+		w.Write([]byte("<html></html>"))
+	})
+	mux.HandleFunc("/api/yggdrasil/", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]any{"meta": map[string]string{"serverName": "Test"}, "skinDomains": []string{"example.com"}})
+	})
+
+	mux.HandleFunc("/api/yggdrasil/authserver/authenticate", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Username, Password, ClientToken string
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.Password != "hunter2" {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "ForbiddenOperationException", "errorMessage": "Invalid credentials."})
+			return
+		}
+		f.token = "token-1"
+		// Several characters and none picked yet
+		writeJSON(w, http.StatusOK, map[string]any{
+			"accessToken":       f.token,
+			"clientToken":       body.ClientToken,
+			"availableProfiles": []map[string]string{steve, {"id": "00000000000000000000000000000002", "name": "Alex"}},
+		})
+	})
+
+	mux.HandleFunc("/api/yggdrasil/authserver/refresh", func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			AccessToken, ClientToken string
+			SelectedProfile          *yggdrasilProfile
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		if body.AccessToken != f.token {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "ForbiddenOperationException", "errorMessage": "Invalid token."})
+			return
+		}
+		f.refreshes++
+		f.token = "token-2"
+		f.validToken = true
+		writeJSON(w, http.StatusOK, map[string]any{"accessToken": f.token, "clientToken": body.ClientToken, "selectedProfile": steve})
+	})
+
+	mux.HandleFunc("/api/yggdrasil/authserver/validate", func(w http.ResponseWriter, r *http.Request) {
+		var body struct{ AccessToken string }
+		json.NewDecoder(r.Body).Decode(&body)
+		if !f.validToken || body.AccessToken != f.token {
+			writeJSON(w, http.StatusForbidden, map[string]string{"error": "ForbiddenOperationException", "errorMessage": "Invalid token."})
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	return mux
+}
+
+func TestYggdrasil_LoginValidateRefresh(t *testing.T) {
+	fake := &fakeYggdrasil{}
+	srv := httptest.NewServer(fake.handler(t))
+	defer srv.Close()
+	y := NewYggdrasil()
+	ctx := context.Background()
+
+	if _, err := y.Login(ctx, srv.URL, "steve@example.com", "wrong"); err == nil || err.Error() != "Invalid credentials." {
+		t.Errorf("expected the server's error message, got %v", err)
+	}
+
+	acc, err := y.Login(ctx, srv.URL, "steve@example.com", "hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if acc.Server != srv.URL+"/api/yggdrasil" {
+		t.Errorf("expected the API root from the location header, got %s", acc.Server)
+	}
+	if acc.Username != "Steve" || acc.ID != "8667ba71b85a4004af54457a9734eed7" || acc.AccessToken != "token-2" || acc.ClientToken == "" {
+		t.Errorf("expected the first character to be selected, got %+v", acc)
+	}
+	if acc.UserType() != "mojang" || !acc.Expired() {
+		t.Errorf("unexpected user type %q or expiry %v", acc.UserType(), acc.ExpiresAt)
+	}
+
+	// A valid token is kept as is
+	same, err := y.Refresh(ctx, acc)
+	if err != nil || same.AccessToken != "token-2" || fake.refreshes != 1 {
+		t.Errorf("expected a valid token to be kept, got %+v, %v", same, err)
+	}
+
+	// An invalidated token is refreshed
+	fake.validToken = false
+	renewed, err := y.Refresh(ctx, acc)
+	if err != nil || renewed.Username != "Steve" || fake.refreshes != 2 {
+		t.Errorf("expected the token to be refreshed, got %+v, %v", renewed, err)
+	}
+
+	meta, err := y.Metadata(ctx, acc.Server)
+	if err != nil || !json.Valid(meta) {
+		t.Errorf("unexpected metadata %s, %v", meta, err)
+	}
+}
@@ -0,0 +1,180 @@
+package launcher
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AuthlibInjectorURL describes the latest authlib-injector release
+var AuthlibInjectorURL = "https://authlib-injector.yushi.moe/artifact/latest.json"
+
+// AuthlibInjectorDir holds downloaded authlib-injector jars in the shared dir
+const AuthlibInjectorDir = "authlib-injector"
+
+const (
+	// authlibReleaseFile caches the release info in AuthlibInjectorDir
+	authlibReleaseFile = "release.json"
+	// authlibCheckInterval is how long the cached release info is trusted
+	authlibCheckInterval = 24 * time.Hour
+)
+
+type authlibRelease struct {
+	Version     string `json:"version"`
+	DownloadURL string `json:"download_url"`
+	Checksums   struct {
+		SHA256 string `json:"sha256"`
+	} `json:"checksums"`
+}
+
+// EnsureAuthlibInjector returns the latest authlib-injector jar in
+// sharedDir, downloading and verifying it if needed. Without a connection
+// the newest jar already downloaded is used.
+func EnsureAuthlibInjector(sharedDir string) (string, error) {
+	dir := filepath.Join(sharedDir, AuthlibInjectorDir)
+
+	release := cachedAuthlibInjector(dir)
+	var err error
+	if release == nil {
+		if release, err = latestAuthlibInjector(); err == nil {
+			saveAuthlibRelease(dir, release)
+		}
+	}
+	if err != nil {
+		if jar := newestAuthlibInjector(dir); jar != "" {
+			fmt.Printf("Warning: Could not check for authlib-injector updates, using %s: %v\n", filepath.Base(jar), err)
+			return jar, nil
+		}
+		return "", fmt.Errorf("failed to get authlib-injector: %w", err)
+	}
+
+	jar := filepath.Join(dir, fmt.Sprintf("authlib-injector-%s.jar", release.Version))
+	if sum, err := fileSHA256(jar); err == nil && sum == release.Checksums.SHA256 {
+		return jar, nil
+	}
+
+	tmp := jar + ".tmp"
+	if err := downloadFile(release.DownloadURL, tmp); err != nil {
+		os.Remove(tmp)
+		return "", fmt.Errorf("failed to download authlib-injector: %w", err)
+	}
+	sum, err := fileSHA256(tmp)
+	if err != nil || sum != release.Checksums.SHA256 {
+		os.Remove(tmp)
+		return "", fmt.Errorf("authlib-injector %s failed verification", release.Version)
+	}
+	if err := os.Rename(tmp, jar); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	return jar, nil
+}
+
+// AuthlibInjectorArgs are the JVM arguments that point the game at a
+// Yggdrasil server. metadata is the server's API metadata, prefetched so
+// the game starts without waiting for it.
+func AuthlibInjectorArgs(jar, apiRoot string, metadata []byte) []string {
+	args := []string{fmt.Sprintf("-javaagent:%s=%s", jar, apiRoot)}
+	if len(metadata) > 0 {
+		args = append(args, "-Dauthlibinjector.yggdrasil.prefetched="+base64.StdEncoding.EncodeToString(metadata))
+	}
+	return args
+}
+
+func latestAuthlibInjector() (*authlibRelease, error) {
+	client := &http.Client{Timeout: 15 * time.Second}
+	resp, err := client.Get(AuthlibInjectorURL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("bad status: %s", resp.Status)
+	}
+
+	var release authlibRelease
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return nil, err
+	}
+	if err := validAuthlibRelease(&release); err != nil {
+		return nil, err
+	}
+	return &release, nil
+}
+
+func validAuthlibRelease(release *authlibRelease) error {
+	// The version ends up in a file name
+	if release.Version == "" || strings.ContainsAny(release.Version, `/\`) || release.DownloadURL == "" || release.Checksums.SHA256 == "" {
+		return fmt.Errorf("incomplete release info")
+	}
+	return nil
+}
+
+// cachedAuthlibInjector returns the release info saved by the last check,
+// or nil if there is none or it's too old
+func cachedAuthlibInjector(dir string) *authlibRelease {
+	path := filepath.Join(dir, authlibReleaseFile)
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > authlibCheckInterval {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var release authlibRelease
+	if json.Unmarshal(data, &release) != nil || validAuthlibRelease(&release) != nil {
+		return nil
+	}
+	return &release
+}
+
+func saveAuthlibRelease(dir string, release *authlibRelease) {
+	data, err := json.Marshal(release)
+	if err == nil {
+		err = os.MkdirAll(dir, 0755)
+	}
+	if err == nil {
+		err = os.WriteFile(filepath.Join(dir, authlibReleaseFile), data, 0644)
+	}
+	if err != nil {
+		fmt.Printf("Warning: Failed to cache authlib-injector release info: %v\n", err)
+	}
+}
+
+// newestAuthlibInjector returns the most recently downloaded jar
+func newestAuthlibInjector(dir string) string {
+	jars, _ := filepath.Glob(filepath.Join(dir, "authlib-injector-*.jar"))
+	if len(jars) == 0 {
+		return ""
+	}
+	sort.Slice(jars, func(i, j int) bool {
+		a, _ := os.Stat(jars[i])
+		b, _ := os.Stat(jars[j])
+		return a != nil && b != nil && a.ModTime().After(b.ModTime())
+	})
+	return jars[0]
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,85 @@
+package launcher
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestEnsureAuthlibInjector(t *testing.T) {
+	jar := []byte("PK fake agent jar")
+	sum := sha256.Sum256(jar)
+	checksum := hex.EncodeToString(sum[:])
+	downloads, checks := 0, 0
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/latest.json":
+			checks++
+			json.NewEncoder(w).Encode(map[string]any{
+				"version":      "1.2.5",
+				"download_url": "http://" + r.Host + "/authlib-injector.jar",
+				"checksums":    map[string]string{"sha256": checksum},
+			})
+		case "/authlib-injector.jar":
+			downloads++
+			w.Write(jar)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	oldURL := AuthlibInjectorURL
+	defer func() { AuthlibInjectorURL = oldURL }()
+	AuthlibInjectorURL = srv.URL + "/latest.json"
+
+	dir := t.TempDir()
+	path, err := EnsureAuthlibInjector(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filepath.Base(path) != "authlib-injector-1.2.5.jar" {
+		t.Errorf("unexpected jar %s", path)
+	}
+
+	// A verified jar isn't downloaded again, and the release is checked daily
+	if _, err := EnsureAuthlibInjector(dir); err != nil || downloads != 1 {
+		t.Errorf("expected one download, got %d (%v)", downloads, err)
+	}
+	if checks != 1 {
+		t.Errorf("expected the release info to be cached, got %d checks", checks)
+	}
+
+	// A tampered jar is replaced
+	os.WriteFile(path, []byte("tampered"), 0644)
+	if _, err := EnsureAuthlibInjector(dir); err != nil || downloads != 2 {
+		t.Errorf("expected tampered jar to be downloaded again, got %d (%v)", downloads, err)
+	}
+
+	// Offline, the downloaded jar is used
+	AuthlibInjectorURL = srv.URL + "/missing.json"
+	if offline, err := EnsureAuthlibInjector(dir); err != nil || offline != path {
+		t.Errorf("expected the downloaded jar offline, got %s, %v", offline, err)
+	}
+
+	// A jar that doesn't match the published checksum is rejected
+	checksum = strings.Repeat("0", 64)
+	AuthlibInjectorURL = srv.URL + "/latest.json"
+	if _, err := EnsureAuthlibInjector(t.TempDir()); err == nil {
+		t.Error("expected a checksum mismatch to fail")
+	}
+}
+
+func TestAuthlibInjectorArgs(t *testing.T) {
+	args := AuthlibInjectorArgs("/jars/ai.jar", "https://auth.example.com/api/yggdrasil", []byte(`{"meta":{}}`))
+	if len(args) != 2 || args[0] != "-javaagent:/jars/ai.jar=https://auth.example.com/api/yggdrasil" || args[1] != "-Dauthlibinjector.yggdrasil.prefetched=eyJtZXRhIjp7fX0=" {
+		t.Errorf("unexpected args %v", args)
+	}
+}
@@ -0,0 +1,543 @@
+// Package cache is a content-addressed store shared by every data dir on the
+// machine. Libraries and assets are kept once under their SHA-1 and
+// hardlinked into each game dir, falling back to a copy across volumes.
+package cache
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	// ObjectsDir holds the objects, fanned out by the first two hash chars
+	ObjectsDir = "objects"
+	// RefsFile maps each object hash to the paths linked to it
+	RefsFile = "refs.json"
+	// RefsLockFile is locked while a process updates RefsFile
+	RefsLockFile = "refs.lock"
+
+	tmpDir = "tmp"
+)
+
+// Dir returns the machine-wide store location (the user's cache dir)
+func Dir() string {
+	if cacheDir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(cacheDir, "craft-launcher", "store")
+	}
+	return filepath.Join(os.TempDir(), "craft-launcher-store")
+}
+
+// GCResult summarizes a garbage collection run
+type GCResult struct {
+	Kept       int   `json:"kept"`       // Objects still referenced
+	Removed    int   `json:"removed"`    // Objects deleted
+	FreedBytes int64 `json:"freedBytes"` // Size of the deleted objects
+}
+
+// Store fetches files into the object store and links them into place.
+// References are kept in memory and merged into RefsFile by Flush, so
+// several launcher processes can share the store.
+type Store struct {
+	root     string
+	download func(url, dest string) error
+	mu       sync.Mutex
+	refs     map[string][]string // hash -> absolute paths, nil until loaded
+	added    map[string][]string // References not written yet
+}
+
+// NewStore creates a store rooted at dir
+func NewStore(root string) *Store {
+	return &Store{root: root, download: httpDownload}
+}
+
+// Root returns the store directory
+func (s *Store) Root() string {
+	return s.root
+}
+
+// Fetch makes dest a link to the object with the given SHA-1, downloading it
+// from url only if the store doesn't have it yet. A matching file already at
+// dest is adopted instead of downloaded. An empty hash means none is
+// published: an existing dest is trusted, otherwise the download is hashed.
+func (s *Store) Fetch(hash, url, dest string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hash = strings.ToLower(hash)
+	if hash == "" {
+		if _, err := os.Stat(dest); err == nil {
+			return s.importFile(dest)
+		}
+		sum, err := s.downloadObject(url, "")
+		if err != nil {
+			return err
+		}
+		return s.link(sum, dest)
+	}
+	if !validHash(hash) {
+		return fmt.Errorf("invalid sha1 %q", hash)
+	}
+
+	if _, err := os.Stat(s.objectPath(hash)); os.IsNotExist(err) {
+		if sum, err := fileSHA1(dest); err == nil && sum == hash {
+			if err := s.adopt(dest, hash); err != nil {
+				return err
+			}
+		} else if _, err := s.downloadObject(url, hash); err != nil {
+			return err
+		}
+	}
+	return s.link(hash, dest)
+}
+
+// Import moves an existing file into the store and links it back in place
+func (s *Store) Import(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.importFile(path)
+}
+
+// Evict removes an object so the next Fetch downloads it again. Paths
+// still linked to it keep their copy.
+func (s *Store) Evict(hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hash = strings.ToLower(hash)
+	if !validHash(hash) {
+		return fmt.Errorf("invalid sha1 %q", hash)
+	}
+	if err := os.Remove(s.objectPath(hash)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// RefCount returns how many paths reference the object
+func (s *Store) RefCount(hash string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.loadRefs(); err != nil {
+		return 0, err
+	}
+	return len(s.refs[strings.ToLower(hash)]), nil
+}
+
+// Flush adds the new references to the reference table on disk, keeping
+// those other processes wrote since it was loaded
+func (s *Store) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.added) == 0 {
+		return nil
+	}
+	unlock, err := s.lockRefs()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	if err := s.reloadRefs(); err != nil {
+		return err
+	}
+	return s.saveRefs()
+}
+
+// GC drops references to paths that no longer hold their object and deletes
+// every object nothing references
+func (s *Store) GC() (GCResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var result GCResult
+	unlock, err := s.lockRefs()
+	if err != nil {
+		return result, err
+	}
+	defer unlock()
+	if err := s.reloadRefs(); err != nil {
+		return result, err
+	}
+
+	// Leftovers from interrupted downloads
+	os.RemoveAll(filepath.Join(s.root, tmpDir))
+
+	live := make(map[string]bool)
+	err = filepath.Walk(filepath.Join(s.root, ObjectsDir), func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		hash := info.Name()
+		var kept []string
+		for _, ref := range s.refs[hash] {
+			if refInfo, err := os.Stat(ref); err == nil && holdsObject(ref, refInfo, info) {
+				kept = append(kept, ref)
+			}
+		}
+		if len(kept) > 0 {
+			s.refs[hash] = kept
+			live[hash] = true
+			result.Kept++
+			return nil
+		}
+
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		result.Removed++
+		result.FreedBytes += info.Size()
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	for hash := range s.refs {
+		if !live[hash] {
+			delete(s.refs, hash)
+		}
+	}
+	if err := s.saveRefs(); err != nil {
+		return result, err
+	}
+	return result, nil
+}
+
+// holdsObject reports whether the referencing file at path still is the
+// object: a hardlink to it, or a copy with its hash where linking wasn't
+// possible
+func holdsObject(path string, ref, object os.FileInfo) bool {
+	if os.SameFile(ref, object) {
+		return true
+	}
+	if ref.Size() != object.Size() {
+		return false
+	}
+	sum, err := fileSHA1(path)
+	return err == nil && sum == object.Name()
+}
+
+func (s *Store) objectPath(hash string) string {
+	return filepath.Join(s.root, ObjectsDir, hash[:2], hash)
+}
+
+func (s *Store) importFile(path string) error {
+	sum, err := fileSHA1(path)
+	if err != nil {
+		return err
+	}
+	if err := s.adopt(path, sum); err != nil {
+		return err
+	}
+	return s.link(sum, path)
+}
+
+// adopt stores the file at path as the object for hash
+func (s *Store) adopt(path, hash string) error {
+	obj := s.objectPath(hash)
+	if _, err := os.Stat(obj); err == nil {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(obj), 0755); err != nil {
+		return err
+	}
+	if err := os.Link(path, obj); err == nil {
+		return nil
+	}
+
+	// Different volume: copy through a temp file so a partial copy never
+	// shows up as an object
+	tmp, err := s.tempPath()
+	if err != nil {
+		return err
+	}
+	if err := copyFile(path, tmp); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return s.place(tmp, hash)
+}
+
+// downloadObject fetches url into the store. If hash is set the download
+// must match it; the object's hash is returned either way.
+func (s *Store) downloadObject(url, hash string) (string, error) {
+	tmp, err := s.tempPath()
+	if err != nil {
+		return "", err
+	}
+	if err := s.download(url, tmp); err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+
+	sum, err := fileSHA1(tmp)
+	if err != nil {
+		os.Remove(tmp)
+		return "", err
+	}
+	if hash != "" && sum != hash {
+		os.Remove(tmp)
+		return "", fmt.Errorf("checksum mismatch for %s: expected %s, got %s", url, hash, sum)
+	}
+	return sum, s.place(tmp, sum)
+}
+
+// place renames a finished temp file into the object path
+func (s *Store) place(tmp, hash string) error {
+	obj := s.objectPath(hash)
+	if err := os.MkdirAll(filepath.Dir(obj), 0755); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, obj); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func (s *Store) tempPath() (string, error) {
+	dir := filepath.Join(s.root, tmpDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(dir, "object-*")
+	if err != nil {
+		return "", err
+	}
+	f.Close()
+	return f.Name(), nil
+}
+
+// link points dest at the object and records the reference. A hardlink is
+// preferred; across volumes an existing matching copy is kept or a new one
+// is made.
+func (s *Store) link(hash, dest string) error {
+	obj := s.objectPath(hash)
+	objInfo, err := os.Stat(obj)
+	if err != nil {
+		return err
+	}
+
+	destInfo, err := os.Stat(dest)
+	if err == nil && os.SameFile(destInfo, objInfo) {
+		return s.addRef(hash, dest)
+	}
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+
+	tmp := dest + ".link"
+	os.Remove(tmp)
+	if err := os.Link(obj, tmp); err != nil {
+		if destInfo != nil {
+			if sum, err := fileSHA1(dest); err == nil && sum == hash {
+				return s.addRef(hash, dest)
+			}
+		}
+		if err := copyFile(obj, tmp); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+	}
+	if err := os.Rename(tmp, dest); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return s.addRef(hash, dest)
+}
+
+func (s *Store) addRef(hash, dest string) error {
+	if err := s.loadRefs(); err != nil {
+		return err
+	}
+	abs, err := filepath.Abs(dest)
+	if err != nil {
+		return err
+	}
+	if hasRef(s.refs[hash], abs) {
+		return nil
+	}
+	s.refs[hash] = append(s.refs[hash], abs)
+	if s.added == nil {
+		s.added = make(map[string][]string)
+	}
+	s.added[hash] = append(s.added[hash], abs)
+	return nil
+}
+
+func hasRef(refs []string, path string) bool {
+	for _, ref := range refs {
+		if ref == path {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Store) loadRefs() error {
+	if s.refs != nil {
+		return nil
+	}
+	refs, err := s.readRefs()
+	if err != nil {
+		return err
+	}
+	s.refs = refs
+	return nil
+}
+
+// reloadRefs reads the reference table again, as other processes may have
+// changed it, and adds the references not written yet. Call it with the
+// table locked.
+func (s *Store) reloadRefs() error {
+	refs, err := s.readRefs()
+	if err != nil {
+		return err
+	}
+	for hash, paths := range s.added {
+		for _, path := range paths {
+			if !hasRef(refs[hash], path) {
+				refs[hash] = append(refs[hash], path)
+			}
+		}
+	}
+	s.refs = refs
+	return nil
+}
+
+func (s *Store) readRefs() (map[string][]string, error) {
+	refs := make(map[string][]string)
+	data, err := os.ReadFile(filepath.Join(s.root, RefsFile))
+	if os.IsNotExist(err) {
+		return refs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &refs); err != nil {
+		return nil, fmt.Errorf("corrupt cache references: %w", err)
+	}
+	return refs, nil
+}
+
+// lockRefs locks the reference table against other processes until the
+// returned func is called
+func (s *Store) lockRefs() (func(), error) {
+	if err := os.MkdirAll(s.root, 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(filepath.Join(s.root, RefsLockFile), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("locking cache references: %w", err)
+	}
+	return func() {
+		unlockFile(f)
+		f.Close()
+	}, nil
+}
+
+// saveRefs writes the reference table, which then holds every reference
+func (s *Store) saveRefs() error {
+	if err := os.MkdirAll(s.root, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s.refs, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(s.root, RefsFile)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return err
+	}
+	s.added = nil
+	return nil
+}
+
+func validHash(hash string) bool {
+	if len(hash) != sha1.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
+
+func fileSHA1(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha1.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func httpDownload(url, dest string) error {
+	resp, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("bad status: %s", resp.Status)
+	}
+
+	out, err := os.Create(dest)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, resp.Body); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
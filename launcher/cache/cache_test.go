@@ -0,0 +1,173 @@
+package cache
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStore_FetchLinksAndGC(t *testing.T) {
+	root := t.TempDir()
+	dataA := t.TempDir()
+	dataB := t.TempDir()
+
+	content := []byte("lwjgl jar")
+	sum := sha1.Sum(content)
+	hash := hex.EncodeToString(sum[:])
+
+	downloads := 0
+	store := NewStore(root)
+	store.download = func(url, dest string) error {
+		downloads++
+		return os.WriteFile(dest, content, 0644)
+	}
+
+	destA := filepath.Join(dataA, "libraries", "lwjgl.jar")
+	destB := filepath.Join(dataB, "libraries", "lwjgl.jar")
+	if err := store.Fetch(hash, "http://libs/lwjgl.jar", destA); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Fetch(hash, "http://libs/lwjgl.jar", destB); err != nil {
+		t.Fatal(err)
+	}
+	if downloads != 1 {
+		t.Errorf("expected a single download, got %d", downloads)
+	}
+
+	infoA, _ := os.Stat(destA)
+	infoB, _ := os.Stat(destB)
+	if !os.SameFile(infoA, infoB) {
+		t.Error("expected both data dirs to link the same object")
+	}
+	if n, _ := store.RefCount(hash); n != 2 {
+		t.Errorf("expected 2 refs, got %d", n)
+	}
+
+	// Refs survive a restart
+	if err := store.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	reopened := NewStore(root)
+	if n, _ := reopened.RefCount(hash); n != 2 {
+		t.Errorf("expected 2 persisted refs, got %d", n)
+	}
+
+	// A corrupted copy of the same size doesn't hold the object, B still does
+	os.Remove(destA)
+	os.WriteFile(destA, []byte("lwjgl jaR"), 0644)
+	result, err := reopened.GC()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Kept != 1 || result.Removed != 0 {
+		t.Errorf("unexpected GC result %+v", result)
+	}
+	if n, _ := reopened.RefCount(hash); n != 1 {
+		t.Errorf("expected stale ref to be dropped, got %d refs", n)
+	}
+
+	os.Remove(destB)
+	result, err = reopened.GC()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Removed != 1 || result.FreedBytes != int64(len(content)) {
+		t.Errorf("unexpected GC result %+v", result)
+	}
+	if _, err := os.Stat(reopened.objectPath(hash)); !os.IsNotExist(err) {
+		t.Error("expected unreferenced object to be deleted")
+	}
+}
+
+func TestStore_AdoptAndVerify(t *testing.T) {
+	store := NewStore(t.TempDir())
+	store.download = func(url, dest string) error {
+		return os.WriteFile(dest, []byte("tampered"), 0644)
+	}
+
+	// A file from an existing install is adopted without downloading
+	content := []byte("asset")
+	sum := sha1.Sum(content)
+	hash := hex.EncodeToString(sum[:])
+	dest := filepath.Join(t.TempDir(), "assets", "objects", hash[:2], hash)
+	os.MkdirAll(filepath.Dir(dest), 0755)
+	os.WriteFile(dest, content, 0644)
+
+	if err := store.Fetch(hash, "http://assets/"+hash, dest); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(store.objectPath(hash)); err != nil {
+		t.Errorf("expected existing file to be adopted: %v", err)
+	}
+
+	// Downloads that don't match the published hash are rejected
+	other := fmt.Sprintf("%040x", 1)
+	if err := store.Fetch(other, "http://assets/other", filepath.Join(t.TempDir(), "other")); err == nil {
+		t.Error("expected checksum mismatch")
+	}
+
+	// An evicted object is downloaded again on the next fetch
+	if err := store.Evict(hash); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(store.objectPath(hash)); !os.IsNotExist(err) {
+		t.Error("expected the object to be evicted")
+	}
+	if _, err := os.Stat(dest); err != nil {
+		t.Errorf("expected the linked file to survive eviction: %v", err)
+	}
+}
+
+func TestStore_SharedBetweenProcesses(t *testing.T) {
+	root := t.TempDir()
+	fetch := func(store *Store, content string) (string, string) {
+		t.Helper()
+		sum := sha1.Sum([]byte(content))
+		hash := hex.EncodeToString(sum[:])
+		store.download = func(url, dest string) error {
+			return os.WriteFile(dest, []byte(content), 0644)
+		}
+		dest := filepath.Join(t.TempDir(), "libraries", content+".jar")
+		if err := store.Fetch(hash, "http://libs/"+content, dest); err != nil {
+			t.Fatal(err)
+		}
+		return hash, dest
+	}
+
+	// Two launchers load the refs, then each links its own library
+	first, second := NewStore(root), NewStore(root)
+	first.RefCount("")
+	second.RefCount("")
+	hashA, _ := fetch(first, "lwjgl")
+	hashB, destB := fetch(second, "guava")
+	if err := first.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := NewStore(root)
+	for _, hash := range []string{hashA, hashB} {
+		if n, _ := reopened.RefCount(hash); n != 1 {
+			t.Errorf("expected the refs of both launchers to be kept, got %d for %s", n, hash)
+		}
+	}
+
+	// A GC in the first launcher keeps what only the second one linked
+	if _, err := first.GC(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(second.objectPath(hashB)); err != nil {
+		t.Errorf("expected the other launcher's object to survive GC: %v", err)
+	}
+	if n, _ := first.RefCount(hashB); n != 1 {
+		t.Errorf("expected GC to see the other launcher's ref, got %d", n)
+	}
+	if _, err := os.Stat(destB); err != nil {
+		t.Fatal(err)
+	}
+}
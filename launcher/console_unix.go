@@ -0,0 +1,8 @@
+//go:build !windows
+
+package launcher
+
+import "os/exec"
+
+// hideConsole is only needed on Windows
+func hideConsole(cmd *exec.Cmd) {}
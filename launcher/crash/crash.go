@@ -0,0 +1,286 @@
+// Package crash finds the crash reports and JVM error logs a game session
+// left behind and boils them down to a structured report.
+package crash
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// CrashReportsDir is where the game writes its crash-*.txt reports
+	CrashReportsDir = "crash-reports"
+
+	// maxFrames caps the stack frames kept from each file
+	maxFrames = 15
+)
+
+// Exit kinds, from most to least specific
+const (
+	KindClean       = "clean"         // Exit code 0
+	KindGameCrash   = "game-crash"    // The game wrote a crash report
+	KindJVMCrash    = "jvm-crash"     // The JVM itself died (hs_err log, segfault)
+	KindOutOfMemory = "out-of-memory" // The JVM couldn't get memory
+	KindKilled      = "killed"        // Killed from outside, e.g. by the OS
+	KindError       = "error"         // Any other non-zero exit
+)
+
+// Report describes why a session ended
+type Report struct {
+	SessionID     string      `json:"sessionId"`
+	Kind          string      `json:"kind"`
+	ExitCode      int         `json:"exitCode"`
+	Signal        string      `json:"signal"`     // Unix signal that ended the process, if any
+	ExitReason    string      `json:"exitReason"` // Human-readable meaning of the exit
+	Description   string      `json:"description"`
+	Exception     string      `json:"exception"`
+	SuspectedMods []string    `json:"suspectedMods"`
+	Frames        []string    `json:"frames"`
+	CrashReport   string      `json:"crashReport"` // Absolute path, empty if none was written
+	JVMErrorLog   string      `json:"jvmErrorLog"` // Absolute path of hs_err_pid*.log, empty if none
+	Diagnoses     []Diagnosis `json:"diagnoses"`   // Known issues that match, see Diagnose
+}
+
+// Analyze builds a report for a session that ran in gameDir from since
+// until it exited with state. Only files written during the session count.
+func Analyze(gameDir string, since time.Time, state *os.ProcessState) *Report {
+	r := &Report{}
+	if state != nil {
+		r.ExitCode = state.ExitCode()
+		if ws, ok := state.Sys().(syscall.WaitStatus); ok && ws.Signaled() {
+			r.Signal = ws.Signal().String()
+		}
+	}
+	r.Kind, r.ExitReason = ClassifyExit(r.ExitCode, r.Signal)
+
+	if path := newestFile(filepath.Join(gameDir, CrashReportsDir), "crash-*.txt", since); path != "" {
+		r.CrashReport = path
+		r.Kind = KindGameCrash
+		if err := r.parseCrashReport(path); err != nil {
+			r.ExitReason += " (crash report unreadable: " + err.Error() + ")"
+		}
+	}
+
+	if path := newestFile(gameDir, "hs_err_pid*.log", since); path != "" {
+		r.JVMErrorLog = path
+		r.Kind = KindJVMCrash
+		if err := r.parseJVMErrorLog(path); err != nil {
+			r.ExitReason += " (JVM error log unreadable: " + err.Error() + ")"
+		}
+	}
+	return r
+}
+
+// Diagnose matches rules against the game output and the files the report
+// points to. Errors are for rules that couldn't be compiled.
+func (r *Report) Diagnose(rules []Rule, output string) []error {
+	texts := []string{output}
+	for _, path := range []string{r.CrashReport, r.JVMErrorLog} {
+		if path == "" {
+			continue
+		}
+		if data, err := os.ReadFile(path); err == nil {
+			texts = append(texts, string(data))
+		}
+	}
+	var errs []error
+	r.Diagnoses, errs = Diagnose(rules, texts...)
+	return errs
+}
+
+// ClassifyExit explains an exit code, or the signal that ended the process
+func ClassifyExit(code int, signal string) (kind, reason string) {
+	switch signal {
+	case "":
+	case "killed":
+		return KindKilled, "Killed (SIGKILL), possibly by the system running out of memory"
+	case "segmentation fault":
+		return KindJVMCrash, "The JVM crashed with a segmentation fault"
+	case "aborted":
+		return KindJVMCrash, "The JVM aborted"
+	default:
+		return KindKilled, "Ended by signal: " + signal
+	}
+
+	switch uint32(code) {
+	case 0:
+		return KindClean, "Exited normally"
+	case 1:
+		return KindError, "Exited with an error"
+	case 255, 0xFFFFFFFF:
+		return KindGameCrash, "The game shut itself down after a crash"
+	case 130:
+		return KindKilled, "Interrupted (SIGINT)"
+	case 134:
+		return KindJVMCrash, "The JVM aborted"
+	case 137:
+		return KindKilled, "Killed (SIGKILL), possibly by the system running out of memory"
+	case 139:
+		return KindJVMCrash, "The JVM crashed with a segmentation fault"
+	case 143:
+		return KindKilled, "Terminated (SIGTERM)"
+	case 0xC0000005:
+		return KindJVMCrash, "Access violation in native code, often a graphics driver"
+	case 0xC00000FD:
+		return KindJVMCrash, "Stack overflow in native code"
+	case 0xC0000409:
+		return KindJVMCrash, "Stack buffer overrun in native code"
+	case 0xC0000017:
+		return KindOutOfMemory, "Windows ran out of memory"
+	default:
+		return KindError, "Exited with an error"
+	}
+}
+
+// mixinHandler matches Mixin-injected method names, which carry the mod ID
+// (e.g. handler$zza000$modid$onRender)
+var mixinHandler = regexp.MustCompile(`\$[a-z]{3}\d{3}\$([a-z0-9_-]+)\$`)
+
+// parseCrashReport reads a Minecraft crash-*.txt
+func (r *Report) parseCrashReport(path string) error {
+	lines, err := readLines(path)
+	if err != nil {
+		return err
+	}
+
+	mods := make(map[string]bool)
+	addMods := func(list string) {
+		for _, mod := range strings.Split(list, ",") {
+			if mod = strings.TrimSpace(mod); mod != "" && mod != "NONE" && !mods[mod] {
+				mods[mod] = true
+				r.SuspectedMods = append(r.SuspectedMods, mod)
+			}
+		}
+	}
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "Description:") && r.Description == "":
+			r.Description = strings.TrimSpace(strings.TrimPrefix(trimmed, "Description:"))
+			// The exception follows the description after a blank line
+			for _, next := range lines[i+1:] {
+				if next = strings.TrimSpace(next); next != "" {
+					r.Exception = next
+					break
+				}
+			}
+		case strings.HasPrefix(trimmed, "Suspected Mod"):
+			// "Suspected Mods: a, b" or a header followed by indented entries
+			if _, list, ok := strings.Cut(trimmed, ":"); ok && strings.TrimSpace(list) != "" {
+				addMods(list)
+				continue
+			}
+			for _, next := range lines[i+1:] {
+				if !strings.HasPrefix(next, "\t") && !strings.HasPrefix(next, " ") {
+					break
+				}
+				name, _, _ := strings.Cut(strings.TrimSpace(next), " ")
+				addMods(name)
+			}
+		case strings.HasPrefix(trimmed, "at ") && len(r.Frames) < maxFrames && r.Exception != "":
+			frame := strings.TrimPrefix(trimmed, "at ")
+			r.Frames = append(r.Frames, frame)
+			if m := mixinHandler.FindStringSubmatch(frame); m != nil {
+				addMods(m[1])
+			}
+		}
+	}
+	return nil
+}
+
+// parseJVMErrorLog reads an hs_err_pid*.log the JVM writes when it dies
+func (r *Report) parseJVMErrorLog(path string) error {
+	lines, err := readLines(path)
+	if err != nil {
+		return err
+	}
+
+	var frames []string
+	var exception string
+	inJavaFrames := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(strings.TrimPrefix(line, "#"))
+		switch {
+		case strings.Contains(line, "There is insufficient memory for the Java Runtime Environment"),
+			strings.Contains(line, "Out of Memory Error"):
+			if r.Kind != KindOutOfMemory {
+				r.Kind = KindOutOfMemory
+				exception = trimmed
+			}
+		case strings.HasPrefix(trimmed, "SIGSEGV"), strings.HasPrefix(trimmed, "SIGBUS"),
+			strings.HasPrefix(trimmed, "EXCEPTION_"):
+			if exception == "" {
+				exception = trimmed
+			}
+		case strings.HasPrefix(trimmed, "Problematic frame:") && i+1 < len(lines):
+			frames = append(frames, strings.TrimSpace(strings.TrimPrefix(lines[i+1], "#")))
+		case strings.HasPrefix(line, "Java frames:"):
+			inJavaFrames = true
+		case inJavaFrames:
+			if trimmed == "" {
+				inJavaFrames = false
+			} else if len(frames) < maxFrames && (strings.HasPrefix(line, "j ") || strings.HasPrefix(line, "J ")) {
+				frames = append(frames, trimmed)
+			}
+		}
+	}
+
+	// The JVM's view explains the crash better than the game's
+	if exception != "" {
+		r.Exception = exception
+	}
+	if len(frames) > 0 {
+		r.Frames = frames
+	}
+	return nil
+}
+
+// newestFile returns the most recently modified file in dir matching
+// pattern that was written at or after since
+func newestFile(dir, pattern string, since time.Time) string {
+	matches, _ := filepath.Glob(filepath.Join(dir, pattern))
+	type candidate struct {
+		path string
+		mod  time.Time
+	}
+	var found []candidate
+	for _, path := range matches {
+		info, err := os.Stat(path)
+		if err != nil || info.IsDir() || info.ModTime().Before(since) {
+			continue
+		}
+		found = append(found, candidate{path, info.ModTime()})
+	}
+	if len(found) == 0 {
+		return ""
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].mod.After(found[j].mod) })
+	abs, err := filepath.Abs(found[0].path)
+	if err != nil {
+		return found[0].path
+	}
+	return abs
+}
+
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
@@ -0,0 +1,106 @@
+package crash
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const sampleCrashReport = `---- Minecraft Crash Report ----
+// Who set us up the TNT?
+
+Time: 1/2/24 3:04 PM
+Description: Rendering screen
+
+java.lang.NullPointerException: Rendering screen
+	at net.minecraft.client.gui.GuiScreen.handler$zza000$modmenu$onRender(GuiScreen.java:42)
+	at net.minecraft.client.renderer.EntityRenderer.func_181560_a(EntityRenderer.java:1133)
+	at net.minecraft.client.Minecraft.func_71411_J(Minecraft.java:1107)
+
+A detailed walkthrough of the error, its code path and all known details is as follows:
+---------------------------------------------------------------------------------------
+Suspected Mods: Phosphor, modmenu
+`
+
+const sampleJVMErrorLog = `#
+# A fatal error has been detected by the Java Runtime Environment:
+#
+#  SIGSEGV (0xb) at pc=0x00007f0000001234, pid=4242, tid=4243
+#
+# Problematic frame:
+# C  [libnvidia-glcore.so.535+0x123456]
+#
+
+Java frames: (J=compiled Java code, j=interpreted, Vv=VM code)
+j  org.lwjgl.opengl.GL11.nglDrawArrays(IIIJ)V+0
+j  net.minecraft.client.renderer.WorldRenderer.draw()V+12
+
+---------------  P R O C E S S  ---------------
+`
+
+func TestAnalyze_CrashReportAndJVMLog(t *testing.T) {
+	gameDir := t.TempDir()
+	start := time.Now().Add(-time.Minute)
+
+	reports := filepath.Join(gameDir, CrashReportsDir)
+	os.MkdirAll(reports, 0755)
+
+	// A report from an earlier session must be ignored
+	old := filepath.Join(reports, "crash-old-client.txt")
+	os.WriteFile(old, []byte("Description: Old crash\n\nold.Exception\n"), 0644)
+	os.Chtimes(old, start.Add(-time.Hour), start.Add(-time.Hour))
+
+	os.WriteFile(filepath.Join(reports, "crash-new-client.txt"), []byte(sampleCrashReport), 0644)
+
+	r := Analyze(gameDir, start, nil)
+	if r.Kind != KindGameCrash || r.Description != "Rendering screen" {
+		t.Fatalf("unexpected report %+v", r)
+	}
+	if r.Exception != "java.lang.NullPointerException: Rendering screen" {
+		t.Errorf("unexpected exception %q", r.Exception)
+	}
+	if len(r.Frames) != 3 {
+		t.Errorf("expected 3 frames, got %v", r.Frames)
+	}
+	if len(r.SuspectedMods) != 2 || r.SuspectedMods[0] != "modmenu" || r.SuspectedMods[1] != "Phosphor" {
+		t.Errorf("unexpected suspected mods %v", r.SuspectedMods)
+	}
+	if filepath.Base(r.CrashReport) != "crash-new-client.txt" {
+		t.Errorf("expected newest crash report, got %q", r.CrashReport)
+	}
+
+	// A JVM crash log takes precedence
+	os.WriteFile(filepath.Join(gameDir, "hs_err_pid4242.log"), []byte(sampleJVMErrorLog), 0644)
+	r = Analyze(gameDir, start, nil)
+	if r.Kind != KindJVMCrash || r.JVMErrorLog == "" {
+		t.Fatalf("expected JVM crash, got %+v", r)
+	}
+	if r.Exception != "SIGSEGV (0xb) at pc=0x00007f0000001234, pid=4242, tid=4243" {
+		t.Errorf("unexpected exception %q", r.Exception)
+	}
+	if len(r.Frames) != 3 || r.Frames[0] != "C  [libnvidia-glcore.so.535+0x123456]" {
+		t.Errorf("unexpected frames %v", r.Frames)
+	}
+}
+
+func TestClassifyExit(t *testing.T) {
+	tests := []struct {
+		code   int
+		signal string
+		kind   string
+	}{
+		{0, "", KindClean},
+		{1, "", KindError},
+		{-1, "", KindGameCrash},
+		{137, "", KindKilled},
+		{-1, "killed", KindKilled},
+		{-1, "segmentation fault", KindJVMCrash},
+		{int(int32(-1073741819)), "", KindJVMCrash}, // 0xC0000005, access violation
+	}
+	for _, tt := range tests {
+		if kind, _ := ClassifyExit(tt.code, tt.signal); kind != tt.kind {
+			t.Errorf("ClassifyExit(%d, %q) = %q, want %q", tt.code, tt.signal, kind, tt.kind)
+		}
+	}
+}
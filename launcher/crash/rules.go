@@ -0,0 +1,103 @@
+package crash
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Rule recognizes a known problem in the game output, crash report or JVM
+// error log. Fix may refer to the pattern's capture groups as $1, ${name}.
+type Rule struct {
+	ID      string `json:"id"`
+	Title   string `json:"title"`
+	Pattern string `json:"pattern"`
+	Fix     string `json:"fix"`
+}
+
+// Diagnosis is a rule that matched, with its fix filled in
+type Diagnosis struct {
+	RuleID string `json:"ruleId"`
+	Title  string `json:"title"`
+	Fix    string `json:"fix"`
+	Match  string `json:"match"` // The text the pattern matched
+}
+
+// BuiltinRules cover the crashes players run into most
+var BuiltinRules = []Rule{
+	{
+		ID:      "out-of-memory",
+		Title:   "Out of memory",
+		Pattern: `java\.lang\.OutOfMemoryError(?:: [^\n]+)?|There is insufficient memory for the Java Runtime Environment`,
+		Fix:     "The game ran out of memory. Raise the RAM allocation, or close other programs if your system is low on memory.",
+	},
+	{
+		ID:      "opengl-driver",
+		Title:   "Graphics driver problem",
+		Pattern: `Pixel format not accelerated|No OpenGL context found in the current thread|Could not create context|\[(?:atio6axx|atioglxx|nvoglv\d+|ig\d+icd\d+|libnvidia-glcore[^\]]*|libGL[^\]]*)\.\w+\+0x[0-9a-f]+\]`,
+		Fix:     "Your graphics driver failed to run the game. Install the latest driver from your GPU vendor (not Windows Update) and make sure the game uses your dedicated GPU.",
+	},
+	{
+		ID:      "duplicate-mod",
+		Title:   "Duplicate mods",
+		Pattern: `(?i)duplicate mod(?:s| id)?[:\s]+'?([\w.-]+)`,
+		Fix:     "The mod '$1' is installed more than once. Remove the extra copy from the mods folder.",
+	},
+	{
+		ID:      "wrong-java",
+		Title:   "Wrong Java version",
+		Pattern: `UnsupportedClassVersionError|has been compiled by a more recent version of the Java Runtime|AppClassLoader cannot be cast to (?:class )?java\.net\.URLClassLoader`,
+		Fix:     "This game needs Java 8. Clear the custom Java path of the instance to use the bundled runtime.",
+	},
+	{
+		ID:      "missing-dependency",
+		Title:   "Missing dependency",
+		Pattern: `requires (?:any version of |version \S+ of )?(?:mod )?'?([\w.-]+)'?,? which is missing|java\.lang\.NoClassDefFoundError: (\S+)`,
+		Fix:     "A mod needs something that isn't installed ($1$2). Install the missing mod or remove the one that depends on it.",
+	},
+}
+
+// MergeRules combines pack-supplied rules with the built-in ones. Pack rules
+// come first and replace built-in rules with the same ID.
+func MergeRules(pack []Rule) []Rule {
+	ids := make(map[string]bool)
+	rules := make([]Rule, 0, len(pack)+len(BuiltinRules))
+	for _, r := range pack {
+		ids[r.ID] = true
+		rules = append(rules, r)
+	}
+	for _, r := range BuiltinRules {
+		if !ids[r.ID] {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// Diagnose runs every rule over the texts and returns one diagnosis per
+// matching rule, in rule order. Rules with invalid patterns are skipped.
+func Diagnose(rules []Rule, texts ...string) ([]Diagnosis, []error) {
+	var found []Diagnosis
+	var errs []error
+	for _, rule := range rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("crash rule %q: %w", rule.ID, err))
+			continue
+		}
+		for _, text := range texts {
+			m := re.FindStringSubmatchIndex(text)
+			if m == nil {
+				continue
+			}
+			fix := string(re.ExpandString(nil, rule.Fix, text, m))
+			found = append(found, Diagnosis{
+				RuleID: rule.ID,
+				Title:  rule.Title,
+				Fix:    fix,
+				Match:  text[m[0]:m[1]],
+			})
+			break
+		}
+	}
+	return found, errs
+}
@@ -0,0 +1,33 @@
+package crash
+
+import "testing"
+
+func TestDiagnose_BuiltinAndPackRules(t *testing.T) {
+	output := `[12:00:01] [main/INFO]: Loading Minecraft 1.8.9
+[12:00:05] [Client thread/ERROR]: Mod 'Legacy Mod Menu' (modmenu) requires any version of 'legacy-fabric-api', which is missing!
+Exception in thread "main" java.lang.OutOfMemoryError: Java heap space`
+
+	found, errs := Diagnose(BuiltinRules, output)
+	if len(errs) > 0 {
+		t.Fatalf("built-in rules must compile: %v", errs)
+	}
+	if len(found) != 2 || found[0].RuleID != "out-of-memory" || found[1].RuleID != "missing-dependency" {
+		t.Fatalf("unexpected diagnoses %+v", found)
+	}
+	if found[1].Fix != "A mod needs something that isn't installed (legacy-fabric-api). Install the missing mod or remove the one that depends on it." {
+		t.Errorf("unexpected fix %q", found[1].Fix)
+	}
+
+	// Pack rules come first, replace built-ins by ID, and bad patterns are reported
+	rules := MergeRules([]Rule{
+		{ID: "out-of-memory", Title: "OOM", Pattern: `OutOfMemoryError: (.+)`, Fix: "Pack needs 4 GiB ($1)"},
+		{ID: "shaders", Title: "Shaders", Pattern: `(`, Fix: "unused"},
+	})
+	found, errs = Diagnose(rules, output)
+	if len(errs) != 1 {
+		t.Errorf("expected one invalid rule, got %v", errs)
+	}
+	if len(found) != 2 || found[0].Fix != "Pack needs 4 GiB (Java heap space)" {
+		t.Errorf("unexpected diagnoses %+v", found)
+	}
+}
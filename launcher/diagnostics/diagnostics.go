@@ -0,0 +1,148 @@
+// Package diagnostics collects what's needed to triage a player's problem
+// into one zip with a machine-readable summary.json.
+package diagnostics
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"craft-launcher/launcher/instance"
+	"craft-launcher/launcher/integrity"
+)
+
+const (
+	// SummaryName is the summary's file name inside the zip
+	SummaryName = "summary.json"
+
+	// ModsDir is listed in the summary
+	ModsDir = "mods"
+)
+
+// System describes the player's machine
+type System struct {
+	OS       string `json:"os"`
+	Arch     string `json:"arch"`
+	CPUs     int    `json:"cpus"`
+	TotalRAM uint64 `json:"totalRAM"` // MiB
+	Is32Bit  bool   `json:"is32Bit"`
+}
+
+// Java describes the JVM the instance launches with
+type Java struct {
+	Path    string `json:"path"`
+	Version string `json:"version,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ManifestState describes the modpack files recorded by the last update
+type ManifestState struct {
+	Present      bool       `json:"present"`
+	Version      int        `json:"version,omitempty"`
+	Files        int        `json:"files,omitempty"`
+	LastVerified *time.Time `json:"lastVerified,omitempty"`
+	Error        string     `json:"error,omitempty"`
+}
+
+// ModFile is one entry of the mods dir
+type ModFile struct {
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"modTime"`
+	Disabled bool      `json:"disabled"`
+}
+
+// Summary is written as summary.json
+type Summary struct {
+	GeneratedAt    time.Time               `json:"generatedAt"`
+	System         System                  `json:"system"`
+	Java           Java                    `json:"java"`
+	Instance       instance.Instance       `json:"instance"`
+	Manifest       ManifestState           `json:"manifest"`
+	FailedFiles    []integrity.FileProblem `json:"failedFiles"`
+	Mods           []ModFile               `json:"mods"`
+	RecentLaunches []LaunchRecord          `json:"recentLaunches"`
+}
+
+// Attachment is an extra file stored next to summary.json
+type Attachment struct {
+	Name string
+	Data []byte
+}
+
+// CheckManifest reads the local manifest of gameDir and verifies the files
+// it lists
+func CheckManifest(gameDir string) (ManifestState, []integrity.FileProblem) {
+	m, err := integrity.LoadLocalManifest(gameDir)
+	if os.IsNotExist(err) {
+		return ManifestState{}, nil
+	}
+	if err != nil {
+		return ManifestState{Error: err.Error()}, nil
+	}
+
+	state := ManifestState{Present: true, Version: m.Version, Files: len(m.Files)}
+	if t, ok := integrity.LastVerified(gameDir); ok {
+		state.LastVerified = &t
+	}
+	return state, integrity.Verify(gameDir, m)
+}
+
+// ListMods lists the files in gameDir/mods, sorted by name
+func ListMods(gameDir string) ([]ModFile, error) {
+	entries, err := os.ReadDir(filepath.Join(gameDir, ModsDir))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var mods []ModFile
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		mods = append(mods, ModFile{
+			Name:     e.Name(),
+			Size:     info.Size(),
+			ModTime:  info.ModTime(),
+			Disabled: strings.HasSuffix(e.Name(), ".disabled"),
+		})
+	}
+	sort.Slice(mods, func(i, j int) bool {
+		return mods[i].Name < mods[j].Name
+	})
+	return mods, nil
+}
+
+// Write zips the summary and attachments into w
+func Write(w io.Writer, s Summary, attachments ...Attachment) error {
+	zw := zip.NewWriter(w)
+
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	attachments = append([]Attachment{{Name: SummaryName, Data: data}}, attachments...)
+
+	for _, a := range attachments {
+		f, err := zw.Create(a.Name)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(a.Data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
@@ -0,0 +1,74 @@
+package diagnostics
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"craft-launcher/launcher/integrity"
+)
+
+func TestHistory_KeepsNewest(t *testing.T) {
+	h := NewHistory(t.TempDir())
+	for i := 0; i < MaxHistory+3; i++ {
+		if err := h.Add(LaunchRecord{RamMB: i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	records, err := h.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != MaxHistory || records[0].RamMB != MaxHistory+2 {
+		t.Errorf("expected newest %d launches first, got %+v", MaxHistory, records)
+	}
+}
+
+func TestListMods(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, ModsDir, "config"), 0755)
+	os.WriteFile(filepath.Join(dir, ModsDir, "b.jar"), []byte("b"), 0644)
+	os.WriteFile(filepath.Join(dir, ModsDir, "a.jar.disabled"), []byte("a"), 0644)
+
+	mods, err := ListMods(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(mods) != 2 || mods[0].Name != "a.jar.disabled" || !mods[0].Disabled || mods[1].Disabled {
+		t.Errorf("unexpected mods %+v", mods)
+	}
+
+	if mods, err := ListMods(t.TempDir()); err != nil || mods != nil {
+		t.Errorf("expected no mods without a mods dir, got %v, %v", mods, err)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	s := Summary{
+		System:      System{OS: "linux", Arch: "amd64"},
+		FailedFiles: []integrity.FileProblem{{Path: "mods/a.jar", Problem: integrity.ProblemMissing}},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, s, Attachment{Name: "extra.txt", Data: []byte("x")}); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(zr.File) != 2 || zr.File[0].Name != SummaryName || zr.File[1].Name != "extra.txt" {
+		t.Fatalf("unexpected zip entries %v", zr.File)
+	}
+	f, _ := zr.File[0].Open()
+	var got Summary
+	if err := json.NewDecoder(f).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	if got.System.OS != "linux" || len(got.FailedFiles) != 1 {
+		t.Errorf("unexpected summary %+v", got)
+	}
+}
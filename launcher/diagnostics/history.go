@@ -0,0 +1,87 @@
+package diagnostics
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	// HistoryFile keeps the recent launches, stored in the data dir
+	HistoryFile = "launch_history.json"
+
+	// MaxHistory is how many launches are kept
+	MaxHistory = 10
+)
+
+// LaunchRecord is the options one session was launched with. The player
+// name is left out.
+type LaunchRecord struct {
+	Time          time.Time `json:"time"`
+	SessionID     string    `json:"sessionId"`
+	InstanceID    string    `json:"instanceId"`
+	VersionID     string    `json:"versionId"`
+	Loader        string    `json:"loader"`
+	RamMB         int       `json:"ramMB"`
+	JavaPath      string    `json:"javaPath,omitempty"` // Empty for the bundled JRE
+	JVMPreset     string    `json:"jvmPreset,omitempty"`
+	JVMArgs       []string  `json:"jvmArgs,omitempty"` // Preset arguments included
+	GameArgs      []string  `json:"gameArgs,omitempty"`
+	ServerAddress string    `json:"serverAddress,omitempty"`
+}
+
+// History stores the most recent launches
+type History struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewHistory creates a history backed by dir/HistoryFile
+func NewHistory(dir string) *History {
+	return &History{path: filepath.Join(dir, HistoryFile)}
+}
+
+// Add records a launch, dropping the oldest beyond MaxHistory
+func (h *History) Add(r LaunchRecord) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	records, _ := h.load() // Start over if the file is unreadable
+	records = append([]LaunchRecord{r}, records...)
+	if len(records) > MaxHistory {
+		records = records[:MaxHistory]
+	}
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(h.path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(h.path, data, 0644)
+}
+
+// List returns the recorded launches, newest first
+func (h *History) List() ([]LaunchRecord, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.load()
+}
+
+func (h *History) load() ([]LaunchRecord, error) {
+	data, err := os.ReadFile(h.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var records []LaunchRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, err
+	}
+	return records, nil
+}
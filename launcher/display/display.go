@@ -0,0 +1,109 @@
+// Package display holds the game window settings and turns them into game
+// arguments or options.txt entries.
+package display
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultWidth and DefaultHeight are the vanilla launcher's window size
+	DefaultWidth  = 854
+	DefaultHeight = 480
+
+	// The game's UI doesn't fit below this
+	MinWidth  = 320
+	MinHeight = 240
+
+	// 8K, anything larger is a typo
+	MaxWidth  = 7680
+	MaxHeight = 4320
+
+	// OptionsFile is the game's settings file in the game dir
+	OptionsFile = "options.txt"
+)
+
+// Window is how the game window opens. A zero size uses the default.
+type Window struct {
+	Width        int  `json:"width"`
+	Height       int  `json:"height"`
+	Fullscreen   bool `json:"fullscreen"`
+	RememberSize bool `json:"rememberSize"` // Keep the size used at launch for next time
+}
+
+// Size returns the window size, with defaults filled in
+func (w Window) Size() (int, int) {
+	width, height := w.Width, w.Height
+	if width == 0 {
+		width = DefaultWidth
+	}
+	if height == 0 {
+		height = DefaultHeight
+	}
+	return width, height
+}
+
+// Check rejects window sizes the game can't open
+func (w Window) Check() error {
+	if w.Width != 0 && (w.Width < MinWidth || w.Width > MaxWidth) {
+		return fmt.Errorf("window width must be between %d and %d", MinWidth, MaxWidth)
+	}
+	if w.Height != 0 && (w.Height < MinHeight || w.Height > MaxHeight) {
+		return fmt.Errorf("window height must be between %d and %d", MinHeight, MaxHeight)
+	}
+	return nil
+}
+
+// FullscreenFlag reports whether a version's main class accepts
+// --fullscreen. It was added in 1.6; unknown versions such as snapshots
+// are assumed not to, since options.txt works for every version.
+func FullscreenFlag(versionID string) bool {
+	parts := strings.SplitN(versionID, ".", 3)
+	if len(parts) < 2 || parts[0] != "1" {
+		return false
+	}
+	minor, err := strconv.Atoi(strings.SplitN(parts[1], "-", 2)[0])
+	return err == nil && minor >= 6
+}
+
+// Args returns the game arguments that open the window as w
+func (w Window) Args(versionID string) []string {
+	width, height := w.Size()
+	args := []string{"--width", strconv.Itoa(width), "--height", strconv.Itoa(height)}
+	if w.Fullscreen && FullscreenFlag(versionID) {
+		args = append(args, "--fullscreen")
+	}
+	return args
+}
+
+// WriteFullscreen sets the fullscreen key in the game dir's options.txt,
+// keeping every other setting. The file is created if the game hasn't
+// written one yet.
+func WriteFullscreen(gameDir string, fullscreen bool) error {
+	path := filepath.Join(gameDir, OptionsFile)
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	entry := "fullscreen:" + strconv.FormatBool(fullscreen)
+	lines := strings.Split(strings.TrimRight(string(data), "\r\n"), "\n")
+	if len(data) == 0 {
+		lines = nil
+	}
+	found := false
+	for i, line := range lines {
+		if key, _, _ := strings.Cut(strings.TrimRight(line, "\r"), ":"); key == "fullscreen" {
+			lines[i] = entry
+			found = true
+		}
+	}
+	if !found {
+		lines = append(lines, entry)
+	}
+	return os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0644)
+}
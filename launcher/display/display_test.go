@@ -0,0 +1,54 @@
+package display
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWindowCheckAndArgs(t *testing.T) {
+	if err := (Window{}).Check(); err != nil {
+		t.Errorf("expected the default size to pass: %v", err)
+	}
+	if err := (Window{Width: 100, Height: 480}).Check(); err == nil {
+		t.Error("expected a tiny width to be rejected")
+	}
+	if err := (Window{Width: 1920, Height: 99999}).Check(); err == nil {
+		t.Error("expected a huge height to be rejected")
+	}
+
+	args := Window{Fullscreen: true}.Args("1.8.9")
+	want := []string{"--width", "854", "--height", "480", "--fullscreen"}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("expected %v, got %v", want, args)
+	}
+	args = Window{Width: 1280, Height: 720, Fullscreen: true}.Args("1.5.2")
+	want = []string{"--width", "1280", "--height", "720"}
+	if !reflect.DeepEqual(args, want) {
+		t.Errorf("expected no fullscreen flag before 1.6, got %v", args)
+	}
+	if FullscreenFlag("24w14a") {
+		t.Error("expected snapshots to fall back to options.txt")
+	}
+}
+
+func TestWriteFullscreen(t *testing.T) {
+	dir := t.TempDir()
+	if err := WriteFullscreen(dir, true); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, OptionsFile))
+	if string(data) != "fullscreen:true\n" {
+		t.Errorf("unexpected new options.txt %q", data)
+	}
+
+	os.WriteFile(filepath.Join(dir, OptionsFile), []byte("gamma:1.0\nfullscreen:true\nfov:0.5\n"), 0644)
+	if err := WriteFullscreen(dir, false); err != nil {
+		t.Fatal(err)
+	}
+	data, _ = os.ReadFile(filepath.Join(dir, OptionsFile))
+	if string(data) != "gamma:1.0\nfullscreen:false\nfov:0.5\n" {
+		t.Errorf("expected only fullscreen to change, got %q", data)
+	}
+}
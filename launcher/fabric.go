@@ -1,21 +1,33 @@
 package launcher
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+
+	"craft-launcher/launcher/cache"
 )
 
 const (
-	LegacyFabricMetaURL = "https://meta.legacyfabric.net/v2/versions/loader/1.8.9"
+	LegacyFabricMetaURL = "https://meta.legacyfabric.net/v2/versions/loader/%s"
+)
+
+// Maven repositories of the loader and the intermediary mappings, swapped
+// out in tests
+var (
+	FabricMavenURL       = "https://maven.fabricmc.net/"
+	LegacyFabricMavenURL = "https://maven.legacyfabric.net/"
 )
 
-// GetFabricMeta fetches the loader metadata for 1.8.9
-func GetFabricMeta() (*FabricLoaderResponse, error) {
-	resp, err := http.Get(LegacyFabricMetaURL)
+// GetFabricMeta fetches the loader metadata for a game version (e.g. 1.8.9)
+func GetFabricMeta(versionID string) (*FabricLoaderResponse, error) {
+	resp, err := http.Get(fmt.Sprintf(LegacyFabricMetaURL, versionID))
 	if err != nil {
 		return nil, err
 	}
@@ -38,116 +50,103 @@ func GetFabricMeta() (*FabricLoaderResponse, error) {
 	return &data[0], nil
 }
 
-// DownloadFabricLibraries downloads the required fabric libraries
-func DownloadFabricLibraries(meta *FabricLoaderResponse, gameDir string) ([]string, error) {
-	libsDir := filepath.Join(gameDir, "libraries")
-	var cp []string
-
-	// Helper to process a list of libraries
-	processLibs := func(libs []FabricLibrary) error {
-		for _, lib := range libs {
-			// Convert maven coordinates to path
-			parts := strings.Split(lib.Name, ":")
-			if len(parts) != 3 {
-				fmt.Printf("Skipping invalid fabric lib: %s\n", lib.Name)
-				continue
-			}
-			group := parts[0]
-			artifact := parts[1]
-			version := parts[2]
-
-			relPath := fmt.Sprintf("%s/%s/%s/%s-%s.jar",
-				strings.ReplaceAll(group, ".", "/"),
-				artifact,
-				version,
-				artifact,
-				version,
-			)
-
-			destPath := filepath.Join(libsDir, relPath)
-			absPath, _ := filepath.Abs(destPath)
-			cp = append(cp, absPath)
-
-			if _, err := os.Stat(destPath); err == nil {
-				continue // Already exists
-			}
-
-			if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
-				return err
-			}
-
-			// Construct download URL
-			downloadURL := lib.URL + relPath
-			if lib.URL == "" {
-				downloadURL = "https://maven.fabricmc.net/" + relPath
-			}
-
-			fmt.Printf("Downloading Fabric Lib: %s\n", lib.Name)
-			if err := downloadFile(downloadURL, destPath); err != nil {
-				return fmt.Errorf("failed to download %s: %w", lib.Name, err)
-			}
+// FabricFiles lists the libraries, intermediary and loader jars of a Fabric
+// launch in classpath order. The metadata has no hash for the intermediary
+// and loader, see MavenSHA1.
+func FabricFiles(meta *FabricLoaderResponse) ([]GameFile, error) {
+	var files []GameFile
+	for _, lib := range append(meta.LaunchMeta.Libraries.Common, meta.LaunchMeta.Libraries.Client...) {
+		relPath, err := mavenPath(lib.Name)
+		if err != nil {
+			fmt.Printf("Skipping invalid fabric lib: %s\n", lib.Name)
+			continue
+		}
+		repo := lib.URL
+		if repo == "" {
+			repo = FabricMavenURL
 		}
-		return nil
+		files = append(files, GameFile{Kind: FileLibrary, Path: path.Join("libraries", relPath), SHA1: lib.SHA1, URL: repo + relPath})
 	}
 
-	// 1. Download Common Libraries
-	if err := processLibs(meta.LaunchMeta.Libraries.Common); err != nil {
-		return nil, err
+	for _, m := range []struct{ name, maven, repo string }{
+		{"intermediary", meta.Intermediary.Maven, LegacyFabricMavenURL},
+		{"loader", meta.Loader.Maven, FabricMavenURL},
+	} {
+		relPath, err := mavenPath(m.maven)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", m.name, err)
+		}
+		files = append(files, GameFile{Kind: FileLibrary, Path: path.Join("libraries", relPath), URL: m.repo + relPath})
 	}
+	return files, nil
+}
 
-	// 2. Download Client Libraries
-	if err := processLibs(meta.LaunchMeta.Libraries.Client); err != nil {
-		return nil, err
+// mavenPath turns group:artifact:version coordinates into a repository path
+func mavenPath(coords string) (string, error) {
+	parts := strings.Split(coords, ":")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("invalid maven: %s", coords)
 	}
+	group, artifact, version := parts[0], parts[1], parts[2]
+	return fmt.Sprintf("%s/%s/%s/%s-%s.jar",
+		strings.ReplaceAll(group, ".", "/"),
+		artifact,
+		version,
+		artifact,
+		version,
+	), nil
+}
 
-	// Helper for maven string
-	downloadMaven := func(mavenStr, repoBase string) (string, error) {
-		parts := strings.Split(mavenStr, ":")
-		if len(parts) != 3 {
-			return "", fmt.Errorf("invalid maven: %s", mavenStr)
-		}
-		group, artifact, version := parts[0], parts[1], parts[2]
-		relPath := fmt.Sprintf("%s/%s/%s/%s-%s.jar",
-			strings.ReplaceAll(group, ".", "/"),
-			artifact,
-			version,
-			artifact,
-			version,
-		)
-		destPath := filepath.Join(libsDir, relPath)
-		absPath, _ := filepath.Abs(destPath)
-
-		if _, err := os.Stat(destPath); err == nil {
-			return absPath, nil
-		}
-
-		if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
-			return "", err
-		}
-
-		url := repoBase + relPath
-		fmt.Printf("Downloading Maven Art: %s\n", mavenStr)
-		if err := downloadFile(url, destPath); err != nil {
-			return "", err
-		}
-		return absPath, nil
+// MavenSHA1 fetches the checksum a Maven repository publishes next to an
+// artifact
+func MavenSHA1(url string) (string, error) {
+	resp, err := http.Get(url + ".sha1")
+	if err != nil {
+		return "", err
 	}
-
-	// 3. Download Intermediary
-	// Intermediary URL usually: https://maven.legacyfabric.net/
-	interPath, err := downloadMaven(meta.Intermediary.Maven, "https://maven.legacyfabric.net/")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch checksum: %s", resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
 	if err != nil {
-		return nil, fmt.Errorf("failed to download intermediary: %w", err)
+		return "", err
+	}
+	// Some repositories append the file name
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 || len(fields[0]) != 40 {
+		return "", fmt.Errorf("invalid checksum %q", data)
+	}
+	if _, err := hex.DecodeString(fields[0]); err != nil {
+		return "", fmt.Errorf("invalid checksum %q", data)
 	}
-	cp = append(cp, interPath)
+	return strings.ToLower(fields[0]), nil
+}
 
-	// 4. Download Loader
-	// Loader URL usually: https://maven.fabricmc.net/
-	loaderPath, err := downloadMaven(meta.Loader.Maven, "https://maven.fabricmc.net/")
+// DownloadFabricLibraries downloads the required fabric libraries into the
+// shared dir, through the object store when one is given
+func DownloadFabricLibraries(meta *FabricLoaderResponse, sharedDir string, store *cache.Store) ([]string, error) {
+	files, err := FabricFiles(meta)
 	if err != nil {
-		return nil, fmt.Errorf("failed to download loader: %w", err)
+		return nil, err
 	}
-	cp = append(cp, loaderPath)
 
+	var cp []string
+	for _, f := range files {
+		destPath := filepath.Join(sharedDir, filepath.FromSlash(f.Path))
+		absPath, _ := filepath.Abs(destPath)
+		cp = append(cp, absPath)
+
+		if store == nil {
+			if _, err := os.Stat(destPath); err == nil {
+				continue // Already exists
+			}
+			fmt.Printf("Downloading Fabric Lib: %s\n", f.Path)
+		}
+		// Without a hash, the store hashes the download
+		if err := fetchFile(store, f.SHA1, f.URL, destPath); err != nil {
+			return nil, fmt.Errorf("failed to download %s: %w", f.Path, err)
+		}
+	}
 	return cp, nil
 }
@@ -0,0 +1,191 @@
+package launcher
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"craft-launcher/launcher/cache"
+)
+
+// Kinds of files a version downloads into the shared dir
+const (
+	FileAsset   = "asset"
+	FileLibrary = "library"
+	FileClient  = "client"
+)
+
+// GameFile is a downloaded file with a published SHA-1
+type GameFile struct {
+	Kind string
+	Path string // Relative to the shared dir, slash-separated
+	SHA1 string
+	URL  string
+}
+
+// GetVersion fetches the package (version JSON) for versionID
+func GetVersion(versionID string) (*Package, error) {
+	manifest, err := GetVersionManifest()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get manifest: %w", err)
+	}
+	versionURL, err := manifest.FindVersionURL(versionID)
+	if err != nil {
+		return nil, err
+	}
+	return GetPackage(versionURL)
+}
+
+// VersionFiles lists the client jar, the asset index and the libraries,
+// including native jars, a version uses on this OS
+func VersionFiles(pkg *Package) []GameFile {
+	files := []GameFile{
+		{Kind: FileClient, Path: path.Join("versions", pkg.ID, pkg.ID+".jar"), SHA1: pkg.Downloads.Client.Sha1, URL: pkg.Downloads.Client.URL},
+		{Kind: FileAsset, Path: path.Join("assets", "indexes", pkg.AssetIndex.ID+".json"), SHA1: pkg.AssetIndex.Sha1, URL: pkg.AssetIndex.URL},
+	}
+	for _, lib := range pkg.Libraries {
+		if !shouldDownloadLibrary(lib) {
+			continue
+		}
+		for _, artifact := range []*Artifact{lib.Downloads.Artifact, nativeArtifact(lib)} {
+			if artifact != nil {
+				files = append(files, GameFile{Kind: FileLibrary, Path: path.Join("libraries", artifact.Path), SHA1: artifact.Sha1, URL: artifact.URL})
+			}
+		}
+	}
+	return files
+}
+
+// AssetFiles lists the objects in a version's asset index, which must
+// already be in the shared dir
+func AssetFiles(pkg *Package, sharedDir string) ([]GameFile, error) {
+	data, err := os.ReadFile(filepath.Join(sharedDir, "assets", "indexes", pkg.AssetIndex.ID+".json"))
+	if err != nil {
+		return nil, err
+	}
+	var assets Assets
+	if err := json.Unmarshal(data, &assets); err != nil {
+		return nil, fmt.Errorf("invalid asset index: %w", err)
+	}
+
+	files := make([]GameFile, 0, len(assets.Objects))
+	for _, obj := range assets.Objects {
+		prefix := obj.Hash[:2]
+		files = append(files, GameFile{
+			Kind: FileAsset,
+			Path: path.Join("assets", "objects", prefix, obj.Hash),
+			SHA1: obj.Hash,
+			URL:  fmt.Sprintf("%s/%s/%s", AssetBaseURL, prefix, obj.Hash),
+		})
+	}
+	return files, nil
+}
+
+// FileSHA1 returns the hex SHA-1 of a file
+func FileSHA1(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha1.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// RestoreFile downloads f into the shared dir again and checks it. The
+// store's copy is dropped first, since a corrupted hardlink corrupts it too.
+func RestoreFile(f GameFile, sharedDir string, store *cache.Store) error {
+	dest := filepath.Join(sharedDir, filepath.FromSlash(f.Path))
+	if store != nil && f.SHA1 != "" {
+		if err := store.Evict(f.SHA1); err != nil {
+			return err
+		}
+	}
+	if err := fetchFile(store, f.SHA1, f.URL, dest); err != nil {
+		return err
+	}
+	if f.SHA1 == "" {
+		return nil
+	}
+	sum, err := FileSHA1(dest)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(sum, f.SHA1) {
+		return fmt.Errorf("checksum mismatch after download: expected %s, got %s", f.SHA1, sum)
+	}
+	return nil
+}
+
+// CheckNatives returns the files in nativesDir that are missing or differ
+// from the native jars they are extracted from, relative to nativesDir,
+// and how many it checked
+func CheckNatives(pkg *Package, sharedDir, nativesDir string) ([]string, int, error) {
+	var bad []string
+	checked := 0
+	for _, lib := range pkg.Libraries {
+		artifact := nativeArtifact(lib)
+		if artifact == nil || !shouldDownloadLibrary(lib) {
+			continue
+		}
+		r, err := zip.OpenReader(filepath.Join(sharedDir, "libraries", artifact.Path))
+		if err != nil {
+			return nil, checked, fmt.Errorf("native jar for %s: %w", lib.Name, err)
+		}
+		for _, f := range r.File {
+			if f.FileInfo().IsDir() || strings.Contains(f.Name, "META-INF") || patchedNative(f.Name) {
+				continue
+			}
+			checked++
+			if sum, err := fileCRC32(filepath.Join(nativesDir, f.Name)); err != nil || sum != f.CRC32 {
+				bad = append(bad, f.Name)
+			}
+		}
+		r.Close()
+	}
+	return bad, checked, nil
+}
+
+// ExtractNatives extracts every native jar a version uses on this OS into
+// nativesDir, replacing what is there
+func ExtractNatives(pkg *Package, sharedDir, nativesDir string) error {
+	if err := os.MkdirAll(nativesDir, 0755); err != nil {
+		return err
+	}
+	for _, lib := range pkg.Libraries {
+		artifact := nativeArtifact(lib)
+		if artifact == nil || !shouldDownloadLibrary(lib) {
+			continue
+		}
+		if err := extractNative(filepath.Join(sharedDir, "libraries", artifact.Path), nativesDir); err != nil {
+			return fmt.Errorf("failed to extract native %s: %w", lib.Name, err)
+		}
+	}
+	return PatchNatives(nativesDir)
+}
+
+func fileCRC32(path string) (uint32, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	h := crc32.NewIEEE()
+	if _, err := io.Copy(h, f); err != nil {
+		return 0, err
+	}
+	return h.Sum32(), nil
+}
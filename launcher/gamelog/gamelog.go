@@ -0,0 +1,418 @@
+// Package gamelog writes each session's launcher status and game output to
+// its own file under launcher_logs, rotating and compressing old logs.
+package gamelog
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DirName is the log directory inside the data dir
+	DirName = "launcher_logs"
+
+	// DefaultMaxFileSize is when a session's log is rolled over to a new file
+	DefaultMaxFileSize = 10 * 1024 * 1024
+	// DefaultMaxFiles is how many log files (including rolled parts) are kept
+	DefaultMaxFiles = 30
+
+	timeLayout = "2006-01-02_15-04-05"
+	gzExt      = ".gz"
+)
+
+// LogInfo describes a log file
+type LogInfo struct {
+	Name       string    `json:"name"`
+	Size       int64     `json:"size"`
+	ModTime    time.Time `json:"modTime"`
+	Compressed bool      `json:"compressed"`
+	Active     bool      `json:"active"` // Still being written by a running session
+}
+
+// Manager owns a log directory. Logs of running sessions are never
+// compressed or pruned.
+type Manager struct {
+	dir         string
+	MaxFileSize int64
+	MaxFiles    int
+
+	mu       sync.Mutex
+	active   map[string]bool
+	lastBase string
+	lastN    int
+}
+
+// NewManager creates a manager for dir with the default limits
+func NewManager(dir string) *Manager {
+	return &Manager{
+		dir:         dir,
+		MaxFileSize: DefaultMaxFileSize,
+		MaxFiles:    DefaultMaxFiles,
+		active:      make(map[string]bool),
+	}
+}
+
+// Dir returns the log directory
+func (m *Manager) Dir() string {
+	return m.dir
+}
+
+// Open starts the log of a new session, named after the current time.
+// Finished logs are compressed and the oldest ones pruned first.
+func (m *Manager) Open() (*Writer, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := os.MkdirAll(m.dir, 0755); err != nil {
+		return nil, err
+	}
+	m.compressFinished()
+
+	// Sessions started within the same second get numbered, in order even
+	// if an earlier one was pruned already
+	base := time.Now().Format(timeLayout)
+	n := 1
+	if base == m.lastBase {
+		n = m.lastN + 1
+	}
+	name := logName(base, n)
+	for m.exists(name) {
+		n++
+		name = logName(base, n)
+	}
+	m.lastBase, m.lastN = base, n
+
+	f, err := os.OpenFile(filepath.Join(m.dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	m.active[name] = true
+	m.prune()
+	return &Writer{m: m, name: name, f: f}, nil
+}
+
+// List returns all logs, newest first
+func (m *Manager) List() ([]LogInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entries, err := os.ReadDir(m.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var logs []LogInfo
+	for _, e := range entries {
+		if e.IsDir() || !isLogName(e.Name()) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		logs = append(logs, LogInfo{
+			Name:       e.Name(),
+			Size:       info.Size(),
+			ModTime:    info.ModTime(),
+			Compressed: strings.HasSuffix(e.Name(), gzExt),
+			Active:     m.active[e.Name()],
+		})
+	}
+	sort.Slice(logs, func(i, j int) bool {
+		return logs[i].ModTime.After(logs[j].ModTime)
+	})
+	return logs, nil
+}
+
+// Read returns the contents of a log, decompressing it if needed
+func (m *Manager) Read(name string) (string, error) {
+	if name != filepath.Base(name) || !isLogName(name) {
+		return "", fmt.Errorf("invalid log name %q", name)
+	}
+
+	f, err := os.Open(filepath.Join(m.dir, name))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(name, gzExt) {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return "", err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	data, err := io.ReadAll(r)
+	return string(data), err
+}
+
+// ReadSession returns a session's whole log: the parts rotated out of it,
+// oldest first, then the log itself. Parts already pruned are noted.
+func (m *Manager) ReadSession(name string) (string, error) {
+	if name != filepath.Base(name) || !isLogName(name) {
+		return "", fmt.Errorf("invalid log name %q", name)
+	}
+	prefix := strings.TrimSuffix(strings.TrimSuffix(name, gzExt), ".log") + ".part"
+
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		return "", err
+	}
+	parts := map[int]string{}
+	var numbers []int
+	for _, e := range entries {
+		rest, ok := strings.CutPrefix(e.Name(), prefix)
+		if !ok || e.IsDir() || !isLogName(e.Name()) {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSuffix(strings.TrimSuffix(rest, gzExt), ".log"))
+		if err != nil {
+			continue
+		}
+		parts[n] = e.Name()
+		numbers = append(numbers, n)
+	}
+	sort.Ints(numbers)
+
+	var b strings.Builder
+	if len(numbers) > 0 && numbers[0] != 1 {
+		b.WriteString("[LAUNCHER] Earlier parts of this log were deleted\n")
+	}
+	for _, n := range numbers {
+		text, err := m.Read(parts[n])
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(text)
+	}
+	text, err := m.Read(name)
+	if err != nil {
+		return "", err
+	}
+	b.WriteString(text)
+	return b.String(), nil
+}
+
+// Find returns the current name of a session's log, which gains a .gz
+// suffix once it's compressed
+func (m *Manager) Find(name string) (LogInfo, error) {
+	logs, err := m.List()
+	if err != nil {
+		return LogInfo{}, err
+	}
+	for _, l := range logs {
+		if l.Name == name || l.Name == name+gzExt {
+			return l, nil
+		}
+	}
+	return LogInfo{}, fmt.Errorf("log %q not found", name)
+}
+
+// StartTime returns when the session that wrote a log started
+func StartTime(name string) (time.Time, bool) {
+	if len(name) < len(timeLayout) {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(timeLayout, name[:len(timeLayout)], time.Local)
+	return t, err == nil
+}
+
+func (m *Manager) exists(name string) bool {
+	for _, candidate := range []string{name, name + gzExt} {
+		if _, err := os.Stat(filepath.Join(m.dir, candidate)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// compressFinished gzips every plain log no session is writing to
+func (m *Manager) compressFinished() {
+	entries, _ := os.ReadDir(m.dir)
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasSuffix(name, ".log") || m.active[name] {
+			continue
+		}
+		if err := compress(filepath.Join(m.dir, name)); err != nil {
+			fmt.Printf("Warning: Failed to compress log %s: %v\n", name, err)
+		}
+	}
+}
+
+// prune deletes the oldest finished logs beyond MaxFiles
+func (m *Manager) prune() {
+	if m.MaxFiles <= 0 {
+		return
+	}
+	entries, _ := os.ReadDir(m.dir)
+	var names []string
+	for _, e := range entries {
+		if !e.IsDir() && isLogName(e.Name()) {
+			names = append(names, e.Name())
+		}
+	}
+	// Names start with the timestamp, so they sort oldest first
+	sort.Strings(names)
+	for i := 0; len(names)-i > m.MaxFiles; i++ {
+		if m.active[names[i]] {
+			continue
+		}
+		os.Remove(filepath.Join(m.dir, names[i]))
+	}
+}
+
+// partName names the nth part rotated out of a session's log
+func partName(name string, n int) string {
+	return fmt.Sprintf("%s.part%d.log", strings.TrimSuffix(name, ".log"), n)
+}
+
+func logName(base string, n int) string {
+	if n <= 1 {
+		return base + ".log"
+	}
+	return fmt.Sprintf("%s_%d.log", base, n)
+}
+
+func isLogName(name string) bool {
+	return strings.HasSuffix(name, ".log") || strings.HasSuffix(name, ".log"+gzExt)
+}
+
+// compress replaces path with path.gz
+func compress(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tmpPath := path + gzExt + ".tmp"
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	gz := gzip.NewWriter(out)
+	gz.Name = filepath.Base(path)
+	if _, err := io.Copy(gz, in); err != nil {
+		gz.Close()
+		out.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		out.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path+gzExt); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	in.Close()
+	return os.Remove(path)
+}
+
+// Writer is one session's log. It's safe for concurrent use, so status
+// messages and game output can share it.
+type Writer struct {
+	m     *Manager
+	name  string
+	f     *os.File
+	size  int64
+	parts int
+	mu    sync.Mutex
+}
+
+// Name returns the log's file name within the log directory
+func (w *Writer) Name() string {
+	return w.name
+}
+
+// Write appends game output
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.f == nil {
+		return 0, os.ErrClosed
+	}
+	n, err := w.f.Write(p)
+	w.size += int64(n)
+	if err == nil && w.m.MaxFileSize > 0 && w.size >= w.m.MaxFileSize {
+		err = w.rotate()
+	}
+	return n, err
+}
+
+// Close finishes the log. It's compressed when the next session starts.
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var err error
+	if w.f != nil {
+		err = w.f.Close()
+		w.f = nil
+	}
+
+	w.m.mu.Lock()
+	delete(w.m.active, w.name)
+	w.m.mu.Unlock()
+	return err
+}
+
+// rotate moves the full log to a numbered, compressed part and carries on
+// in a fresh file under the same name
+func (w *Writer) rotate() error {
+	path := filepath.Join(w.m.dir, w.name)
+	partPath := filepath.Join(w.m.dir, partName(w.name, w.parts+1))
+	err := w.f.Close()
+	if err == nil {
+		err = os.Rename(path, partPath)
+	}
+	if err != nil {
+		// Keep appending to the full log rather than go silent
+		f, openErr := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if openErr != nil {
+			w.f = nil
+			return openErr
+		}
+		w.f = f
+		return err
+	}
+	w.parts++
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		w.f = nil
+		return err
+	}
+	w.f = f
+	w.size = 0
+
+	if err := compress(partPath); err != nil {
+		fmt.Printf("Warning: Failed to compress log %s: %v\n", partPath, err)
+	}
+	w.m.mu.Lock()
+	w.m.prune()
+	w.m.mu.Unlock()
+	return nil
+}
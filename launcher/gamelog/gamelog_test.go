@@ -0,0 +1,125 @@
+package gamelog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestManager_WriteRotateAndRead(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), DirName)
+	m := NewManager(dir)
+	m.MaxFileSize = 64
+	m.MaxFiles = 3
+
+	w, err := m.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write([]byte("[LAUNCHER] Launching...\n"))
+	w.Write([]byte("[12:00:00] [Client thread/INFO]: Setting user: Player\n"))
+	w.Write([]byte("[12:00:01] [Client thread/INFO]: LWJGL Version: 2.9.4\n"))
+
+	// The full first file was rolled into a compressed part
+	logs, err := m.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(logs) != 2 {
+		t.Fatalf("expected active log and one part, got %+v", logs)
+	}
+	var part string
+	for _, l := range logs {
+		if l.Compressed {
+			part = l.Name
+		} else if !l.Active || l.Name != w.Name() {
+			t.Errorf("expected %s to be the active log, got %+v", w.Name(), l)
+		}
+	}
+	text, err := m.Read(part)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(text, "[LAUNCHER] Launching...\n") {
+		t.Errorf("unexpected part contents %q", text)
+	}
+	w.Close()
+
+	// The next session compresses the finished log
+	next, err := m.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	next.Close()
+	if _, err := os.Stat(filepath.Join(dir, w.Name()+".gz")); err != nil {
+		t.Errorf("expected finished log to be compressed: %v", err)
+	}
+	if found, err := m.Find(w.Name()); err != nil || found.Name != w.Name()+".gz" {
+		t.Errorf("expected Find to follow the compressed log, got %+v, %v", found, err)
+	}
+	if _, ok := StartTime(w.Name()); !ok {
+		t.Errorf("expected a start time in %s", w.Name())
+	}
+
+	// Older logs are pruned down to MaxFiles
+	for i := 0; i < 2; i++ {
+		next, err = m.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		next.Close()
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 3 {
+		t.Errorf("expected 3 logs after pruning, got %d", len(entries))
+	}
+	if _, err := os.Stat(filepath.Join(dir, part)); !os.IsNotExist(err) {
+		t.Error("expected the oldest log to be pruned")
+	}
+
+	if _, err := m.Read("../settings.json"); err == nil {
+		t.Error("expected invalid log name to be rejected")
+	}
+}
+
+func TestWriter_RotationKeepsLogging(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), DirName)
+	m := NewManager(dir)
+	m.MaxFileSize = 16
+
+	w, err := m.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	// Rolling over fails while the first part's name is taken
+	blocker := filepath.Join(dir, partName(w.Name(), 1))
+	os.MkdirAll(filepath.Join(blocker, "x"), 0755)
+	if _, err := w.Write([]byte("first line of log\n")); err == nil {
+		t.Error("expected the failed rotation to be reported")
+	}
+	if _, err := w.Write([]byte("second\n")); err == nil {
+		t.Error("expected the rotation to be retried")
+	}
+	os.RemoveAll(blocker)
+
+	// Nothing was lost and writing carries on in parts
+	w.Write([]byte("third line\n"))
+	w.Write([]byte("fourth line here\n"))
+	text, err := m.ReadSession(w.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "first line of log\nsecond\nthird line\nfourth line here\n"; text != want {
+		t.Errorf("expected the whole session, got %q", text)
+	}
+
+	// Pruned parts are noted
+	os.Remove(filepath.Join(dir, partName(w.Name(), 1)+gzExt))
+	text, _ = m.ReadSession(w.Name())
+	if !strings.HasPrefix(text, "[LAUNCHER] Earlier parts of this log were deleted\n") {
+		t.Errorf("expected a note about the missing part, got %q", text)
+	}
+}
@@ -0,0 +1,189 @@
+package gamelog
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Output streams
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+	// StreamLauncher marks the launcher's own messages among game output
+	StreamLauncher = "launcher"
+)
+
+// Record is one parsed line (or log4j XML event) of game output
+type Record struct {
+	Stream  string `json:"stream"`
+	Time    string `json:"time"`   // HH:MM:SS, empty if the line had none
+	Level   string `json:"level"`  // INFO, WARN, ERROR, ...; empty if unknown
+	Thread  string `json:"thread"` // e.g. "Client thread"
+	Logger  string `json:"logger"` // Only in formats that print it
+	Message string `json:"message"`
+}
+
+// Text renders the record in the game's plain log format
+func (r Record) Text() string {
+	if r.Level == "" || r.Time == "" {
+		return r.Message
+	}
+	if r.Logger != "" {
+		return fmt.Sprintf("[%s] [%s/%s] (%s) %s", r.Time, r.Thread, r.Level, r.Logger, r.Message)
+	}
+	return fmt.Sprintf("[%s] [%s/%s]: %s", r.Time, r.Thread, r.Level, r.Message)
+}
+
+// plainLine matches vanilla "[12:00:00] [Client thread/INFO]: msg" and
+// Fabric's "[12:00:00] [main/INFO] (FabricLoader) msg"
+var plainLine = regexp.MustCompile(`^\[(\d{2}:\d{2}:\d{2})\] \[(.*)/([A-Z]+)\](?:: | \(([^)]*)\) ?)(.*)$`)
+
+// log4jEvent is the XMLLayout output used with -Dlog4j.configurationFile
+type log4jEvent struct {
+	Logger    string `xml:"logger,attr"`
+	Timestamp int64  `xml:"timestamp,attr"`
+	Level     string `xml:"level,attr"`
+	Thread    string `xml:"thread,attr"`
+	Message   string `xml:"Message"`
+	Throwable string `xml:"Throwable"`
+}
+
+// Parser turns lines of game output into records. XML events spanning
+// several lines are collected until complete. It's safe for concurrent use
+// by the stdout and stderr line splitters.
+type Parser struct {
+	mu   sync.Mutex
+	last Record                      // For stack trace lines, which carry no header
+	xml  map[string]*strings.Builder // Pending XML event per stream
+}
+
+// NewParser creates a parser
+func NewParser() *Parser {
+	return &Parser{xml: make(map[string]*strings.Builder)}
+}
+
+// Line parses one line. ok is false while an XML event is incomplete.
+func (p *Parser) Line(stream, line string) (rec Record, ok bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	line = strings.TrimRight(line, "\r")
+	trimmed := strings.TrimSpace(line)
+
+	if pending := p.xml[stream]; pending != nil || strings.HasPrefix(trimmed, "<log4j:Event") {
+		if pending == nil {
+			pending = &strings.Builder{}
+			p.xml[stream] = pending
+		}
+		pending.WriteString(line)
+		pending.WriteByte('\n')
+		if !strings.Contains(trimmed, "</log4j:Event>") {
+			return Record{}, false
+		}
+		delete(p.xml, stream)
+		rec = parseXMLEvent(pending.String())
+		rec.Stream = stream
+		p.last = rec
+		return rec, true
+	}
+
+	if m := plainLine.FindStringSubmatch(line); m != nil {
+		rec = Record{
+			Stream:  stream,
+			Time:    m[1],
+			Thread:  m[2],
+			Level:   m[3],
+			Logger:  m[4],
+			Message: m[5],
+		}
+		p.last = rec
+		return rec, true
+	}
+
+	rec = Record{Stream: stream, Message: line}
+	if isContinuation(line) && p.last.Stream == stream {
+		// Stack traces belong to the record that logged them
+		rec.Level = p.last.Level
+		rec.Thread = p.last.Thread
+		rec.Logger = p.last.Logger
+	}
+	return rec, true
+}
+
+func isContinuation(line string) bool {
+	if strings.HasPrefix(line, "\t") || strings.HasPrefix(line, "    ") {
+		return true
+	}
+	return strings.HasPrefix(line, "Caused by: ") || strings.HasPrefix(line, "Exception in thread ")
+}
+
+func parseXMLEvent(raw string) Record {
+	// The log4j namespace prefix isn't declared in the stream, so strip it
+	var ev log4jEvent
+	if err := xml.Unmarshal([]byte(strings.ReplaceAll(raw, "log4j:", "")), &ev); err != nil {
+		return Record{Message: strings.TrimSpace(raw)}
+	}
+
+	rec := Record{
+		Level:   ev.Level,
+		Thread:  ev.Thread,
+		Logger:  ev.Logger,
+		Message: strings.TrimSpace(ev.Message),
+	}
+	if ev.Timestamp > 0 {
+		rec.Time = time.UnixMilli(ev.Timestamp).Format("15:04:05")
+	}
+	if t := strings.TrimSpace(ev.Throwable); t != "" {
+		rec.Message += "\n" + t
+	}
+	return rec
+}
+
+// LineWriter reassembles whole lines from the chunks a stream is written
+// in. Call Flush once the stream ended to emit an unterminated last line.
+type LineWriter struct {
+	stream string
+	emit   func(stream, line string)
+	mu     sync.Mutex
+	buf    []byte
+}
+
+// NewLineWriter creates a writer that calls emit for every line of stream
+func NewLineWriter(stream string, emit func(stream, line string)) *LineWriter {
+	return &LineWriter{stream: stream, emit: emit}
+}
+
+// Write buffers p and emits every completed line
+func (w *LineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		line := string(w.buf[:i])
+		w.buf = w.buf[i+1:]
+		w.emit(w.stream, line)
+	}
+	return len(p), nil
+}
+
+// Flush emits whatever is left of an unterminated line
+func (w *LineWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) > 0 {
+		line := string(w.buf)
+		w.buf = nil
+		w.emit(w.stream, line)
+	}
+}
@@ -0,0 +1,67 @@
+package gamelog
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestParser_PlainAndXML(t *testing.T) {
+	p := NewParser()
+
+	rec, ok := p.Line(StreamStdout, "[12:00:01] [Client thread/INFO]: Setting user: Player")
+	if !ok || rec.Time != "12:00:01" || rec.Thread != "Client thread" || rec.Level != "INFO" || rec.Message != "Setting user: Player" {
+		t.Errorf("unexpected vanilla record %+v", rec)
+	}
+
+	rec, _ = p.Line(StreamStdout, "[12:00:02] [main/WARN] (FabricLoader/Mixin) Reference map not found")
+	if rec.Level != "WARN" || rec.Logger != "FabricLoader/Mixin" || rec.Message != "Reference map not found" {
+		t.Errorf("unexpected fabric record %+v", rec)
+	}
+
+	p.Line(StreamStderr, "[12:00:03] [Client thread/ERROR]: Couldn't load texture")
+	rec, _ = p.Line(StreamStderr, "\tat net.minecraft.client.renderer.texture.TextureManager.func_110579_a(TextureManager.java:58)")
+	if rec.Level != "ERROR" || rec.Thread != "Client thread" {
+		t.Errorf("expected stack trace line to inherit its header, got %+v", rec)
+	}
+
+	ts := time.Date(2024, 1, 2, 12, 0, 4, 0, time.Local).UnixMilli()
+	lines := []string{
+		`<log4j:Event logger="net.minecraft.client.Minecraft" timestamp="` + strconv.FormatInt(ts, 10) + `" level="ERROR" thread="Client thread">`,
+		`  <log4j:Message><![CDATA[Unreported exception thrown!]]></log4j:Message>`,
+		`  <log4j:Throwable><![CDATA[java.lang.IllegalStateException: boom]]></log4j:Throwable>`,
+		`</log4j:Event>`,
+	}
+	for i, line := range lines {
+		rec, ok = p.Line(StreamStdout, line)
+		if ok != (i == len(lines)-1) {
+			t.Fatalf("line %d: unexpected ok=%v", i, ok)
+		}
+	}
+	want := Record{
+		Stream:  StreamStdout,
+		Time:    "12:00:04",
+		Level:   "ERROR",
+		Thread:  "Client thread",
+		Logger:  "net.minecraft.client.Minecraft",
+		Message: "Unreported exception thrown!\njava.lang.IllegalStateException: boom",
+	}
+	if rec != want {
+		t.Errorf("unexpected XML record %+v", rec)
+	}
+}
+
+func TestLineWriter_ReassemblesChunks(t *testing.T) {
+	var got []string
+	w := NewLineWriter(StreamStdout, func(stream, line string) {
+		got = append(got, stream+":"+line)
+	})
+	w.Write([]byte("[12:00:01] [main/IN"))
+	w.Write([]byte("FO]: one\ntwo\nthr"))
+	w.Write([]byte("ee"))
+	w.Flush()
+
+	if len(got) != 3 || got[0] != "stdout:[12:00:01] [main/INFO]: one" || got[1] != "stdout:two" || got[2] != "stdout:three" {
+		t.Errorf("unexpected lines %q", got)
+	}
+}
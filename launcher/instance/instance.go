@@ -0,0 +1,464 @@
+// Package instance manages independent game installations that share
+// libraries, assets and JREs but keep their own game directory.
+package instance
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"craft-launcher/launcher/auth"
+	"craft-launcher/launcher/display"
+	"craft-launcher/launcher/settings"
+)
+
+const (
+	// RegistryFile lists all instances, stored in the root dir
+	RegistryFile = "instances.json"
+	// InstancesDir holds the game dirs of all non-default instances
+	InstancesDir = "instances"
+
+	DefaultID      = "default"
+	DefaultVersion = "1.8.9"
+
+	LoaderVanilla = "vanilla"
+	LoaderFabric  = "fabric"
+)
+
+// Instance is a single game installation
+type Instance struct {
+	ID         string         `json:"id"`
+	Name       string         `json:"name"`
+	GameDir    string         `json:"gameDir"` // Stored relative to the root when inside it
+	VersionID  string         `json:"versionId"`
+	Loader     string         `json:"loader"`
+	ServerURL  string         `json:"serverURL"` // Modpack server, empty for none
+	JavaPath   string         `json:"javaPath"`  // Empty uses the bundled JRE
+	RamMB      int            `json:"ramMB"`     // 0 uses the launcher setting
+	JVMArgs    []string       `json:"jvmArgs"`   // Extra JVM arguments
+	JVMPreset  string         `json:"jvmPreset"` // Preset whose arguments come before JVMArgs, empty for none
+	GameArgs   []string       `json:"gameArgs"`  // Extra game arguments
+	Window     display.Window `json:"window"`
+	CreatedAt  time.Time      `json:"createdAt"`
+	LastPlayed time.Time      `json:"lastPlayed"`
+}
+
+// UseFabric reports whether the instance runs the Fabric loader
+func (i Instance) UseFabric() bool {
+	return i.Loader == LoaderFabric
+}
+
+type registry struct {
+	Instances []Instance `json:"instances"`
+}
+
+// Manager keeps the instance registry under a root dir. The root dir also
+// acts as the shared dir for libraries, assets and JREs, and as the game
+// dir of the default instance so existing installs keep working.
+type Manager struct {
+	root     string
+	excluded []string
+	mu       sync.Mutex
+}
+
+// NewManager creates a manager rooted at dir. excluded names top-level
+// entries other packages keep in game dirs or the root, such as logs or
+// quarantined files, which Clone never copies.
+func NewManager(root string, excluded ...string) *Manager {
+	return &Manager{root: root, excluded: excluded}
+}
+
+// SharedDir returns where libraries, assets, versions and JREs are stored
+func (m *Manager) SharedDir() string {
+	return m.root
+}
+
+// List returns all instances, creating the default one on first use
+func (m *Manager) List() ([]Instance, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reg, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	return reg.Instances, nil
+}
+
+// Get returns the instance with the given ID
+func (m *Manager) Get(id string) (Instance, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reg, err := m.load()
+	if err != nil {
+		return Instance{}, err
+	}
+	idx := reg.find(id)
+	if idx < 0 {
+		return Instance{}, fmt.Errorf("instance %q not found", id)
+	}
+	return reg.Instances[idx], nil
+}
+
+// Create adds a new instance with its own game dir
+func (m *Manager) Create(name, versionID, loader, serverURL string) (Instance, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := validate(name, versionID, loader); err != nil {
+		return Instance{}, err
+	}
+
+	reg, err := m.load()
+	if err != nil {
+		return Instance{}, err
+	}
+
+	id := reg.uniqueID(name)
+	inst := Instance{
+		ID:        id,
+		Name:      strings.TrimSpace(name),
+		GameDir:   filepath.Join(m.root, InstancesDir, id),
+		VersionID: versionID,
+		Loader:    loader,
+		ServerURL: serverURL,
+		CreatedAt: time.Now(),
+	}
+	if err := os.MkdirAll(inst.GameDir, 0755); err != nil {
+		return Instance{}, err
+	}
+
+	reg.Instances = append(reg.Instances, inst)
+	if err := m.save(reg); err != nil {
+		return Instance{}, err
+	}
+	return inst, nil
+}
+
+// Update replaces an instance's settings. The ID and game dir can't change.
+func (m *Manager) Update(inst Instance) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if err := validate(inst.Name, inst.VersionID, inst.Loader); err != nil {
+		return err
+	}
+	if err := inst.Window.Check(); err != nil {
+		return err
+	}
+
+	reg, err := m.load()
+	if err != nil {
+		return err
+	}
+	idx := reg.find(inst.ID)
+	if idx < 0 {
+		return fmt.Errorf("instance %q not found", inst.ID)
+	}
+
+	existing := reg.Instances[idx]
+	inst.Name = strings.TrimSpace(inst.Name)
+	inst.GameDir = existing.GameDir
+	inst.CreatedAt = existing.CreatedAt
+	reg.Instances[idx] = inst
+	return m.save(reg)
+}
+
+// Rename changes an instance's display name
+func (m *Manager) Rename(id, name string) error {
+	inst, err := m.Get(id)
+	if err != nil {
+		return err
+	}
+	inst.Name = name
+	return m.Update(inst)
+}
+
+// Clone copies an instance, including its game dir, under a new name
+func (m *Manager) Clone(id, name string) (Instance, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reg, err := m.load()
+	if err != nil {
+		return Instance{}, err
+	}
+	idx := reg.find(id)
+	if idx < 0 {
+		return Instance{}, fmt.Errorf("instance %q not found", id)
+	}
+	src := reg.Instances[idx]
+	if err := validate(name, src.VersionID, src.Loader); err != nil {
+		return Instance{}, err
+	}
+
+	clone := src
+	clone.ID = reg.uniqueID(name)
+	clone.Name = strings.TrimSpace(name)
+	clone.GameDir = filepath.Join(m.root, InstancesDir, clone.ID)
+	clone.JVMArgs = append([]string(nil), src.JVMArgs...)
+	clone.CreatedAt = time.Now()
+	clone.LastPlayed = time.Time{}
+
+	if err := copyGameDir(src.GameDir, clone.GameDir, m.sharedEntries(src)); err != nil {
+		os.RemoveAll(clone.GameDir)
+		return Instance{}, fmt.Errorf("failed to copy game dir: %w", err)
+	}
+
+	reg.Instances = append(reg.Instances, clone)
+	if err := m.save(reg); err != nil {
+		return Instance{}, err
+	}
+	return clone, nil
+}
+
+// Delete removes an instance and its game dir. The default instance lives
+// in the shared root and can't be deleted.
+func (m *Manager) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reg, err := m.load()
+	if err != nil {
+		return err
+	}
+	idx := reg.find(id)
+	if idx < 0 {
+		return fmt.Errorf("instance %q not found", id)
+	}
+
+	inst := reg.Instances[idx]
+	if m.isRootDir(inst.GameDir) {
+		return fmt.Errorf("the default instance can't be deleted")
+	}
+
+	reg.Instances = append(reg.Instances[:idx], reg.Instances[idx+1:]...)
+	if err := m.save(reg); err != nil {
+		return err
+	}
+	return os.RemoveAll(inst.GameDir)
+}
+
+// MarkPlayed records the last launch time
+func (m *Manager) MarkPlayed(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reg, err := m.load()
+	if err != nil {
+		return err
+	}
+	idx := reg.find(id)
+	if idx < 0 {
+		return fmt.Errorf("instance %q not found", id)
+	}
+	reg.Instances[idx].LastPlayed = time.Now()
+	return m.save(reg)
+}
+
+func (m *Manager) isRootDir(dir string) bool {
+	a, _ := filepath.Abs(dir)
+	b, _ := filepath.Abs(m.root)
+	return a == b
+}
+
+// sharedEntries lists top-level entries of an instance's game dir that are
+// shared or launcher data and must not be copied when cloning
+func (m *Manager) sharedEntries(inst Instance) map[string]bool {
+	skip := make(map[string]bool)
+	for _, name := range m.excluded {
+		skip[name] = true
+	}
+	if !m.isRootDir(inst.GameDir) {
+		return skip
+	}
+	for _, name := range []string{"libraries", "assets", "versions", "natives", InstancesDir, RegistryFile, settings.FileName, auth.AccountsFile} {
+		skip[name] = true
+	}
+	entries, _ := os.ReadDir(inst.GameDir)
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), "jre-") {
+			skip[e.Name()] = true
+		}
+	}
+	return skip
+}
+
+func (m *Manager) load() (*registry, error) {
+	path := filepath.Join(m.root, RegistryFile)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		// First run: the existing data dir becomes the default instance
+		reg := &registry{Instances: []Instance{{
+			ID:        DefaultID,
+			Name:      "Default",
+			GameDir:   m.root,
+			VersionID: DefaultVersion,
+			Loader:    LoaderVanilla,
+			CreatedAt: time.Now(),
+		}}}
+		return reg, m.save(reg)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var reg registry
+	if err := json.Unmarshal(data, &reg); err != nil {
+		return nil, fmt.Errorf("corrupt instance registry: %w", err)
+	}
+	for i := range reg.Instances {
+		reg.Instances[i].GameDir = m.resolveDir(reg.Instances[i])
+	}
+	return &reg, nil
+}
+
+// resolveDir turns a stored game dir into a usable path. Dirs inside the
+// root are stored relative to it so the portable folder can be moved; an
+// absolute dir that no longer exists falls back to where the instance would
+// live in this root.
+func (m *Manager) resolveDir(inst Instance) string {
+	dir := filepath.FromSlash(inst.GameDir)
+	if !filepath.IsAbs(dir) {
+		return filepath.Join(m.root, dir)
+	}
+	if _, err := os.Stat(dir); err == nil {
+		return dir
+	}
+	standard := filepath.Join(m.root, InstancesDir, inst.ID)
+	if inst.ID == DefaultID {
+		standard = m.root
+	}
+	if _, err := os.Stat(standard); err == nil {
+		return standard
+	}
+	return dir
+}
+
+// storedDir is the inverse of resolveDir
+func (m *Manager) storedDir(dir string) string {
+	root, err := filepath.Abs(m.root)
+	if err != nil {
+		return dir
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return dir
+	}
+	rel, err := filepath.Rel(root, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return dir
+	}
+	return filepath.ToSlash(rel)
+}
+
+func (m *Manager) save(reg *registry) error {
+	if err := os.MkdirAll(m.root, 0755); err != nil {
+		return err
+	}
+	stored := registry{Instances: make([]Instance, len(reg.Instances))}
+	for i, inst := range reg.Instances {
+		inst.GameDir = m.storedDir(inst.GameDir)
+		stored.Instances[i] = inst
+	}
+	data, err := json.MarshalIndent(stored, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(m.root, RegistryFile)
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+func (r *registry) find(id string) int {
+	for i, inst := range r.Instances {
+		if inst.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+var nonSlugChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// uniqueID derives a directory-safe ID from a name
+func (r *registry) uniqueID(name string) string {
+	base := strings.Trim(nonSlugChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if base == "" {
+		base = "instance"
+	}
+	id := base
+	for n := 2; r.find(id) >= 0; n++ {
+		id = fmt.Sprintf("%s-%d", base, n)
+	}
+	return id
+}
+
+func validate(name, versionID, loader string) error {
+	if strings.TrimSpace(name) == "" {
+		return fmt.Errorf("instance name can't be empty")
+	}
+	if versionID == "" {
+		return fmt.Errorf("instance needs a Minecraft version")
+	}
+	if loader != LoaderVanilla && loader != LoaderFabric {
+		return fmt.Errorf("unknown loader %q", loader)
+	}
+	return nil
+}
+
+func copyGameDir(src, dst string, skip map[string]bool) error {
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if top := strings.Split(filepath.ToSlash(rel), "/")[0]; skip[top] {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		return copyFile(path, target, info.Mode())
+	})
+}
+
+func copyFile(src, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode.Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,91 @@
+package instance
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestManager_Lifecycle(t *testing.T) {
+	root := t.TempDir()
+	m := NewManager(root, "quarantine")
+
+	// Existing single-instance data dir with shared data and a world
+	os.MkdirAll(filepath.Join(root, "libraries", "org"), 0755)
+	os.MkdirAll(filepath.Join(root, "saves", "World"), 0755)
+	os.WriteFile(filepath.Join(root, "saves", "World", "level.dat"), []byte("level"), 0644)
+	os.MkdirAll(filepath.Join(root, "quarantine", "old"), 0755)
+
+	list, err := m.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 1 || list[0].ID != DefaultID || list[0].GameDir != root {
+		t.Fatalf("expected default instance in root, got %+v", list)
+	}
+
+	inst, err := m.Create("My Pack!", "1.8.9", LoaderFabric, "http://pack")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inst.ID != "my-pack" || !inst.UseFabric() {
+		t.Errorf("unexpected instance %+v", inst)
+	}
+	if dup, _ := m.Create("my pack", "1.8.9", LoaderVanilla, ""); dup.ID != "my-pack-2" {
+		t.Errorf("expected unique ID, got %q", dup.ID)
+	}
+	if _, err := m.Create("Bad", "1.8.9", "forge", ""); err == nil {
+		t.Error("expected error for unknown loader")
+	}
+
+	// Cloning the default instance copies the world but not shared data
+	clone, err := m.Clone(DefaultID, "Copy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(clone.GameDir, "saves", "World", "level.dat")); err != nil {
+		t.Errorf("world was not cloned: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(clone.GameDir, "libraries")); !os.IsNotExist(err) {
+		t.Error("shared libraries should not be cloned")
+	}
+	if _, err := os.Stat(filepath.Join(clone.GameDir, "quarantine")); !os.IsNotExist(err) {
+		t.Error("excluded entries should not be cloned")
+	}
+
+	if err := m.Rename(clone.ID, "Renamed"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := m.Get(clone.ID); got.Name != "Renamed" || got.GameDir != clone.GameDir {
+		t.Errorf("rename failed: %+v", got)
+	}
+
+	if err := m.Delete(DefaultID); err == nil {
+		t.Error("default instance must not be deletable")
+	}
+	if err := m.Delete(clone.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(clone.GameDir); !os.IsNotExist(err) {
+		t.Error("deleted instance dir still exists")
+	}
+
+	// Registry survives a new manager
+	list, _ = NewManager(root).List()
+	if len(list) != 3 {
+		t.Errorf("expected 3 instances after reload, got %d", len(list))
+	}
+
+	// Moving the portable folder keeps the game dirs working
+	moved := filepath.Join(t.TempDir(), "moved")
+	if err := os.Rename(root, moved); err != nil {
+		t.Fatal(err)
+	}
+	got, err := NewManager(moved).Get(inst.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.GameDir != filepath.Join(moved, InstancesDir, inst.ID) {
+		t.Errorf("expected the game dir to move with the root, got %q", got.GameDir)
+	}
+}
@@ -0,0 +1,224 @@
+package integrity
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Delta format (gzip-compressed):
+//   magic "CLDELTA1"
+//   repeated ops:
+//     'C' <offset uvarint> <length uvarint>   copy bytes from the old file
+//     'I' <length uvarint> <bytes>            insert literal bytes
+//   'E'                                       end of stream
+//
+// Matching is done rsync-style: the old file is indexed in fixed-size blocks
+// by a weak rolling checksum, and the new file is scanned byte by byte for
+// blocks that also exist in the old one. This works well for jars where only
+// a few entries changed, since untouched entries keep their compressed bytes.
+
+const (
+	deltaMagic     = "CLDELTA1"
+	deltaBlockSize = 64
+
+	opCopy   = 'C'
+	opInsert = 'I'
+	opEnd    = 'E'
+)
+
+var errBadDelta = errors.New("invalid delta stream")
+
+// CreateDelta builds a delta that turns oldData into newData.
+func CreateDelta(oldData, newData []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	w := bufio.NewWriter(gz)
+
+	if _, err := w.WriteString(deltaMagic); err != nil {
+		return nil, err
+	}
+
+	index := indexBlocks(oldData)
+
+	literalStart := 0
+	flushLiteral := func(end int) {
+		if end > literalStart {
+			writeInsert(w, newData[literalStart:end])
+		}
+	}
+
+	i := 0
+	var sum rollingSum
+	sumValid := false
+	for i+deltaBlockSize <= len(newData) {
+		if !sumValid {
+			sum = newRollingSum(newData[i : i+deltaBlockSize])
+			sumValid = true
+		}
+
+		if offset, ok := findBlock(index, oldData, newData[i:i+deltaBlockSize], sum.value()); ok {
+			// Extend the match forward as far as the bytes agree
+			length := deltaBlockSize
+			for i+length < len(newData) && offset+length < len(oldData) && newData[i+length] == oldData[offset+length] {
+				length++
+			}
+			// And backwards into pending literal bytes
+			for i > literalStart && offset > 0 && newData[i-1] == oldData[offset-1] {
+				i--
+				offset--
+				length++
+			}
+
+			flushLiteral(i)
+			writeCopy(w, offset, length)
+			i += length
+			literalStart = i
+			sumValid = false
+			continue
+		}
+
+		if i+deltaBlockSize < len(newData) {
+			sum.roll(newData[i], newData[i+deltaBlockSize])
+		} else {
+			sumValid = false
+		}
+		i++
+	}
+	flushLiteral(len(newData))
+
+	if err := w.WriteByte(opEnd); err != nil {
+		return nil, err
+	}
+	if err := w.Flush(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ApplyDelta reconstructs the new file from oldData and a delta stream.
+// The result must be size bytes, so a bad stream can't grow it past that.
+func ApplyDelta(oldData []byte, delta io.Reader, size int64) ([]byte, error) {
+	gz, err := gzip.NewReader(delta)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", errBadDelta, err)
+	}
+	defer gz.Close()
+	r := bufio.NewReader(gz)
+
+	magic := make([]byte, len(deltaMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != deltaMagic {
+		return nil, errBadDelta
+	}
+
+	if size < 0 {
+		return nil, fmt.Errorf("%w: negative size", errBadDelta)
+	}
+	var out bytes.Buffer
+	fits := func(length uint64) bool {
+		return length <= uint64(size)-uint64(out.Len())
+	}
+	for {
+		op, err := r.ReadByte()
+		if err != nil {
+			return nil, errBadDelta
+		}
+
+		switch op {
+		case opCopy:
+			offset, err1 := binary.ReadUvarint(r)
+			length, err2 := binary.ReadUvarint(r)
+			if err1 != nil || err2 != nil {
+				return nil, errBadDelta
+			}
+			if offset > uint64(len(oldData)) || length > uint64(len(oldData))-offset {
+				return nil, fmt.Errorf("%w: copy out of range", errBadDelta)
+			}
+			if !fits(length) {
+				return nil, fmt.Errorf("%w: larger than %d bytes", errBadDelta, size)
+			}
+			out.Write(oldData[offset : offset+length])
+		case opInsert:
+			length, err := binary.ReadUvarint(r)
+			if err != nil {
+				return nil, errBadDelta
+			}
+			if !fits(length) {
+				return nil, fmt.Errorf("%w: larger than %d bytes", errBadDelta, size)
+			}
+			if _, err := io.CopyN(&out, r, int64(length)); err != nil {
+				return nil, errBadDelta
+			}
+		case opEnd:
+			if int64(out.Len()) != size {
+				return nil, fmt.Errorf("%w: %d bytes instead of %d", errBadDelta, out.Len(), size)
+			}
+			return out.Bytes(), nil
+		default:
+			return nil, fmt.Errorf("%w: unknown op %q", errBadDelta, op)
+		}
+	}
+}
+
+func writeCopy(w *bufio.Writer, offset, length int) {
+	var tmp [binary.MaxVarintLen64]byte
+	w.WriteByte(opCopy)
+	w.Write(tmp[:binary.PutUvarint(tmp[:], uint64(offset))])
+	w.Write(tmp[:binary.PutUvarint(tmp[:], uint64(length))])
+}
+
+func writeInsert(w *bufio.Writer, data []byte) {
+	var tmp [binary.MaxVarintLen64]byte
+	w.WriteByte(opInsert)
+	w.Write(tmp[:binary.PutUvarint(tmp[:], uint64(len(data)))])
+	w.Write(data)
+}
+
+// indexBlocks maps the weak checksum of every aligned block of data to its offsets
+func indexBlocks(data []byte) map[uint32][]int {
+	index := make(map[uint32][]int)
+	for off := 0; off+deltaBlockSize <= len(data); off += deltaBlockSize {
+		sum := newRollingSum(data[off : off+deltaBlockSize])
+		index[sum.value()] = append(index[sum.value()], off)
+	}
+	return index
+}
+
+func findBlock(index map[uint32][]int, oldData, block []byte, weak uint32) (int, bool) {
+	for _, off := range index[weak] {
+		if bytes.Equal(oldData[off:off+deltaBlockSize], block) {
+			return off, true
+		}
+	}
+	return 0, false
+}
+
+// rollingSum is an Adler-32 style weak checksum that can slide one byte at a time
+type rollingSum struct {
+	a, b uint32
+}
+
+func newRollingSum(block []byte) rollingSum {
+	var s rollingSum
+	for i, c := range block {
+		s.a += uint32(c)
+		s.b += uint32(len(block)-i) * uint32(c)
+	}
+	return s
+}
+
+func (s *rollingSum) roll(out, in byte) {
+	s.a += uint32(in) - uint32(out)
+	s.b += s.a - deltaBlockSize*uint32(out)
+}
+
+func (s rollingSum) value() uint32 {
+	return s.a&0xffff | s.b<<16
+}
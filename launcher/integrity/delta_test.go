@@ -0,0 +1,167 @@
+package integrity
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func checksumOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func TestDelta_RoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	oldData := make([]byte, 256*1024)
+	rng.Read(oldData)
+
+	// Change a few bytes in the middle, insert a chunk and drop a chunk
+	newData := append([]byte{}, oldData[:1000]...)
+	newData = append(newData, []byte("a brand new config entry")...)
+	newData = append(newData, oldData[1000:50000]...)
+	newData = append(newData, oldData[60000:]...)
+	newData[100000] ^= 0xff
+
+	delta, err := CreateDelta(oldData, newData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(delta) > len(newData)/10 {
+		t.Errorf("delta too large: %d bytes for %d byte file", len(delta), len(newData))
+	}
+
+	patched, err := ApplyDelta(oldData, bytes.NewReader(delta), int64(len(newData)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(patched, newData) {
+		t.Fatal("patched data does not match new data")
+	}
+
+	// Applying against the wrong base must not silently succeed
+	if _, err := ApplyDelta(oldData[:100], bytes.NewReader(delta), int64(len(newData))); err == nil {
+		t.Error("expected error applying delta to truncated base")
+	}
+	if _, err := ApplyDelta(oldData, bytes.NewReader(delta), int64(len(newData))-1); err == nil {
+		t.Error("expected error for a result larger than the manifest size")
+	}
+}
+
+func TestApplyDelta_Bounded(t *testing.T) {
+	oldData := bytes.Repeat([]byte("x"), 1024)
+
+	// Many copies of the whole old file, or one huge insert
+	var copies, insert bytes.Buffer
+	for _, buf := range []*bytes.Buffer{&copies, &insert} {
+		buf.WriteString(deltaMagic)
+	}
+	w := bufio.NewWriter(&copies)
+	for i := 0; i < 1000; i++ {
+		writeCopy(w, 0, len(oldData))
+	}
+	w.WriteByte(opEnd)
+	w.Flush()
+	var tmp [binary.MaxVarintLen64]byte
+	insert.WriteByte(opInsert)
+	insert.Write(tmp[:binary.PutUvarint(tmp[:], 1<<40)])
+
+	for name, raw := range map[string][]byte{"copies": copies.Bytes(), "insert": insert.Bytes()} {
+		var delta bytes.Buffer
+		gz := gzip.NewWriter(&delta)
+		gz.Write(raw)
+		gz.Close()
+		_, err := ApplyDelta(oldData, &delta, 4096)
+		if !errors.Is(err, errBadDelta) || !strings.Contains(err.Error(), "larger than") {
+			t.Errorf("%s: expected the size limit to stop the delta, got %v", name, err)
+		}
+	}
+}
+
+func TestCheckAndUpdate_DeltaAndFallback(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "launcher_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	rng := rand.New(rand.NewSource(2))
+	oldJar := make([]byte, 32*1024)
+	rng.Read(oldJar)
+	newJar := append([]byte{}, oldJar...)
+	copy(newJar[4096:], "patched")
+
+	delta, err := CreateDelta(oldJar, newJar)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// mods/A.jar holds the previous version, mods/B.jar holds something unknown
+	for _, name := range []string{"A.jar", "B.jar"} {
+		path := filepath.Join(tmpDir, "mods", name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		data := oldJar
+		if name == "B.jar" {
+			data = []byte("user modified")
+		}
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deltas := []DeltaInfo{{From: checksumOf(oldJar), Path: ".deltas/a.cldelta", Size: int64(len(delta))}}
+	serverManifest := Manifest{
+		Version: 2,
+		Files: []FileInfo{
+			{Path: "mods/A.jar", Size: int64(len(newJar)), Checksum: checksumOf(newJar), Override: true, Deltas: deltas},
+			{Path: "mods/B.jar", Size: int64(len(newJar)), Checksum: checksumOf(newJar), Override: true, Deltas: deltas},
+		},
+	}
+
+	var fullDownloads []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/manifest.json"):
+			json.NewEncoder(w).Encode(serverManifest)
+		case strings.HasSuffix(r.URL.Path, "/files/.deltas/a.cldelta"):
+			w.Write(delta)
+		case strings.HasPrefix(r.URL.Path, "/files/mods/"):
+			fullDownloads = append(fullDownloads, r.URL.Path)
+			w.Write(newJar)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	if err := CheckAndUpdate(tmpDir, server.URL, func(string) {}); err != nil {
+		t.Fatalf("CheckAndUpdate failed: %v", err)
+	}
+
+	for _, name := range []string{"A.jar", "B.jar"} {
+		data, err := os.ReadFile(filepath.Join(tmpDir, "mods", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, newJar) {
+			t.Errorf("%s was not updated to the new version", name)
+		}
+	}
+
+	// Only B.jar (unknown base) should have needed a full download
+	if len(fullDownloads) != 1 || fullDownloads[0] != "/files/mods/B.jar" {
+		t.Errorf("unexpected full downloads: %v", fullDownloads)
+	}
+}
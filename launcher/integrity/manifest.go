@@ -1,15 +1,53 @@
 package integrity
 
+import "encoding/json"
+
 // Manifest represents the structure of the server-side modpack manifest
 type Manifest struct {
-	Version int        `json:"version"`
-	Files   []FileInfo `json:"files"`
+	Version        int             `json:"version"`
+	Files          []FileInfo      `json:"files"`
+	OfflinePolicy  *OfflinePolicy  `json:"offlinePolicy,omitempty"`
+	Servers        []ServerEntry   `json:"servers,omitempty"`
+	AutoJoin       string          `json:"autoJoin,omitempty"`         // Server address to join on launch
+	CrashRules     json.RawMessage `json:"crashRules,omitempty"`       // Pack-specific known-issue rules, see crash.Rule
+	JVMPresets     json.RawMessage `json:"jvmPresets,omitempty"`       // Pack-specific argument presets, see jvmargs.Preset
+	MinRAM         int             `json:"minRamMB,omitempty"`         // Least heap the pack runs with
+	RecommendedRAM int             `json:"recommendedRamMB,omitempty"` // Heap the pack runs well with
+}
+
+// ServerEntry is a multiplayer server the pack requires in servers.dat
+type ServerEntry struct {
+	Name string `json:"name"`
+	IP   string `json:"ip"`
+	Icon string `json:"icon,omitempty"` // Base64 PNG, 64x64
+}
+
+// Offline policy modes, used when the manifest server can't be reached
+const (
+	OfflinePolicyStrict = "strict" // Refuse to launch (default)
+	OfflinePolicyGrace  = "grace"  // Launch if recently verified and local files still match
+	OfflinePolicyAllow  = "allow"  // Always launch
+)
+
+// OfflinePolicy is set by the server operator and cached with the local manifest
+type OfflinePolicy struct {
+	Mode       string `json:"mode"`
+	GraceHours int    `json:"graceHours,omitempty"`
 }
 
 // FileInfo represents a single file trackable by the integrity system
 type FileInfo struct {
-	Path     string `json:"path"`
-	Size     int64  `json:"size"`
-	Checksum string `json:"checksum"`
-	Override bool   `json:"override"`
+	Path     string      `json:"path"`
+	Size     int64       `json:"size"`
+	Checksum string      `json:"checksum"`
+	Override bool        `json:"override"`
+	Deltas   []DeltaInfo `json:"deltas,omitempty"`
+}
+
+// DeltaInfo describes a precomputed binary patch that upgrades a previous
+// version of a file (identified by its checksum) to the current one
+type DeltaInfo struct {
+	From string `json:"from"` // SHA256 of the file the patch applies to
+	Path string `json:"path"` // Patch location relative to /files/
+	Size int64  `json:"size"`
 }
@@ -0,0 +1,190 @@
+package integrity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// PackBaseDir keeps the last pack-provided copy of each mergeable user file,
+// which serves as the common ancestor for three-way merges
+const PackBaseDir = ".pack_base"
+
+// mergeableFiles maps line-based user config files to their key separator
+var mergeableFiles = map[string]string{
+	"options.txt":        ":",
+	"optionsof.txt":      ":",
+	"optionsshaders.txt": "=",
+}
+
+// MergeConflict is a key both the pack and the user changed differently
+type MergeConflict struct {
+	Key       string
+	UserValue string
+	PackValue string
+}
+
+func mergeSeparator(filePath string) (string, bool) {
+	sep, ok := mergeableFiles[path.Base(filePath)]
+	return sep, ok
+}
+
+// mergeUserFile brings pack changes into a user-owned config file without
+// losing the user's own edits
+func mergeUserFile(gameDir string, serverURL string, file FileInfo, sep string, cb func(string)) error {
+	localPath := filepath.Join(gameDir, file.Path)
+	basePath := filepath.Join(gameDir, PackBaseDir, file.Path)
+
+	// Nothing new from the pack since the last merge
+	if baseChecksum, err := fileChecksum(basePath); err == nil && baseChecksum == file.Checksum {
+		return nil
+	}
+
+	// A missing base is treated as empty: new keys are added, and anything
+	// the user already has wins
+	base, _ := os.ReadFile(basePath)
+
+	user, err := os.ReadFile(localPath)
+	if err != nil {
+		return err
+	}
+
+	pack, err := fetchFile(serverURL, file.Path)
+	if err != nil {
+		return err
+	}
+	// A bad download would end up in both the user's file and the base
+	sum := sha256.Sum256(pack)
+	if hex.EncodeToString(sum[:]) != file.Checksum {
+		return fmt.Errorf("checksum mismatch after download for %s", file.Path)
+	}
+
+	merged, conflicts := MergeKeyValue(string(base), string(pack), string(user), sep)
+	for _, c := range conflicts {
+		cb(fmt.Sprintf("Merge conflict in %s: kept your %s=%q (pack default is %q)", file.Path, c.Key, c.UserValue, c.PackValue))
+	}
+
+	if merged != string(user) {
+		cb(fmt.Sprintf("Merged new defaults into %s", file.Path))
+		if err := os.WriteFile(localPath, []byte(merged), 0644); err != nil {
+			return err
+		}
+	}
+
+	return saveMergeBase(basePath, pack)
+}
+
+func saveMergeBase(basePath string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(basePath), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(basePath, data, 0644)
+}
+
+func fetchFile(serverURL string, filePath string) ([]byte, error) {
+	resp, err := http.Get(fmt.Sprintf("%s/files/%s", serverURL, filePath))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("server download failed: %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// MergeKeyValue three-way merges line-based key/value files.
+// User edits win on conflict. The user's line order is kept and keys new to
+// the pack are appended in pack order.
+func MergeKeyValue(base, pack, user, sep string) (string, []MergeConflict) {
+	baseVals, _ := parseKeyValue(base, sep)
+	packVals, packOrder := parseKeyValue(pack, sep)
+
+	var conflicts []MergeConflict
+	var out []string
+	seen := make(map[string]bool)
+
+	for _, line := range splitLines(user) {
+		key, userVal, ok := splitKeyValue(line, sep)
+		if !ok {
+			out = append(out, line)
+			continue
+		}
+		seen[key] = true
+
+		baseVal, inBase := baseVals[key]
+		packVal, inPack := packVals[key]
+
+		switch {
+		case inBase && baseVal == userVal && !inPack:
+			// Pack removed a key the user never touched
+			continue
+		case inBase && baseVal == userVal:
+			out = append(out, key+sep+packVal)
+		case inPack && packVal != userVal && (!inBase || packVal != baseVal):
+			conflicts = append(conflicts, MergeConflict{Key: key, UserValue: userVal, PackValue: packVal})
+			out = append(out, line)
+		default:
+			out = append(out, line)
+		}
+	}
+
+	for _, key := range packOrder {
+		if seen[key] {
+			continue
+		}
+		if _, inBase := baseVals[key]; inBase {
+			// The user deleted it; respect that
+			continue
+		}
+		out = append(out, key+sep+packVals[key])
+	}
+
+	// Keep the user's file from gaining or losing a trailing newline
+	result := strings.Join(out, "\n")
+	if user == "" || strings.HasSuffix(user, "\n") {
+		result += "\n"
+	}
+	return result, conflicts
+}
+
+func parseKeyValue(content, sep string) (map[string]string, []string) {
+	values := make(map[string]string)
+	var order []string
+	for _, line := range splitLines(content) {
+		if key, val, ok := splitKeyValue(line, sep); ok {
+			if _, dup := values[key]; !dup {
+				order = append(order, key)
+			}
+			values[key] = val
+		}
+	}
+	return values, order
+}
+
+func splitKeyValue(line, sep string) (string, string, bool) {
+	line = strings.TrimRight(line, "\r")
+	if strings.HasPrefix(strings.TrimSpace(line), "#") {
+		return "", "", false
+	}
+	key, val, ok := strings.Cut(line, sep)
+	if !ok || key == "" {
+		return "", "", false
+	}
+	return key, val, true
+}
+
+func splitLines(content string) []string {
+	content = strings.TrimSuffix(content, "\n")
+	if content == "" {
+		return nil
+	}
+	return strings.Split(content, "\n")
+}
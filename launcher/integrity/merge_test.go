@@ -0,0 +1,84 @@
+package integrity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMergeKeyValue(t *testing.T) {
+	base := "fov:0.0\nkey_key.jump:57\nrenderDistance:8\nlegacyKey:1\n"
+	pack := "fov:0.0\nkey_key.jump:57\nrenderDistance:12\nkey_key.zoom:46\n"
+	user := "fov:0.5\nkey_key.jump:44\nrenderDistance:6\nlegacyKey:1\nlang:de_DE\n"
+
+	// The user edited jump and renderDistance; the pack changed renderDistance,
+	// added zoom and removed legacyKey
+	merged, conflicts := MergeKeyValue(base, pack, user, ":")
+
+	want := "fov:0.5\nkey_key.jump:44\nrenderDistance:6\nlang:de_DE\nkey_key.zoom:46\n"
+	if merged != want {
+		t.Errorf("merged =\n%s\nwant\n%s", merged, want)
+	}
+
+	wantConflicts := []MergeConflict{{Key: "renderDistance", UserValue: "6", PackValue: "12"}}
+	if !reflect.DeepEqual(conflicts, wantConflicts) {
+		t.Errorf("conflicts = %+v, want %+v", conflicts, wantConflicts)
+	}
+}
+
+func TestMergeKeyValue_NoBase(t *testing.T) {
+	// Without a base, the user's values always win and only new keys are added
+	merged, conflicts := MergeKeyValue("", "a=1\nb=2\n", "a=5\n", "=")
+	if merged != "a=5\nb=2\n" {
+		t.Errorf("unexpected merge result %q", merged)
+	}
+	if len(conflicts) != 1 || conflicts[0].Key != "a" {
+		t.Errorf("unexpected conflicts %+v", conflicts)
+	}
+}
+
+func TestMergeUserFile(t *testing.T) {
+	pack := "fov:0.0\nrenderDistance:12\n"
+	served := pack
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(served))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	userPath := filepath.Join(dir, "options.txt")
+	basePath := filepath.Join(dir, PackBaseDir, "options.txt")
+	os.WriteFile(userPath, []byte("fov:0.5\n"), 0644)
+	saveMergeBase(basePath, []byte("fov:0.0\n"))
+
+	sum := sha256.Sum256([]byte(pack))
+	file := FileInfo{Path: "options.txt", Checksum: hex.EncodeToString(sum[:])}
+
+	// A truncated download touches neither the user's file nor the base
+	served = "fov:0.0\nrender"
+	if err := mergeUserFile(dir, srv.URL, file, ":", func(string) {}); err == nil {
+		t.Error("expected a checksum mismatch to fail")
+	}
+	if data, _ := os.ReadFile(userPath); string(data) != "fov:0.5\n" {
+		t.Errorf("expected the user's file to be kept, got %q", data)
+	}
+	if data, _ := os.ReadFile(basePath); string(data) != "fov:0.0\n" {
+		t.Errorf("expected the base to be kept, got %q", data)
+	}
+
+	served = pack
+	if err := mergeUserFile(dir, srv.URL, file, ":", func(string) {}); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(userPath); string(data) != "fov:0.5\nrenderDistance:12\n" {
+		t.Errorf("unexpected merge result %q", data)
+	}
+	if data, _ := os.ReadFile(basePath); string(data) != pack {
+		t.Errorf("expected the pack copy as the new base, got %q", data)
+	}
+}
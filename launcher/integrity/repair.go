@@ -0,0 +1,72 @@
+package integrity
+
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
+
+// PackRepair is a pack file that failed deep verification
+type PackRepair struct {
+	FileProblem
+	Quarantined string `json:"quarantined,omitempty"` // Where the bad copy was moved, empty if it was missing
+	Repaired    bool   `json:"repaired"`
+}
+
+// Repair checks every file in the server's manifest, moves the ones that
+// don't match aside with quarantine and downloads them again. Files the
+// player may edit only need to exist unless includeUserFiles is set. It
+// returns the files it had to repair and how many it checked.
+func Repair(gameDir string, serverURL string, includeUserFiles bool, quarantine func(path string) (string, error), cb func(string)) ([]PackRepair, int, error) {
+	cb("Fetching modpack manifest...")
+	manifest, err := fetchManifest(serverURL)
+	if err != nil {
+		return nil, 0, fmt.Errorf("can't reach the modpack server: %w", err)
+	}
+
+	cb(fmt.Sprintf("Verifying %d modpack files...", len(manifest.Files)))
+	files := make(map[string]FileInfo, len(manifest.Files))
+	for _, file := range manifest.Files {
+		files[file.Path] = file
+	}
+
+	var repairs []PackRepair
+	failed := false
+	for _, problem := range verifyFiles(gameDir, manifest, includeUserFiles) {
+		r := PackRepair{FileProblem: problem}
+		if problem.Problem != ProblemMissing {
+			dest, err := quarantine(filepath.Join(gameDir, problem.Path))
+			if err != nil {
+				r.Error = fmt.Sprintf("quarantine failed: %v", err)
+				repairs = append(repairs, r)
+				failed = true
+				continue
+			}
+			r.Quarantined = dest
+		}
+
+		cb(fmt.Sprintf("Downloading: %s", problem.Path))
+		file := files[problem.Path]
+		if err := downloadFile(gameDir, serverURL, file); err != nil {
+			r.Error = err.Error()
+		} else if valid, err := verifyFileChecksum(gameDir, file); err != nil || !valid {
+			r.Error = "checksum mismatch after download"
+		} else {
+			r.Repaired = true
+			r.Error = ""
+		}
+		failed = failed || !r.Repaired
+		repairs = append(repairs, r)
+	}
+
+	// Everything now matches this manifest
+	if err := saveLocalManifest(filepath.Join(gameDir, LocalManifest), manifest); err != nil {
+		return repairs, len(manifest.Files), fmt.Errorf("failed to save local manifest: %w", err)
+	}
+	if !failed {
+		if err := saveState(filepath.Join(gameDir, LocalState), &clientState{LastVerified: time.Now()}); err != nil {
+			fmt.Printf("Warning: Failed to save client state: %v\n", err)
+		}
+	}
+	return repairs, len(manifest.Files), nil
+}
@@ -0,0 +1,107 @@
+package integrity
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"craft-launcher/launcher/nbt"
+)
+
+// ServersFile is the multiplayer server list, relative to the game dir
+const ServersFile = "servers.dat"
+
+// EnsureServers adds or updates the pack's servers in servers.dat.
+// Entries are matched by address, so servers the user added are left alone.
+// It reports whether the file was changed.
+func EnsureServers(gameDir string, servers []ServerEntry) (bool, error) {
+	path := filepath.Join(gameDir, ServersFile)
+
+	rootName, root, err := readServers(path)
+	if err != nil {
+		return false, err
+	}
+
+	list, _ := root.Get("servers").(*nbt.List)
+	if list == nil || (list.Type != nbt.TagCompound && len(list.Items) == 0) {
+		list = &nbt.List{Type: nbt.TagCompound}
+		root.Set("servers", list)
+	}
+	if list.Type != nbt.TagCompound {
+		return false, fmt.Errorf("%s: unexpected server list type %d", ServersFile, list.Type)
+	}
+
+	changed := false
+	for _, server := range servers {
+		entry := findServer(list, server.IP)
+		if entry == nil {
+			entry = &nbt.Compound{}
+			list.Items = append(list.Items, entry)
+			changed = true
+		}
+
+		if entry.GetString("name") != server.Name {
+			entry.Set("name", server.Name)
+			changed = true
+		}
+		if entry.GetString("ip") != server.IP {
+			entry.Set("ip", server.IP)
+			changed = true
+		}
+		if server.Icon != "" && entry.GetString("icon") != server.Icon {
+			entry.Set("icon", server.Icon)
+			changed = true
+		}
+	}
+
+	if !changed {
+		return false, nil
+	}
+	return true, writeServers(path, rootName, root)
+}
+
+func findServer(list *nbt.List, ip string) *nbt.Compound {
+	for _, item := range list.Items {
+		entry, ok := item.(*nbt.Compound)
+		if ok && strings.EqualFold(entry.GetString("ip"), ip) {
+			return entry
+		}
+	}
+	return nil
+}
+
+func readServers(path string) (string, *nbt.Compound, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return "", &nbt.Compound{}, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+
+	name, root, err := nbt.Read(f)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to parse %s: %w", ServersFile, err)
+	}
+	return name, root, nil
+}
+
+func writeServers(path string, rootName string, root *nbt.Compound) error {
+	var buf bytes.Buffer
+	if err := nbt.Write(&buf, rootName, root); err != nil {
+		return err
+	}
+
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
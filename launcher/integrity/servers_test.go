@@ -0,0 +1,98 @@
+package integrity
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"craft-launcher/launcher/nbt"
+)
+
+func TestEnsureServers(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "launcher_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// A user's servers.dat with their own server and an outdated pack entry
+	userEntry := &nbt.Compound{Fields: []nbt.Field{
+		{Name: "name", Value: "My Server"},
+		{Name: "ip", Value: "localhost:25566"},
+		{Name: "acceptTextures", Value: int8(1)},
+	}}
+	packEntry := &nbt.Compound{Fields: []nbt.Field{
+		{Name: "name", Value: "Old Name"},
+		{Name: "ip", Value: "MC.example.org"},
+	}}
+	root := &nbt.Compound{Fields: []nbt.Field{
+		{Name: "servers", Value: &nbt.List{Type: nbt.TagCompound, Items: []any{userEntry, packEntry}}},
+	}}
+
+	var original bytes.Buffer
+	if err := nbt.Write(&original, "", root); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(tmpDir, ServersFile)
+	if err := os.WriteFile(path, original.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Decoding and re-encoding must be lossless
+	_, decoded, err := nbt.Read(bytes.NewReader(original.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reencoded bytes.Buffer
+	nbt.Write(&reencoded, "", decoded)
+	if !bytes.Equal(reencoded.Bytes(), original.Bytes()) {
+		t.Fatal("nbt round trip changed the file")
+	}
+
+	servers := []ServerEntry{
+		{Name: "Pack Server", IP: "mc.example.org"},
+		{Name: "Pack Creative", IP: "creative.example.org", Icon: "aWNvbg=="},
+	}
+
+	changed, err := EnsureServers(tmpDir, servers)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Error("expected servers.dat to change")
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, updated, err := nbt.Read(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list := updated.Get("servers").(*nbt.List)
+	if len(list.Items) != 3 {
+		t.Fatalf("expected 3 servers, got %d", len(list.Items))
+	}
+
+	got := list.Items[0].(*nbt.Compound)
+	if got.GetString("name") != "My Server" || got.Get("acceptTextures") != int8(1) {
+		t.Errorf("user entry was modified: %+v", got)
+	}
+	got = list.Items[1].(*nbt.Compound)
+	if got.GetString("name") != "Pack Server" || got.GetString("ip") != "mc.example.org" {
+		t.Errorf("pack entry not updated: %+v", got)
+	}
+	got = list.Items[2].(*nbt.Compound)
+	if got.GetString("name") != "Pack Creative" || got.GetString("icon") != "aWNvbg==" {
+		t.Errorf("pack entry not added: %+v", got)
+	}
+
+	// A second run has nothing to do
+	if changed, err := EnsureServers(tmpDir, servers); err != nil || changed {
+		t.Errorf("second run: changed=%v err=%v", changed, err)
+	}
+}
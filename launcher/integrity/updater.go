@@ -14,9 +14,18 @@ import (
 
 const (
 	LocalManifest   = ".client_manifest.json"
+	LocalState      = ".client_state.json"
 	WhitelistedFile = "options.txt" // Basic whitelist logic
 )
 
+// retryDelay is the pause between manifest fetch attempts
+var retryDelay = 2 * time.Second
+
+// clientState records facts about previous update runs
+type clientState struct {
+	LastVerified time.Time `json:"lastVerified"`
+}
+
 // CheckAndUpdate handles the entire update flow
 func CheckAndUpdate(gameDir string, serverURL string, statusCallback func(string)) error {
 	// Ensure game dir exists
@@ -26,15 +35,21 @@ func CheckAndUpdate(gameDir string, serverURL string, statusCallback func(string
 
 	// 1. Fetch Server Manifest
 	statusCallback("Checking for updates...")
+	localManifestPath := filepath.Join(gameDir, LocalManifest)
 	serverManifest, err := fetchManifest(serverURL)
 	if err != nil {
-		// STRICT REQUIREMENT: Refuse to start if unable to connect to server.
+		// Refuse to start if unable to connect to server, unless the
+		// operator's cached offline policy says otherwise.
 		// OBFUSCATION: Do not show IP or detailed error.
+		localManifest, _ := loadLocalManifest(localManifestPath)
+		if reason, ok := checkOfflinePolicy(gameDir, localManifest); ok {
+			statusCallback(fmt.Sprintf("Server unreachable, launching offline (%s).", reason))
+			return nil
+		}
 		return fmt.Errorf("can't connect to server. You either need to:\n1. Connect to the internet\n2. Wait 30 seconds and try again")
 	}
 
 	// 2. Read Local Manifest
-	localManifestPath := filepath.Join(gameDir, LocalManifest)
 	localManifest, _ := loadLocalManifest(localManifestPath)
 
 	currentVersion := 0
@@ -57,15 +72,64 @@ func CheckAndUpdate(gameDir string, serverURL string, statusCallback func(string
 	if err := syncingUpdate(gameDir, serverURL, serverManifest, statusCallback); err != nil {
 		return err
 	}
+
+	// Make sure the pack's servers are in the multiplayer list
+	if len(serverManifest.Servers) > 0 {
+		changed, err := EnsureServers(gameDir, serverManifest.Servers)
+		if err != nil {
+			// Warn but don't fail update
+			fmt.Printf("Warning: Failed to update server list: %v\n", err)
+		} else if changed {
+			statusCallback("Updated multiplayer server list.")
+		}
+	}
 	// Save new manifest as local state
 	if err := saveLocalManifest(localManifestPath, serverManifest); err != nil {
 		return fmt.Errorf("failed to save local manifest: %w", err)
 	}
+	if err := saveState(filepath.Join(gameDir, LocalState), &clientState{LastVerified: time.Now()}); err != nil {
+		fmt.Printf("Warning: Failed to save client state: %v\n", err)
+	}
 	statusCallback("Integrity verified & up to date.")
 
 	return nil
 }
 
+// checkOfflinePolicy decides whether launching without the server is allowed,
+// based on the policy cached from the last successful update
+func checkOfflinePolicy(gameDir string, localManifest *Manifest) (string, bool) {
+	if localManifest == nil || localManifest.OfflinePolicy == nil {
+		return "", false
+	}
+
+	switch localManifest.OfflinePolicy.Mode {
+	case OfflinePolicyAllow:
+		return "always allowed", true
+	case OfflinePolicyGrace:
+		state, err := loadState(filepath.Join(gameDir, LocalState))
+		if err != nil {
+			return "", false
+		}
+		grace := time.Duration(localManifest.OfflinePolicy.GraceHours) * time.Hour
+		age := time.Since(state.LastVerified)
+		if age < 0 || age > grace {
+			return "", false
+		}
+		// Local files must still match what we last verified
+		for _, file := range localManifest.Files {
+			if !file.Override {
+				continue
+			}
+			if valid, err := verifyFileChecksum(gameDir, file); err != nil || !valid {
+				return "", false
+			}
+		}
+		return fmt.Sprintf("verified %s ago", age.Round(time.Minute)), true
+	default:
+		return "", false
+	}
+}
+
 func fetchManifest(serverURL string) (*Manifest, error) {
 	var resp *http.Response
 	var err error
@@ -80,7 +144,7 @@ func fetchManifest(serverURL string) (*Manifest, error) {
 		// Wait and retry if it's a network error (likely macOS permission prompt blocking)
 		if i < maxRetries-1 {
 			// fmt.Printf("Failed to connect (attempt %d/%d): %v. Retrying in 2s...\n", i+1, maxRetries, err)
-			time.Sleep(2 * time.Second)
+			time.Sleep(retryDelay)
 		}
 	}
 
@@ -107,6 +171,12 @@ func syncingUpdate(gameDir string, serverURL string, manifest *Manifest, cb func
 		localPath := filepath.Join(gameDir, file.Path)
 		if !file.Override {
 			if _, err := os.Stat(localPath); err == nil {
+				// Line-based configs get new pack defaults merged in
+				if sep, ok := mergeSeparator(file.Path); ok {
+					if err := mergeUserFile(gameDir, serverURL, file, sep, cb); err != nil {
+						fmt.Printf("Warning: Failed to merge %s: %v\n", file.Path, err)
+					}
+				}
 				// File exists, skipping
 				// cb(fmt.Sprintf("Skipping user file: %s", file.Path))
 				continue
@@ -114,15 +184,27 @@ func syncingUpdate(gameDir string, serverURL string, manifest *Manifest, cb func
 		}
 
 		// Check if file exists and matches checksum to avoid unnecessary re-download
+		localChecksum := ""
 		if _, err := os.Stat(localPath); err == nil {
-			valid, err := verifyFileChecksum(gameDir, file)
-			if err == nil && valid {
+			localChecksum, err = fileChecksum(localPath)
+			if err == nil && localChecksum == file.Checksum {
 				// File exists and is valid, skip download
 				// cb(fmt.Sprintf("Skipping unchanged file: %s", file.Path))
 				continue
 			}
 		}
 
+		// Prefer a delta patch when we hold the exact previous version
+		if delta := findDelta(file, localChecksum); delta != nil {
+			cb(fmt.Sprintf("Patching [%d/%d]: %s", i+1, len(manifest.Files), file.Path))
+			err := patchFile(gameDir, serverURL, file, *delta)
+			if err == nil {
+				continue
+			}
+			// Fall back to a full download below
+			fmt.Printf("Warning: Delta patch failed for %s: %v\n", file.Path, err)
+		}
+
 		cb(fmt.Sprintf("Downloading [%d/%d]: %s", i+1, len(manifest.Files), file.Path))
 
 		if err := downloadFile(gameDir, serverURL, file); err != nil {
@@ -137,6 +219,17 @@ func syncingUpdate(gameDir string, serverURL string, manifest *Manifest, cb func
 		if !valid {
 			return fmt.Errorf("checksum mismatch after download for %s", file.Path)
 		}
+
+		// Remember the pack version of mergeable user files as the merge base
+		if _, ok := mergeSeparator(file.Path); ok && !file.Override {
+			data, err := os.ReadFile(localPath)
+			if err == nil {
+				err = saveMergeBase(filepath.Join(gameDir, PackBaseDir, file.Path), data)
+			}
+			if err != nil {
+				fmt.Printf("Warning: Failed to save merge base for %s: %v\n", file.Path, err)
+			}
+		}
 	}
 	return nil
 }
@@ -192,21 +285,84 @@ func downloadFile(gameDir string, serverURL string, file FileInfo) error {
 	return err
 }
 
-func verifyFileChecksum(gameDir string, file FileInfo) (bool, error) {
+func findDelta(file FileInfo, localChecksum string) *DeltaInfo {
+	if localChecksum == "" {
+		return nil
+	}
+	for i := range file.Deltas {
+		if file.Deltas[i].From == localChecksum {
+			return &file.Deltas[i]
+		}
+	}
+	return nil
+}
+
+// patchFile downloads a delta and applies it to the local copy of file.
+// The result is only moved into place once its checksum matches.
+func patchFile(gameDir string, serverURL string, file FileInfo, delta DeltaInfo) error {
 	localPath := filepath.Join(gameDir, file.Path)
-	f, err := os.Open(localPath)
+
+	oldData, err := os.ReadFile(localPath)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.Get(fmt.Sprintf("%s/files/%s", serverURL, delta.Path))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("server delta download failed: %s", resp.Status)
+	}
+
+	newData, err := ApplyDelta(oldData, resp.Body, file.Size)
+	if err != nil {
+		return err
+	}
+
+	sum := sha256.Sum256(newData)
+	if hex.EncodeToString(sum[:]) != file.Checksum {
+		return fmt.Errorf("checksum mismatch after patching")
+	}
+
+	tmpPath := localPath + ".patch.tmp"
+	if err := os.WriteFile(tmpPath, newData, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, localPath); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+func verifyFileChecksum(gameDir string, file FileInfo) (bool, error) {
+	checksum, err := fileChecksum(filepath.Join(gameDir, file.Path))
 	if err != nil {
 		return false, err
 	}
+	return checksum == file.Checksum, nil
+}
+
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
 	defer f.Close()
 
 	h := sha256.New()
 	if _, err := io.Copy(h, f); err != nil {
-		return false, err
+		return "", err
 	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
 
-	checksum := hex.EncodeToString(h.Sum(nil))
-	return checksum == file.Checksum, nil
+// LoadLocalManifest returns the manifest saved by the last successful update
+func LoadLocalManifest(gameDir string) (*Manifest, error) {
+	return loadLocalManifest(filepath.Join(gameDir, LocalManifest))
 }
 
 func loadLocalManifest(path string) (*Manifest, error) {
@@ -223,6 +379,27 @@ func loadLocalManifest(path string) (*Manifest, error) {
 	return &m, nil
 }
 
+func loadState(path string) (*clientState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var state clientState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func saveState(path string, state *clientState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
 func saveLocalManifest(path string, m *Manifest) error {
 	f, err := os.Create(path)
 	if err != nil {
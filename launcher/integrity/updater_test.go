@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestManifestUpdate_ContentChangeSameVersion(t *testing.T) {
@@ -94,3 +95,64 @@ func TestManifestUpdate_ContentChangeSameVersion(t *testing.T) {
 		t.Errorf("Local manifest was not updated correctly. Got: %+v", updatedLocalManifest)
 	}
 }
+
+func TestCheckAndUpdate_OfflinePolicy(t *testing.T) {
+	retryDelay = 0
+	defer func() { retryDelay = 2 * time.Second }()
+
+	tmpDir, err := os.MkdirTemp("", "launcher_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	fileA := FileInfo{Path: "mods/A.jar", Size: 4, Checksum: "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08", Override: true} // "test"
+	fileAPath := filepath.Join(tmpDir, fileA.Path)
+	os.MkdirAll(filepath.Dir(fileAPath), 0755)
+	if err := os.WriteFile(fileAPath, []byte("test"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A server that is already gone
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	writeLocal := func(policy *OfflinePolicy, verified time.Time) {
+		m := Manifest{Version: 1, Files: []FileInfo{fileA}, OfflinePolicy: policy}
+		if err := saveLocalManifest(filepath.Join(tmpDir, LocalManifest), &m); err != nil {
+			t.Fatal(err)
+		}
+		if err := saveState(filepath.Join(tmpDir, LocalState), &clientState{LastVerified: verified}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	check := func() error {
+		return CheckAndUpdate(tmpDir, server.URL, func(string) {})
+	}
+
+	writeLocal(nil, time.Now())
+	if err := check(); err == nil {
+		t.Error("strict (default) policy should refuse to launch offline")
+	}
+
+	writeLocal(&OfflinePolicy{Mode: OfflinePolicyGrace, GraceHours: 24}, time.Now().Add(-time.Hour))
+	if err := check(); err != nil {
+		t.Errorf("grace policy should allow recent verification: %v", err)
+	}
+
+	writeLocal(&OfflinePolicy{Mode: OfflinePolicyGrace, GraceHours: 24}, time.Now().Add(-48*time.Hour))
+	if err := check(); err == nil {
+		t.Error("grace policy should refuse an expired verification")
+	}
+
+	writeLocal(&OfflinePolicy{Mode: OfflinePolicyGrace, GraceHours: 24}, time.Now())
+	os.WriteFile(fileAPath, []byte("tampered"), 0644)
+	if err := check(); err == nil {
+		t.Error("grace policy should refuse when local files changed")
+	}
+
+	writeLocal(&OfflinePolicy{Mode: OfflinePolicyAllow}, time.Time{})
+	if err := check(); err != nil {
+		t.Errorf("allow policy should always launch: %v", err)
+	}
+}
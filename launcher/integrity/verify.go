@@ -0,0 +1,68 @@
+package integrity
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Reasons a managed file fails verification
+const (
+	ProblemMissing    = "missing"
+	ProblemModified   = "modified"
+	ProblemUnreadable = "unreadable"
+)
+
+// FileProblem is a managed file that doesn't match the manifest
+type FileProblem struct {
+	Path     string `json:"path"`
+	Problem  string `json:"problem"`
+	Expected string `json:"expected,omitempty"` // Manifest checksum
+	Actual   string `json:"actual,omitempty"`   // Checksum on disk
+	Error    string `json:"error,omitempty"`
+}
+
+// Verify checks the files listed in m against gameDir without downloading
+// anything. Files the player may edit (Override false) only need to exist.
+func Verify(gameDir string, m *Manifest) []FileProblem {
+	return verifyFiles(gameDir, m, false)
+}
+
+// verifyFiles is Verify, optionally checksumming user files too
+func verifyFiles(gameDir string, m *Manifest, includeUserFiles bool) []FileProblem {
+	var problems []FileProblem
+	for _, file := range m.Files {
+		path := filepath.Join(gameDir, file.Path)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			problems = append(problems, FileProblem{Path: file.Path, Problem: ProblemMissing})
+			continue
+		}
+		if !file.Override && !includeUserFiles {
+			continue
+		}
+
+		checksum, err := fileChecksum(path)
+		if err != nil {
+			problems = append(problems, FileProblem{Path: file.Path, Problem: ProblemUnreadable, Error: err.Error()})
+			continue
+		}
+		if checksum != file.Checksum {
+			problems = append(problems, FileProblem{
+				Path:     file.Path,
+				Problem:  ProblemModified,
+				Expected: file.Checksum,
+				Actual:   checksum,
+			})
+		}
+	}
+	return problems
+}
+
+// LastVerified returns when the last successful update of gameDir finished
+func LastVerified(gameDir string) (time.Time, bool) {
+	state, err := loadState(filepath.Join(gameDir, LocalState))
+	if err != nil || state.LastVerified.IsZero() {
+		return time.Time{}, false
+	}
+	return state.LastVerified, true
+}
@@ -0,0 +1,34 @@
+package integrity
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerify(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "mods"), 0755)
+	os.WriteFile(filepath.Join(dir, "mods", "ok.jar"), []byte("test"), 0644)
+	os.WriteFile(filepath.Join(dir, "mods", "changed.jar"), []byte("other"), 0644)
+	os.WriteFile(filepath.Join(dir, "options.txt"), []byte("fov:90"), 0644)
+
+	const testSum = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08" // "test"
+	m := &Manifest{Files: []FileInfo{
+		{Path: "mods/ok.jar", Checksum: testSum, Override: true},
+		{Path: "mods/changed.jar", Checksum: testSum, Override: true},
+		{Path: "mods/gone.jar", Checksum: testSum, Override: true},
+		{Path: "options.txt", Checksum: testSum}, // Player's edits are fine
+	}}
+
+	problems := Verify(dir, m)
+	if len(problems) != 2 {
+		t.Fatalf("expected 2 problems, got %+v", problems)
+	}
+	if p := problems[0]; p.Path != "mods/changed.jar" || p.Problem != ProblemModified || p.Expected != testSum || p.Actual == "" {
+		t.Errorf("unexpected problem %+v", p)
+	}
+	if p := problems[1]; p.Path != "mods/gone.jar" || p.Problem != ProblemMissing {
+		t.Errorf("unexpected problem %+v", p)
+	}
+}
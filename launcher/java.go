@@ -7,9 +7,12 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
 	"runtime"
 	"strings"
+	"sync"
 )
 
 // JRE download URLs for different platforms (Java 8)
@@ -22,9 +25,10 @@ var jreDownloadURLs = map[string]string{
 	"linux-amd64":   "https://cdn.azul.com/zulu/bin/zulu8.78.0.19-ca-jdk8.0.412-linux_x64.tar.gz",
 }
 
-func EnsureJava(gameDir string) (string, error) {
+// EnsureJava returns the bundled JRE in sharedDir, downloading it if needed
+func EnsureJava(sharedDir string) (string, error) {
 	// Reverted to native architecture (arm64 on M1) because we are now patching the natives.
-	jreDir := filepath.Join(gameDir, fmt.Sprintf("jre-%s-%s", runtime.GOOS, runtime.GOARCH))
+	jreDir := JREDir(sharedDir)
 
 	// Check if exists
 	if execPath := findJavaExecutable(jreDir); execPath != "" {
@@ -33,13 +37,111 @@ func EnsureJava(gameDir string) (string, error) {
 
 	// Download
 	fmt.Println("JRE not found, downloading...")
-	if err := downloadAndInstallJRE(gameDir, jreDir, runtime.GOARCH); err != nil {
+	if err := downloadAndInstallJRE(sharedDir, jreDir, runtime.GOARCH); err != nil {
 		return "", err
 	}
 
 	return findJavaExecutable(jreDir), nil
 }
 
+// FindJava returns the bundled JRE in sharedDir without downloading it, or
+// "" if it isn't installed
+func FindJava(sharedDir string) string {
+	return findJavaExecutable(JREDir(sharedDir))
+}
+
+// JREDir returns where the bundled JRE for this platform is installed
+func JREDir(sharedDir string) string {
+	return filepath.Join(sharedDir, fmt.Sprintf("jre-%s-%s", runtime.GOOS, runtime.GOARCH))
+}
+
+// JavaVersion returns the first line of "java -version", e.g.
+// openjdk version "1.8.0_412"
+func JavaVersion(javaPath string) (string, error) {
+	javaPath = consoleJava(javaPath)
+	out, err := javaCommand(javaPath, "-version").CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("%s -version: %w", javaPath, err)
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	return strings.TrimSpace(line), nil
+}
+
+// dataModel finds the JVM's pointer size in -XshowSettings:properties output
+var dataModel = regexp.MustCompile(`sun\.arch\.data\.model = (\d+)`)
+
+// javaBits remembers each java executable's data model
+var javaBits sync.Map
+
+// Java64Bit reports whether a java executable is a 64-bit JVM, which
+// decides how much heap it can be given
+func Java64Bit(javaPath string) (bool, error) {
+	if bits, ok := javaBits.Load(javaPath); ok {
+		return bits.(bool), nil
+	}
+	out, err := javaCommand(consoleJava(javaPath), "-XshowSettings:properties", "-version").CombinedOutput()
+	if err != nil {
+		return false, fmt.Errorf("%s -XshowSettings:properties: %w", javaPath, err)
+	}
+	var is64 bool
+	if m := dataModel.FindSubmatch(out); m != nil {
+		is64 = string(m[1]) == "64"
+	} else {
+		// Old or unusual JVMs name their bitness in the version banner
+		is64 = strings.Contains(string(out), "64-Bit")
+	}
+	javaBits.Store(javaPath, is64)
+	return is64, nil
+}
+
+// unrecognizedOption finds the option Java names when it refuses to start
+var unrecognizedOption = regexp.MustCompile(`Unrecognized (?:VM )?option '?([^'\s]+)'?|Improperly specified VM option '([^']+)'|Invalid (?:initial|maximum) heap size: (\S+)`)
+
+// probed remembers which option sets a java executable accepted
+var probed sync.Map
+
+// ProbeJVMOptions starts Java with the given -X/-XX options to find any it
+// doesn't know, since that stops the game from starting at all
+func ProbeJVMOptions(javaPath string, opts []string) error {
+	if len(opts) == 0 {
+		return nil
+	}
+	key := javaPath + "\x00" + strings.Join(opts, "\x00")
+	if _, ok := probed.Load(key); ok {
+		return nil
+	}
+
+	args := append(append([]string{}, opts...), "-version")
+	out, err := javaCommand(consoleJava(javaPath), args...).CombinedOutput()
+	if err == nil {
+		probed.Store(key, true)
+		return nil
+	}
+	if m := unrecognizedOption.FindStringSubmatch(string(out)); m != nil {
+		return fmt.Errorf("java doesn't support the JVM option %s", m[1]+m[2]+m[3])
+	}
+	if _, ok := err.(*exec.ExitError); ok {
+		line, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+		return fmt.Errorf("java rejected the JVM options: %s", line)
+	}
+	return fmt.Errorf("failed to run %s: %w", javaPath, err)
+}
+
+// javaCommand runs java.exe for its output without showing a console
+func javaCommand(javaPath string, args ...string) *exec.Cmd {
+	cmd := exec.Command(javaPath, args...)
+	hideConsole(cmd)
+	return cmd
+}
+
+// consoleJava swaps javaw.exe, which has no console to print to, for java.exe
+func consoleJava(javaPath string) string {
+	if strings.EqualFold(filepath.Base(javaPath), "javaw.exe") {
+		return filepath.Join(filepath.Dir(javaPath), "java.exe")
+	}
+	return javaPath
+}
+
 func findJavaExecutable(jrePath string) string {
 	// On Windows, prefer javaw.exe (no console) over java.exe
 	targetExecs := []string{"java"}
@@ -0,0 +1,53 @@
+//go:build !windows
+
+package launcher
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakeJava writes a script that rejects -XX:+Bogus like HotSpot does
+func fakeJava(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "java")
+	script := `#!/bin/sh
+for arg in "$@"; do
+  case "$arg" in
+    -XX:+Bogus)
+      echo "Unrecognized VM option 'Bogus'" >&2
+      echo "Error: Could not create the Java Virtual Machine." >&2
+      exit 1;;
+    -XshowSettings:properties)
+      echo "    sun.arch.data.model = 32" >&2;;
+  esac
+done
+echo 'openjdk version "1.8.0_412"' >&2
+`
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestProbeJVMOptions(t *testing.T) {
+	java := fakeJava(t)
+
+	if err := ProbeJVMOptions(java, []string{"-Xms512M", "-XX:+UseG1GC"}); err != nil {
+		t.Errorf("expected known options to pass: %v", err)
+	}
+	err := ProbeJVMOptions(java, []string{"-XX:+UseG1GC", "-XX:+Bogus"})
+	if err == nil || !strings.Contains(err.Error(), "Bogus") {
+		t.Errorf("expected the unknown option to be named, got %v", err)
+	}
+
+	version, err := JavaVersion(java)
+	if err != nil || version != `openjdk version "1.8.0_412"` {
+		t.Errorf("unexpected version %q, %v", version, err)
+	}
+
+	if is64, err := Java64Bit(java); err != nil || is64 {
+		t.Errorf("expected a 32-bit JVM, got 64-bit=%v, %v", is64, err)
+	}
+}
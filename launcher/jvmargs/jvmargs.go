@@ -0,0 +1,273 @@
+// Package jvmargs validates custom JVM and game arguments and provides
+// named argument presets that modpacks can extend.
+package jvmargs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Preset is a named set of arguments a player can pick for an instance
+type Preset struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	JVMArgs     []string `json:"jvmArgs"`
+	GameArgs    []string `json:"gameArgs,omitempty"`
+}
+
+// BuiltinPresets ship with the launcher
+var BuiltinPresets = []Preset{
+	{
+		ID:          "low-end",
+		Name:        "Low-end",
+		Description: "Small heap steps and a simple collector for machines with little RAM or few cores.",
+		JVMArgs: []string{
+			"-Xms256M",
+			"-XX:+UseSerialGC",
+			"-XX:+UseCompressedOops",
+			"-XX:+DisableExplicitGC",
+		},
+	},
+	{
+		ID:          "aikar-g1",
+		Name:        "Aikar G1",
+		Description: "Aikar's tuned G1 flags: fewer and shorter lag spikes from garbage collection.",
+		JVMArgs: []string{
+			"-XX:+UseG1GC",
+			"-XX:+ParallelRefProcEnabled",
+			"-XX:MaxGCPauseMillis=200",
+			"-XX:+UnlockExperimentalVMOptions",
+			"-XX:+DisableExplicitGC",
+			"-XX:+AlwaysPreTouch",
+			"-XX:G1NewSizePercent=30",
+			"-XX:G1MaxNewSizePercent=40",
+			"-XX:G1HeapRegionSize=8M",
+			"-XX:G1ReservePercent=20",
+			"-XX:G1HeapWastePercent=5",
+			"-XX:G1MixedGCCountTarget=4",
+			"-XX:InitiatingHeapOccupancyPercent=15",
+			"-XX:G1MixedGCLiveThresholdPercent=90",
+			"-XX:G1RSetUpdatingPauseTimePercent=5",
+			"-XX:SurvivorRatio=32",
+			"-XX:+PerfDisableSharedMem",
+			"-XX:MaxTenuringThreshold=1",
+		},
+	},
+	{
+		ID:          "debug-jdwp",
+		Name:        "Debug with JDWP",
+		Description: "Lets a Java debugger attach on localhost:5005. For mod developers only.",
+		JVMArgs: []string{
+			"-agentlib:jdwp=transport=dt_socket,server=y,suspend=n,address=127.0.0.1:5005",
+		},
+	},
+}
+
+// MergePresets combines pack-supplied presets with the built-in ones. Pack
+// presets come first and replace built-in presets with the same ID.
+func MergePresets(pack []Preset) []Preset {
+	ids := make(map[string]bool)
+	presets := make([]Preset, 0, len(pack)+len(BuiltinPresets))
+	for _, p := range pack {
+		ids[p.ID] = true
+		presets = append(presets, p)
+	}
+	for _, p := range BuiltinPresets {
+		if !ids[p.ID] {
+			presets = append(presets, p)
+		}
+	}
+	return presets
+}
+
+// Find returns the preset with the given ID
+func Find(presets []Preset, id string) (Preset, bool) {
+	for _, p := range presets {
+		if p.ID == id {
+			return p, true
+		}
+	}
+	return Preset{}, false
+}
+
+// Merge returns a preset's arguments followed by an instance's own. Preset
+// options the instance sets too are left out, so the instance's win.
+func Merge(preset Preset, jvmArgs, gameArgs []string) ([]string, []string) {
+	ownJVM := make(map[string]bool)
+	for _, arg := range jvmArgs {
+		ownJVM[optionKey(arg)] = true
+	}
+	var jvm []string
+	for _, arg := range preset.JVMArgs {
+		if !ownJVM[optionKey(arg)] || isAgent(arg) {
+			jvm = append(jvm, arg)
+		}
+	}
+
+	ownGame := make(map[string]bool)
+	for _, arg := range gameArgs {
+		if strings.HasPrefix(arg, "--") {
+			ownGame[gameOption(arg)] = true
+		}
+	}
+	var game []string
+	skipping := false
+	for _, arg := range preset.GameArgs {
+		if strings.HasPrefix(arg, "--") {
+			skipping = ownGame[gameOption(arg)]
+		}
+		if !skipping {
+			game = append(game, arg)
+		}
+	}
+
+	return append(jvm, jvmArgs...), append(game, gameArgs...)
+}
+
+// JVM arguments the launcher sets itself
+var managedJVMArgs = map[string]string{
+	"-cp":                 "the launcher builds the classpath",
+	"-classpath":          "the launcher builds the classpath",
+	"--class-path":        "the launcher builds the classpath",
+	"-jar":                "the launcher picks the main class",
+	"-Xmx":                "use the RAM setting instead",
+	"-Djava.library.path": "the launcher sets the natives dir",
+}
+
+// Game arguments the launcher sets itself. The game rejects an option
+// given twice, so these can't be overridden either.
+var managedGameArgs = map[string]string{
+	"--username":       "",
+	"--uuid":           "",
+	"--accessToken":    "",
+	"--userType":       "",
+	"--userProperties": "",
+	"--version":        "",
+	"--gameDir":        "",
+	"--assetsDir":      "",
+	"--assetIndex":     "",
+	"--server":         "use the auto-join server setting instead",
+	"--port":           "use the auto-join server setting instead",
+	"--width":          "use the window settings instead",
+	"--height":         "use the window settings instead",
+	"--fullscreen":     "use the window settings instead",
+}
+
+// Check validates custom arguments without running Java: nothing the
+// launcher manages, no option given twice, and -Xms within ramMB
+func Check(jvmArgs, gameArgs []string, ramMB int) error {
+	seen := make(map[string]string)
+	for _, arg := range jvmArgs {
+		if !strings.HasPrefix(arg, "-") {
+			return fmt.Errorf("JVM argument %q must start with -", arg)
+		}
+		key := optionKey(arg)
+		if reason, ok := managedJVMArgs[key]; ok {
+			return fmt.Errorf("JVM argument %s is not allowed: %s", arg, reason)
+		}
+		if prev, ok := seen[key]; ok {
+			return fmt.Errorf("JVM arguments %s and %s conflict", prev, arg)
+		}
+		seen[key] = arg
+
+		if key == "-Xms" && ramMB > 0 {
+			size, err := parseSize(arg[len("-Xms"):])
+			if err != nil {
+				return fmt.Errorf("invalid JVM argument %s: %w", arg, err)
+			}
+			if size > int64(ramMB)*1024*1024 {
+				return fmt.Errorf("%s is more than the %d MiB RAM allocation", arg, ramMB)
+			}
+		}
+	}
+
+	seenGame := make(map[string]bool)
+	for _, arg := range gameArgs {
+		if !strings.HasPrefix(arg, "--") {
+			continue // Value of the previous option
+		}
+		name := gameOption(arg)
+		if reason, ok := managedGameArgs[name]; ok {
+			if reason != "" {
+				return fmt.Errorf("game argument %s is set by the launcher, %s", name, reason)
+			}
+			return fmt.Errorf("game argument %s is set by the launcher", name)
+		}
+		if seenGame[name] {
+			return fmt.Errorf("game argument %s is given twice", name)
+		}
+		seenGame[name] = true
+	}
+	return nil
+}
+
+// VMOptions returns the -X and -XX options, which Java rejects when it
+// doesn't know them
+func VMOptions(jvmArgs []string) []string {
+	var opts []string
+	for _, arg := range jvmArgs {
+		if strings.HasPrefix(arg, "-X") {
+			opts = append(opts, arg)
+		}
+	}
+	return opts
+}
+
+// optionKey identifies what an argument sets, so -XX:+UseG1GC and
+// -XX:-UseG1GC or two -Dfoo= values are seen as the same option
+func optionKey(arg string) string {
+	switch {
+	case strings.HasPrefix(arg, "-XX:"):
+		name := strings.TrimLeft(arg[len("-XX:"):], "+-")
+		name, _, _ = strings.Cut(name, "=")
+		return "-XX:" + name
+	case strings.HasPrefix(arg, "-D"):
+		key, _, _ := strings.Cut(arg, "=")
+		return key
+	case isAgent(arg):
+		return arg // Several agents may be loaded
+	}
+	for _, sized := range []string{"-Xms", "-Xmx", "-Xss", "-Xmn"} {
+		if strings.HasPrefix(arg, sized) {
+			return sized
+		}
+	}
+	key, _, _ := strings.Cut(arg, "=")
+	return key
+}
+
+func isAgent(arg string) bool {
+	return strings.HasPrefix(arg, "-agentlib:") || strings.HasPrefix(arg, "-javaagent:")
+}
+
+// gameOption returns the name of a game option given as --name or --name=value
+func gameOption(arg string) string {
+	name, _, _ := strings.Cut(arg, "=")
+	return name
+}
+
+// parseSize parses a JVM memory size such as 512M or 2G into bytes
+func parseSize(s string) (int64, error) {
+	if s == "" {
+		return 0, fmt.Errorf("missing size")
+	}
+	mult := int64(1)
+	switch s[len(s)-1] {
+	case 'k', 'K':
+		mult = 1024
+	case 'm', 'M':
+		mult = 1024 * 1024
+	case 'g', 'G':
+		mult = 1024 * 1024 * 1024
+	}
+	if mult > 1 {
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * mult, nil
+}
@@ -0,0 +1,88 @@
+package jvmargs
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	ok := []struct {
+		jvm, game []string
+	}{
+		{[]string{"-Xms1G", "-XX:+UseG1GC", "-Dfml.ignoreInvalidMinecraftCertificates=true"}, []string{"--resourcePackDir", "packs"}},
+		{BuiltinPresets[1].JVMArgs, nil},
+		{[]string{"-javaagent:a.jar", "-javaagent:b.jar"}, nil},
+	}
+	for _, c := range ok {
+		if err := Check(c.jvm, c.game, 2048); err != nil {
+			t.Errorf("expected %v %v to pass: %v", c.jvm, c.game, err)
+		}
+	}
+
+	bad := []struct {
+		jvm, game []string
+		want      string
+	}{
+		{[]string{"-cp", "evil.jar"}, nil, "classpath"},
+		{[]string{"-Xmx4G"}, nil, "RAM setting"},
+		{[]string{"-XX:+UseG1GC", "-XX:-UseG1GC"}, nil, "conflict"},
+		{[]string{"-Dfoo=1", "-Dfoo=2"}, nil, "conflict"},
+		{[]string{"-Xms4G"}, nil, "RAM allocation"},
+		{[]string{"-Xms1Q"}, nil, "invalid"},
+		{[]string{"UseG1GC"}, nil, "must start with -"},
+		{nil, []string{"--username", "Notch"}, "set by the launcher"},
+		{nil, []string{"--resourcePackDir", "a", "--resourcePackDir=b"}, "twice"},
+		{nil, []string{"--width", "1280"}, "window settings"},
+		{nil, []string{"--fullscreen"}, "window settings"},
+	}
+	for _, c := range bad {
+		err := Check(c.jvm, c.game, 2048)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("expected %v %v to fail with %q, got %v", c.jvm, c.game, c.want, err)
+		}
+	}
+}
+
+func TestMerge(t *testing.T) {
+	preset := Preset{
+		JVMArgs:  []string{"-XX:+UseG1GC", "-XX:MaxGCPauseMillis=200", "-javaagent:a.jar"},
+		GameArgs: []string{"--resourcePackDir", "packs", "--demo"},
+	}
+	jvm, game := Merge(preset, []string{"-XX:MaxGCPauseMillis=50", "-javaagent:b.jar"}, []string{"--resourcePackDir=mine"})
+	want := "-XX:+UseG1GC -javaagent:a.jar -XX:MaxGCPauseMillis=50 -javaagent:b.jar"
+	if got := strings.Join(jvm, " "); got != want {
+		t.Errorf("expected JVM args %q, got %q", want, got)
+	}
+	if got := strings.Join(game, " "); got != "--demo --resourcePackDir=mine" {
+		t.Errorf("unexpected game args %q", got)
+	}
+	if err := Check(jvm, game, 2048); err != nil {
+		t.Errorf("expected merged arguments to pass: %v", err)
+	}
+
+	// Tweaking a built-in preset is fine
+	jvm, _ = Merge(BuiltinPresets[0], []string{"-XX:-DisableExplicitGC"}, nil)
+	if err := Check(jvm, nil, 2048); err != nil {
+		t.Errorf("expected a tweaked preset to pass: %v", err)
+	}
+}
+
+func TestMergePresets(t *testing.T) {
+	pack := []Preset{
+		{ID: "aikar-g1", Name: "Pack G1", JVMArgs: []string{"-XX:+UseG1GC"}},
+		{ID: "pack-zgc", Name: "ZGC"},
+	}
+	presets := MergePresets(pack)
+	if len(presets) != len(BuiltinPresets)+1 {
+		t.Fatalf("unexpected presets %+v", presets)
+	}
+	if p, ok := Find(presets, "aikar-g1"); !ok || p.Name != "Pack G1" {
+		t.Errorf("expected the pack to replace the built-in preset, got %+v", p)
+	}
+	if _, ok := Find(presets, "debug-jdwp"); !ok {
+		t.Error("expected built-in presets to be kept")
+	}
+	if got := VMOptions([]string{"-Xms1G", "-Dfoo=1", "-XX:+UseG1GC", "-agentlib:jdwp"}); len(got) != 2 {
+		t.Errorf("unexpected VM options %v", got)
+	}
+}
@@ -8,12 +8,15 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+
+	"craft-launcher/launcher/cache"
 )
 
-// DownloadLibraries downloads all required libraries and extracts natives
-func DownloadLibraries(libs []Library, gameDir string) (string, error) {
-	libsDir := filepath.Join(gameDir, "libraries")
-	nativesDir := filepath.Join(gameDir, "natives")
+// DownloadLibraries downloads all required libraries into the shared dir,
+// through the object store when one is given, and extracts natives into
+// nativesDir
+func DownloadLibraries(libs []Library, sharedDir string, nativesDir string, store *cache.Store) (string, error) {
+	libsDir := filepath.Join(sharedDir, "libraries")
 
 	if err := os.MkdirAll(nativesDir, 0755); err != nil {
 		return "", err
@@ -29,7 +32,7 @@ func DownloadLibraries(libs []Library, gameDir string) (string, error) {
 		// Handle Main Artifact
 		if lib.Downloads.Artifact != nil {
 			path := filepath.Join(libsDir, lib.Downloads.Artifact.Path)
-			if err := ensureLibrary(lib.Downloads.Artifact, path); err != nil {
+			if err := ensureLibrary(lib.Downloads.Artifact, path, store); err != nil {
 				fmt.Printf("Failed to download library %s: %v\n", lib.Name, err)
 			} else {
 				// Convert to absolute path for classpath
@@ -39,26 +42,12 @@ func DownloadLibraries(libs []Library, gameDir string) (string, error) {
 		}
 
 		// Handle Natives
-		if lib.Natives != nil {
-			nativeKey := ""
-			switch runtime.GOOS {
-			case "windows":
-				nativeKey = "windows"
-			case "darwin": // macOS
-				nativeKey = "osx"
-			case "linux":
-				nativeKey = "linux"
-			}
-
-			if classifier, ok := lib.Natives[nativeKey]; ok {
-				if artifact, exists := lib.Downloads.Classifiers[classifier]; exists {
-					path := filepath.Join(libsDir, artifact.Path)
-					if err := ensureLibrary(artifact, path); err == nil {
-						// Extract native
-						if err := extractNative(path, nativesDir); err != nil {
-							fmt.Printf("Failed to extract native %s: %v\n", lib.Name, err)
-						}
-					}
+		if artifact := nativeArtifact(lib); artifact != nil {
+			path := filepath.Join(libsDir, artifact.Path)
+			if err := ensureLibrary(artifact, path, store); err == nil {
+				// Extract native
+				if err := extractNative(path, nativesDir); err != nil {
+					fmt.Printf("Failed to extract native %s: %v\n", lib.Name, err)
 				}
 			}
 		}
@@ -67,6 +56,28 @@ func DownloadLibraries(libs []Library, gameDir string) (string, error) {
 	return strings.Join(cp, string(os.PathListSeparator)), nil
 }
 
+// nativeArtifact returns the jar of natives a library has for this OS, or
+// nil if it has none
+func nativeArtifact(lib Library) *Artifact {
+	if lib.Natives == nil {
+		return nil
+	}
+	nativeKey := ""
+	switch runtime.GOOS {
+	case "windows":
+		nativeKey = "windows"
+	case "darwin": // macOS
+		nativeKey = "osx"
+	case "linux":
+		nativeKey = "linux"
+	}
+	classifier, ok := lib.Natives[nativeKey]
+	if !ok {
+		return nil
+	}
+	return lib.Downloads.Classifiers[classifier]
+}
+
 func shouldDownloadLibrary(lib Library) bool {
 	if len(lib.Rules) == 0 {
 		return true
@@ -95,7 +106,10 @@ func isOSMatch(osName string) bool {
 	}
 }
 
-func ensureLibrary(artifact *Artifact, dest string) error {
+func ensureLibrary(artifact *Artifact, dest string, store *cache.Store) error {
+	if store != nil {
+		return store.Fetch(artifact.Sha1, artifact.URL, dest)
+	}
 	if _, err := os.Stat(dest); os.IsNotExist(err) {
 		if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
 			return err
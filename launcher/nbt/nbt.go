@@ -0,0 +1,390 @@
+// Package nbt reads and writes Minecraft's uncompressed Named Binary Tag format,
+// as used by servers.dat.
+package nbt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Tag type IDs
+const (
+	TagEnd byte = iota
+	TagByte
+	TagShort
+	TagInt
+	TagLong
+	TagFloat
+	TagDouble
+	TagByteArray
+	TagString
+	TagList
+	TagCompound
+	TagIntArray
+	TagLongArray
+)
+
+// Values decode to these Go types:
+//
+//	TagByte int8, TagShort int16, TagInt int32, TagLong int64,
+//	TagFloat float32, TagDouble float64, TagByteArray []byte,
+//	TagString string, TagList *List, TagCompound *Compound,
+//	TagIntArray []int32, TagLongArray []int64
+
+// List is a homogeneous list of values
+type List struct {
+	Type  byte
+	Items []any
+}
+
+// Field is a named entry of a compound
+type Field struct {
+	Name  string
+	Value any
+}
+
+// Compound is an ordered set of named values. Order is kept so files
+// round-trip byte for byte.
+type Compound struct {
+	Fields []Field
+}
+
+// Get returns the value stored under name, or nil
+func (c *Compound) Get(name string) any {
+	for _, f := range c.Fields {
+		if f.Name == name {
+			return f.Value
+		}
+	}
+	return nil
+}
+
+// GetString returns the string stored under name, or "" if missing or not a string
+func (c *Compound) GetString(name string) string {
+	s, _ := c.Get(name).(string)
+	return s
+}
+
+// Set replaces the value stored under name or appends a new field
+func (c *Compound) Set(name string, value any) {
+	for i, f := range c.Fields {
+		if f.Name == name {
+			c.Fields[i].Value = value
+			return
+		}
+	}
+	c.Fields = append(c.Fields, Field{Name: name, Value: value})
+}
+
+// Delete removes the field stored under name
+func (c *Compound) Delete(name string) {
+	for i, f := range c.Fields {
+		if f.Name == name {
+			c.Fields = append(c.Fields[:i], c.Fields[i+1:]...)
+			return
+		}
+	}
+}
+
+// Read decodes a named root compound. The input is read whole, so lengths
+// in a corrupt file can be checked against what is actually there.
+func Read(r io.Reader) (string, *Compound, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", nil, err
+	}
+	br := bytes.NewReader(data)
+	tagType, err := br.ReadByte()
+	if err != nil {
+		return "", nil, err
+	}
+	if tagType != TagCompound {
+		return "", nil, fmt.Errorf("root tag is type %d, expected compound", tagType)
+	}
+
+	d := decoder{r: br}
+	name := d.readString()
+	root := d.readCompound(0)
+	if d.err != nil {
+		return "", nil, d.err
+	}
+	return name, root, nil
+}
+
+// Write encodes root as a named root compound
+func Write(w io.Writer, name string, root *Compound) error {
+	bw := bufio.NewWriter(w)
+	e := encoder{w: bw}
+	e.writeByte(TagCompound)
+	e.writeString(name)
+	e.writeCompound(root)
+	if e.err != nil {
+		return e.err
+	}
+	return bw.Flush()
+}
+
+// Nesting this deep only happens in malformed or hostile files
+const maxDepth = 512
+
+type decoder struct {
+	r   *bytes.Reader
+	err error
+}
+
+func (d *decoder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+func (d *decoder) read(v any) {
+	if d.err != nil {
+		return
+	}
+	if err := binary.Read(d.r, binary.BigEndian, v); err != nil {
+		d.fail(err)
+	}
+}
+
+// readLength reads an array or list length. Each element takes at least
+// elemSize bytes, so a length the rest of the input can't hold is corrupt.
+func (d *decoder) readLength(elemSize int) int {
+	var n int32
+	d.read(&n)
+	if d.err != nil {
+		return 0
+	}
+	if n < 0 {
+		d.fail(fmt.Errorf("negative length %d", n))
+		return 0
+	}
+	if int64(n)*int64(elemSize) > int64(d.r.Len()) {
+		d.fail(fmt.Errorf("length %d exceeds the remaining %d bytes", n, d.r.Len()))
+		return 0
+	}
+	return int(n)
+}
+
+func (d *decoder) readString() string {
+	var n uint16
+	d.read(&n)
+	if d.err != nil {
+		return ""
+	}
+	if int(n) > d.r.Len() {
+		d.fail(fmt.Errorf("string length %d exceeds the remaining %d bytes", n, d.r.Len()))
+		return ""
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(d.r, buf); err != nil {
+		d.fail(err)
+		return ""
+	}
+	return string(buf)
+}
+
+func (d *decoder) readCompound(depth int) *Compound {
+	c := &Compound{}
+	for d.err == nil {
+		var tagType byte
+		d.read(&tagType)
+		if d.err != nil || tagType == TagEnd {
+			break
+		}
+		name := d.readString()
+		c.Fields = append(c.Fields, Field{Name: name, Value: d.readPayload(tagType, depth+1)})
+	}
+	return c
+}
+
+func (d *decoder) readPayload(tagType byte, depth int) any {
+	if depth > maxDepth {
+		d.fail(fmt.Errorf("nbt nesting too deep"))
+		return nil
+	}
+
+	switch tagType {
+	case TagByte:
+		var v int8
+		d.read(&v)
+		return v
+	case TagShort:
+		var v int16
+		d.read(&v)
+		return v
+	case TagInt:
+		var v int32
+		d.read(&v)
+		return v
+	case TagLong:
+		var v int64
+		d.read(&v)
+		return v
+	case TagFloat:
+		var v uint32
+		d.read(&v)
+		return math.Float32frombits(v)
+	case TagDouble:
+		var v uint64
+		d.read(&v)
+		return math.Float64frombits(v)
+	case TagByteArray:
+		n := d.readLength(1)
+		if d.err != nil {
+			return nil
+		}
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(d.r, buf); err != nil {
+			d.fail(err)
+		}
+		return buf
+	case TagString:
+		return d.readString()
+	case TagList:
+		var elemType byte
+		d.read(&elemType)
+		n := d.readLength(1) // Even an empty compound takes a byte
+		if elemType == TagEnd && n > 0 {
+			d.fail(fmt.Errorf("list of %d end tags", n))
+		}
+		list := &List{Type: elemType}
+		for i := 0; i < n && d.err == nil; i++ {
+			list.Items = append(list.Items, d.readPayload(elemType, depth+1))
+		}
+		return list
+	case TagCompound:
+		return d.readCompound(depth)
+	case TagIntArray:
+		n := d.readLength(4)
+		vals := make([]int32, 0, n)
+		for i := 0; i < n && d.err == nil; i++ {
+			var v int32
+			d.read(&v)
+			vals = append(vals, v)
+		}
+		return vals
+	case TagLongArray:
+		n := d.readLength(8)
+		vals := make([]int64, 0, n)
+		for i := 0; i < n && d.err == nil; i++ {
+			var v int64
+			d.read(&v)
+			vals = append(vals, v)
+		}
+		return vals
+	default:
+		d.fail(fmt.Errorf("unknown tag type %d", tagType))
+		return nil
+	}
+}
+
+type encoder struct {
+	w   *bufio.Writer
+	err error
+}
+
+func (e *encoder) write(v any) {
+	if e.err != nil {
+		return
+	}
+	e.err = binary.Write(e.w, binary.BigEndian, v)
+}
+
+func (e *encoder) writeByte(b byte) {
+	e.write(b)
+}
+
+func (e *encoder) writeString(s string) {
+	if len(s) > math.MaxUint16 {
+		e.err = fmt.Errorf("string too long for nbt: %d bytes", len(s))
+		return
+	}
+	e.write(uint16(len(s)))
+	if e.err == nil {
+		_, e.err = e.w.WriteString(s)
+	}
+}
+
+func (e *encoder) writeCompound(c *Compound) {
+	for _, f := range c.Fields {
+		tagType, err := typeOf(f.Value)
+		if err != nil {
+			e.err = fmt.Errorf("field %q: %w", f.Name, err)
+			return
+		}
+		e.writeByte(tagType)
+		e.writeString(f.Name)
+		e.writePayload(f.Value)
+	}
+	e.writeByte(TagEnd)
+}
+
+func (e *encoder) writePayload(v any) {
+	switch v := v.(type) {
+	case int8, int16, int32, int64:
+		e.write(v)
+	case float32:
+		e.write(math.Float32bits(v))
+	case float64:
+		e.write(math.Float64bits(v))
+	case []byte:
+		e.write(int32(len(v)))
+		e.write(v)
+	case string:
+		e.writeString(v)
+	case *List:
+		e.writeByte(v.Type)
+		e.write(int32(len(v.Items)))
+		for _, item := range v.Items {
+			if t, err := typeOf(item); err != nil || t != v.Type {
+				e.err = fmt.Errorf("list item %T does not match list type %d", item, v.Type)
+				return
+			}
+			e.writePayload(item)
+		}
+	case *Compound:
+		e.writeCompound(v)
+	case []int32:
+		e.write(int32(len(v)))
+		e.write(v)
+	case []int64:
+		e.write(int32(len(v)))
+		e.write(v)
+	}
+}
+
+func typeOf(v any) (byte, error) {
+	switch v.(type) {
+	case int8:
+		return TagByte, nil
+	case int16:
+		return TagShort, nil
+	case int32:
+		return TagInt, nil
+	case int64:
+		return TagLong, nil
+	case float32:
+		return TagFloat, nil
+	case float64:
+		return TagDouble, nil
+	case []byte:
+		return TagByteArray, nil
+	case string:
+		return TagString, nil
+	case *List:
+		return TagList, nil
+	case *Compound:
+		return TagCompound, nil
+	case []int32:
+		return TagIntArray, nil
+	case []int64:
+		return TagLongArray, nil
+	default:
+		return 0, fmt.Errorf("unsupported nbt value type %T", v)
+	}
+}
@@ -0,0 +1,95 @@
+package nbt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadWrite_RoundTrip(t *testing.T) {
+	server := &Compound{}
+	server.Set("name", "Pack Server")
+	server.Set("ip", "play.example.com:25565")
+	server.Set("acceptTextures", int8(1))
+
+	root := &Compound{Fields: []Field{
+		{Name: "servers", Value: &List{Type: TagCompound, Items: []any{server}}},
+		{Name: "short", Value: int16(-2)},
+		{Name: "int", Value: int32(1 << 20)},
+		{Name: "long", Value: int64(-1 << 40)},
+		{Name: "float", Value: float32(1.5)},
+		{Name: "double", Value: 2.25},
+		{Name: "bytes", Value: []byte{1, 2, 3}},
+		{Name: "ints", Value: []int32{4, 5}},
+		{Name: "longs", Value: []int64{6}},
+		{Name: "empty", Value: &List{Type: TagEnd}},
+	}}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, "", root); err != nil {
+		t.Fatal(err)
+	}
+	name, got, err := Read(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "" || !reflect.DeepEqual(got, root) {
+		t.Errorf("round trip changed the tree:\n%+v\n%+v", root, got)
+	}
+
+	// Writing what was read gives the same bytes
+	var again bytes.Buffer
+	if err := Write(&again, name, got); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(again.Bytes(), buf.Bytes()) {
+		t.Error("expected a byte for byte round trip")
+	}
+
+	servers := got.Get("servers").(*List)
+	if ip := servers.Items[0].(*Compound).GetString("ip"); ip != "play.example.com:25565" {
+		t.Errorf("unexpected server ip %q", ip)
+	}
+}
+
+func TestRead_Corrupt(t *testing.T) {
+	// A root compound holding one array whose length claims a huge size
+	header := func(tagType byte) *bytes.Buffer {
+		b := new(bytes.Buffer)
+		b.Write([]byte{TagCompound, 0, 0, tagType, 0, 1, 'a'})
+		return b
+	}
+	cases := map[string][]byte{}
+	for name, tagType := range map[string]byte{"bytes": TagByteArray, "ints": TagIntArray, "longs": TagLongArray} {
+		b := header(tagType)
+		binary.Write(b, binary.BigEndian, int32(1<<30))
+		cases[name] = b.Bytes()
+	}
+	list := header(TagList)
+	list.WriteByte(TagInt)
+	binary.Write(list, binary.BigEndian, int32(1<<30))
+	cases["list"] = list.Bytes()
+
+	endList := header(TagList)
+	endList.WriteByte(TagEnd)
+	binary.Write(endList, binary.BigEndian, int32(3))
+	cases["end list"] = endList.Bytes()
+
+	str := header(TagString)
+	binary.Write(str, binary.BigEndian, uint16(60000))
+	cases["string"] = str.Bytes()
+
+	cases["truncated"] = []byte{TagCompound, 0, 0, TagInt, 0, 1, 'a', 0}
+
+	for name, data := range cases {
+		if _, _, err := Read(bytes.NewReader(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+
+	if _, _, err := Read(strings.NewReader("\x08")); err == nil || !strings.Contains(err.Error(), "expected compound") {
+		t.Errorf("expected a non-compound root to fail, got %v", err)
+	}
+}
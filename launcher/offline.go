@@ -0,0 +1,32 @@
+package launcher
+
+import (
+	"crypto/md5"
+	"fmt"
+	"regexp"
+)
+
+var validUsername = regexp.MustCompile(`^[A-Za-z0-9_]{3,16}$`)
+
+// ValidateUsername checks a player name against Minecraft's rules
+func ValidateUsername(name string) error {
+	switch {
+	case len(name) < 3:
+		return fmt.Errorf("username %q is too short (3-16 characters)", name)
+	case len(name) > 16:
+		return fmt.Errorf("username %q is too long (3-16 characters)", name)
+	case !validUsername.MatchString(name):
+		return fmt.Errorf("username %q may only contain letters, digits and _", name)
+	}
+	return nil
+}
+
+// OfflineUUID returns the UUID offline-mode servers give a player: a
+// version 3 UUID of "OfflinePlayer:<name>", like Java's
+// UUID.nameUUIDFromBytes
+func OfflineUUID(name string) string {
+	sum := md5.Sum([]byte("OfflinePlayer:" + name))
+	sum[6] = sum[6]&0x0f | 0x30 // Version 3
+	sum[8] = sum[8]&0x3f | 0x80 // IETF variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
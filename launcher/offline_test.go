@@ -0,0 +1,26 @@
+package launcher
+
+import "testing"
+
+func TestOfflineUUID(t *testing.T) {
+	// Matches UUID.nameUUIDFromBytes("OfflinePlayer:Notch")
+	if got := OfflineUUID("Notch"); got != "b50ad385-829d-3141-a216-7e7d7539ba7f" {
+		t.Errorf("unexpected UUID %s", got)
+	}
+	if OfflineUUID("notch") == OfflineUUID("Notch") {
+		t.Error("expected UUIDs to be case sensitive")
+	}
+}
+
+func TestValidateUsername(t *testing.T) {
+	for _, name := range []string{"Steve", "abc", "Player_123456789"} {
+		if err := ValidateUsername(name); err != nil {
+			t.Errorf("expected %q to be valid: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "ab", "Player_1234567890", "bad name", "Ünïcode", "a-b-c"} {
+		if err := ValidateUsername(name); err == nil {
+			t.Errorf("expected %q to be rejected", name)
+		}
+	}
+}
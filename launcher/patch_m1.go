@@ -33,3 +33,12 @@ func PatchNatives(nativesDir string) error {
 
 	return nil
 }
+
+// patchedNative reports whether PatchNatives replaces the extracted native
+// called name on this platform
+func patchedNative(name string) bool {
+	if runtime.GOOS != "darwin" || runtime.GOARCH != "arm64" {
+		return false
+	}
+	return name == "liblwjgl.dylib" || name == "libopenal.dylib"
+}
@@ -0,0 +1,408 @@
+package launcher
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+const (
+	DefaultServerPort = 25565
+
+	// Protocol version we announce in the handshake (1.8.x)
+	pingProtocolVersion = 47
+	// Protocol version we announce in the legacy 1.6 ping
+	legacyPingProtocolVersion = 74
+
+	maxStatusLength = 1 << 20
+)
+
+// ServerStatus is the result of a Server List Ping
+type ServerStatus struct {
+	MOTD          string   `json:"motd"`
+	VersionName   string   `json:"versionName"`
+	Protocol      int      `json:"protocol"`
+	OnlinePlayers int      `json:"onlinePlayers"`
+	MaxPlayers    int      `json:"maxPlayers"`
+	PlayerSample  []string `json:"playerSample"`
+	LatencyMs     int64    `json:"latencyMs"`
+	Legacy        bool     `json:"legacy"` // Answered only the pre-1.7 ping
+}
+
+// errBadStatus is a 1.7+ status exchange that got a bad or empty answer,
+// which is what servers older than 1.7 give
+var errBadStatus = errors.New("bad status response")
+
+// PingServer queries a server's status. The 1.7+ handshake is tried first,
+// then the legacy 1.6 ping if the server didn't understand it.
+func PingServer(address string, timeout time.Duration) (*ServerStatus, error) {
+	host, port, err := ResolveServerAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	// Proxies pick the backend by the address the player typed, not the
+	// SRV target
+	virtualHost, _, _ := SplitServerAddress(address)
+
+	status, err := pingModern(host, port, virtualHost, timeout)
+	if err == nil {
+		return status, nil
+	}
+	if !errors.Is(err, errBadStatus) {
+		return nil, fmt.Errorf("server did not answer ping: %w", err)
+	}
+
+	legacyStatus, legacyErr := pingLegacy(host, port, virtualHost, timeout)
+	if legacyErr == nil {
+		return legacyStatus, nil
+	}
+	return nil, fmt.Errorf("server did not answer ping: %w", err)
+}
+
+// lookupSRV is swapped out in tests
+var lookupSRV = net.LookupSRV
+
+// ResolveServerAddress turns a server address into the host and port to
+// connect to. Like the vanilla client, addresses without an explicit port
+// are looked up as a _minecraft._tcp SRV record first.
+func ResolveServerAddress(address string) (string, int, error) {
+	host, port, err := SplitServerAddress(address)
+	if err != nil {
+		return "", 0, err
+	}
+	// An explicit port or a literal IP skips the lookup
+	if _, _, err := net.SplitHostPort(strings.TrimSpace(address)); err == nil || net.ParseIP(host) != nil {
+		return host, port, nil
+	}
+
+	_, records, err := lookupSRV("minecraft", "tcp", host)
+	if err != nil || len(records) == 0 {
+		return host, port, nil
+	}
+	return strings.TrimSuffix(records[0].Target, "."), int(records[0].Port), nil
+}
+
+// SplitServerAddress splits "host[:port]" and applies the default port
+func SplitServerAddress(address string) (string, int, error) {
+	address = strings.TrimSpace(address)
+	if address == "" {
+		return "", 0, fmt.Errorf("empty server address")
+	}
+
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		// No port given
+		return strings.Trim(address, "[]"), DefaultServerPort, nil
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 1 || port > 65535 {
+		return "", 0, fmt.Errorf("invalid port %q", portStr)
+	}
+	return host, port, nil
+}
+
+func dialServer(host string, port int, timeout time.Duration) (net.Conn, error) {
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(host, strconv.Itoa(port)), timeout)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	return conn, nil
+}
+
+// --- 1.7+ status protocol ---
+
+type statusResponse struct {
+	Version struct {
+		Name     string `json:"name"`
+		Protocol int    `json:"protocol"`
+	} `json:"version"`
+	Players struct {
+		Max    int `json:"max"`
+		Online int `json:"online"`
+		Sample []struct {
+			Name string `json:"name"`
+		} `json:"sample"`
+	} `json:"players"`
+	Description json.RawMessage `json:"description"`
+}
+
+// badStatus marks an answer as not understanding the status protocol.
+// Timeouts are kept as they are: the server is too slow, not too old.
+func badStatus(err error) error {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return err
+	}
+	return fmt.Errorf("%w: %v", errBadStatus, err)
+}
+
+func pingModern(host string, port int, virtualHost string, timeout time.Duration) (*ServerStatus, error) {
+	conn, err := dialServer(host, port, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+
+	// Handshake (next state 1 = status) followed by a status request
+	var handshake bytes.Buffer
+	writeVarInt(&handshake, 0x00)
+	writeVarInt(&handshake, pingProtocolVersion)
+	writeMCString(&handshake, virtualHost)
+	binary.Write(&handshake, binary.BigEndian, uint16(port))
+	writeVarInt(&handshake, 1)
+
+	if err := writePacket(conn, handshake.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := writePacket(conn, []byte{0x00}); err != nil {
+		return nil, err
+	}
+
+	packetID, payload, err := readPacket(r)
+	if err != nil {
+		return nil, badStatus(err)
+	}
+	if packetID != 0x00 {
+		return nil, badStatus(fmt.Errorf("unexpected status packet id %d", packetID))
+	}
+	jsonStr, err := readMCString(bytes.NewReader(payload))
+	if err != nil {
+		return nil, badStatus(err)
+	}
+
+	var resp statusResponse
+	if err := json.Unmarshal([]byte(jsonStr), &resp); err != nil {
+		return nil, badStatus(fmt.Errorf("invalid status json: %w", err))
+	}
+
+	status := &ServerStatus{
+		MOTD:          chatToText(resp.Description),
+		VersionName:   resp.Version.Name,
+		Protocol:      resp.Version.Protocol,
+		OnlinePlayers: resp.Players.Online,
+		MaxPlayers:    resp.Players.Max,
+	}
+	for _, p := range resp.Players.Sample {
+		status.PlayerSample = append(status.PlayerSample, p.Name)
+	}
+
+	// Ping/pong for latency
+	var ping bytes.Buffer
+	writeVarInt(&ping, 0x01)
+	token := time.Now().UnixNano()
+	binary.Write(&ping, binary.BigEndian, token)
+
+	start := time.Now()
+	if err := writePacket(conn, ping.Bytes()); err != nil {
+		return nil, err
+	}
+	packetID, payload, err = readPacket(r)
+	if err != nil {
+		return nil, err
+	}
+	if packetID != 0x01 || len(payload) != 8 || int64(binary.BigEndian.Uint64(payload)) != token {
+		return nil, fmt.Errorf("invalid pong")
+	}
+	status.LatencyMs = time.Since(start).Milliseconds()
+
+	return status, nil
+}
+
+// chatToText flattens a chat component (or plain string) into text
+func chatToText(raw json.RawMessage) string {
+	if len(raw) == 0 {
+		return ""
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+
+	var component struct {
+		Text  string            `json:"text"`
+		Extra []json.RawMessage `json:"extra"`
+	}
+	if err := json.Unmarshal(raw, &component); err != nil {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(component.Text)
+	for _, extra := range component.Extra {
+		sb.WriteString(chatToText(extra))
+	}
+	return sb.String()
+}
+
+func writePacket(w io.Writer, data []byte) error {
+	var buf bytes.Buffer
+	writeVarInt(&buf, int32(len(data)))
+	buf.Write(data)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+func readPacket(r *bufio.Reader) (int32, []byte, error) {
+	length, err := readVarInt(r)
+	if err != nil {
+		return 0, nil, err
+	}
+	if length <= 0 || length > maxStatusLength {
+		return 0, nil, fmt.Errorf("invalid packet length %d", length)
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, err
+	}
+
+	br := bytes.NewReader(data)
+	packetID, err := readVarInt(br)
+	if err != nil {
+		return 0, nil, err
+	}
+	payload, _ := io.ReadAll(br)
+	return packetID, payload, nil
+}
+
+func writeVarInt(buf *bytes.Buffer, value int32) {
+	v := uint32(value)
+	for {
+		if v&^0x7F == 0 {
+			buf.WriteByte(byte(v))
+			return
+		}
+		buf.WriteByte(byte(v&0x7F | 0x80))
+		v >>= 7
+	}
+}
+
+func readVarInt(r io.ByteReader) (int32, error) {
+	var result uint32
+	for i := 0; i < 5; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		result |= uint32(b&0x7F) << (7 * i)
+		if b&0x80 == 0 {
+			return int32(result), nil
+		}
+	}
+	return 0, errors.New("varint too long")
+}
+
+func writeMCString(buf *bytes.Buffer, s string) {
+	writeVarInt(buf, int32(len(s)))
+	buf.WriteString(s)
+}
+
+func readMCString(r *bytes.Reader) (string, error) {
+	length, err := readVarInt(r)
+	if err != nil {
+		return "", err
+	}
+	if length < 0 || int(length) > r.Len() {
+		return "", fmt.Errorf("invalid string length %d", length)
+	}
+	data := make([]byte, length)
+	io.ReadFull(r, data)
+	return string(data), nil
+}
+
+// --- Legacy (1.6) ping ---
+
+func pingLegacy(host string, port int, virtualHost string, timeout time.Duration) (*ServerStatus, error) {
+	conn, err := dialServer(host, port, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	// 0xFE 0x01, then a MC|PingHost plugin message
+	hostUTF16 := utf16.Encode([]rune(virtualHost))
+	var req bytes.Buffer
+	req.Write([]byte{0xFE, 0x01, 0xFA})
+	writeUTF16String(&req, "MC|PingHost")
+	binary.Write(&req, binary.BigEndian, uint16(7+2*len(hostUTF16)))
+	req.WriteByte(legacyPingProtocolVersion)
+	writeUTF16String(&req, virtualHost)
+	binary.Write(&req, binary.BigEndian, int32(port))
+
+	start := time.Now()
+	if _, err := conn.Write(req.Bytes()); err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(conn)
+	kick, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if kick != 0xFF {
+		return nil, fmt.Errorf("unexpected legacy response 0x%02x", kick)
+	}
+	var length uint16
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return nil, err
+	}
+	units := make([]uint16, length)
+	if err := binary.Read(r, binary.BigEndian, units); err != nil {
+		return nil, err
+	}
+	latency := time.Since(start)
+
+	status, err := parseLegacyResponse(string(utf16.Decode(units)))
+	if err != nil {
+		return nil, err
+	}
+	status.LatencyMs = latency.Milliseconds()
+	return status, nil
+}
+
+// parseLegacyResponse handles both the 1.4-1.6 "§1" format and the older
+// "motd§online§max" format
+func parseLegacyResponse(s string) (*ServerStatus, error) {
+	status := &ServerStatus{Legacy: true}
+
+	if strings.HasPrefix(s, "§1\x00") {
+		parts := strings.Split(s, "\x00")
+		if len(parts) != 6 {
+			return nil, fmt.Errorf("malformed legacy response")
+		}
+		status.Protocol, _ = strconv.Atoi(parts[1])
+		status.VersionName = parts[2]
+		status.MOTD = parts[3]
+		status.OnlinePlayers, _ = strconv.Atoi(parts[4])
+		status.MaxPlayers, _ = strconv.Atoi(parts[5])
+		return status, nil
+	}
+
+	parts := strings.Split(s, "§")
+	if len(parts) < 3 {
+		return nil, fmt.Errorf("malformed legacy response")
+	}
+	status.MOTD = strings.Join(parts[:len(parts)-2], "§")
+	status.OnlinePlayers, _ = strconv.Atoi(parts[len(parts)-2])
+	status.MaxPlayers, _ = strconv.Atoi(parts[len(parts)-1])
+	return status, nil
+}
+
+func writeUTF16String(buf *bytes.Buffer, s string) {
+	units := utf16.Encode([]rune(s))
+	binary.Write(buf, binary.BigEndian, uint16(len(units)))
+	binary.Write(buf, binary.BigEndian, units)
+}
@@ -0,0 +1,222 @@
+package launcher
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+// startFakeServer serves a single behaviour on a local port and returns its address
+func startFakeServer(t *testing.T, handle func(net.Conn)) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.SetDeadline(time.Now().Add(5 * time.Second))
+				handle(conn)
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestPingServer_Modern(t *testing.T) {
+	const statusJSON = `{"version":{"name":"1.8.9","protocol":47},` +
+		`"players":{"max":20,"online":2,"sample":[{"name":"Alex","id":"x"},{"name":"Steve","id":"y"}]},` +
+		`"description":{"text":"Welcome ","extra":[{"text":"home"}]}}`
+
+	addr := startFakeServer(t, func(conn net.Conn) {
+		r := bufio.NewReader(conn)
+
+		// Handshake
+		id, payload, err := readPacket(r)
+		if err != nil || id != 0x00 {
+			return
+		}
+		pr := bytes.NewReader(payload)
+		if proto, _ := readVarInt(pr); proto != pingProtocolVersion {
+			t.Errorf("unexpected protocol %d", proto)
+		}
+
+		// Status request
+		if id, _, err := readPacket(r); err != nil || id != 0x00 {
+			return
+		}
+		var resp bytes.Buffer
+		writeVarInt(&resp, 0x00)
+		writeMCString(&resp, statusJSON)
+		writePacket(conn, resp.Bytes())
+
+		// Ping, echoed back as pong
+		id, payload, err = readPacket(r)
+		if err != nil || id != 0x01 {
+			return
+		}
+		var pong bytes.Buffer
+		writeVarInt(&pong, 0x01)
+		pong.Write(payload)
+		writePacket(conn, pong.Bytes())
+	})
+
+	status, err := PingServer(addr, 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Legacy || status.MOTD != "Welcome home" || status.VersionName != "1.8.9" || status.Protocol != 47 {
+		t.Errorf("unexpected status %+v", status)
+	}
+	if status.OnlinePlayers != 2 || status.MaxPlayers != 20 || len(status.PlayerSample) != 2 || status.PlayerSample[1] != "Steve" {
+		t.Errorf("unexpected players %+v", status)
+	}
+}
+
+func TestPingServer_LegacyFallback(t *testing.T) {
+	addr := startFakeServer(t, func(conn net.Conn) {
+		r := bufio.NewReader(conn)
+		first, err := r.ReadByte()
+		if err != nil || first != 0xFE {
+			// Not a legacy ping: drop the connection like a 1.6 server would
+			return
+		}
+		// Drain the rest of the request
+		conn.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+		r.Discard(r.Buffered())
+
+		units := utf16.Encode([]rune("§1\x0078\x001.6.4\x00A Legacy Server\x005\x0010"))
+		var resp bytes.Buffer
+		resp.WriteByte(0xFF)
+		binary.Write(&resp, binary.BigEndian, uint16(len(units)))
+		binary.Write(&resp, binary.BigEndian, units)
+		conn.Write(resp.Bytes())
+	})
+
+	status, err := PingServer(addr, 2*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.Legacy || status.MOTD != "A Legacy Server" || status.VersionName != "1.6.4" || status.Protocol != 78 {
+		t.Errorf("unexpected status %+v", status)
+	}
+	if status.OnlinePlayers != 5 || status.MaxPlayers != 10 {
+		t.Errorf("unexpected players %+v", status)
+	}
+}
+
+func TestPingServer_VirtualHost(t *testing.T) {
+	hosts := make(chan string, 1)
+	addr := startFakeServer(t, func(conn net.Conn) {
+		r := bufio.NewReader(conn)
+		if first, err := r.Peek(1); err != nil || first[0] == 0xFE {
+			return // The legacy ping that follows
+		}
+		_, payload, err := readPacket(r)
+		if err != nil {
+			return
+		}
+		pr := bytes.NewReader(payload)
+		readVarInt(pr)
+		host, _ := readMCString(pr)
+		hosts <- host
+	})
+	_, portStr, _ := net.SplitHostPort(addr)
+	port, _ := strconv.Atoi(portStr)
+
+	lookupSRV = func(service, proto, name string) (string, []*net.SRV, error) {
+		return "", []*net.SRV{{Target: "127.0.0.1.", Port: uint16(port)}}, nil
+	}
+	defer func() { lookupSRV = net.LookupSRV }()
+
+	// The server hangs up, so the legacy ping follows, but it's the
+	// typed address that's announced
+	PingServer("play.example.org", 2*time.Second)
+	if host := <-hosts; host != "play.example.org" {
+		t.Errorf("expected the typed address in the handshake, got %q", host)
+	}
+}
+
+func TestPingServer_NoLegacyAfterTimeout(t *testing.T) {
+	var mu sync.Mutex
+	conns := 0
+	addr := startFakeServer(t, func(conn net.Conn) {
+		mu.Lock()
+		conns++
+		mu.Unlock()
+		// Read everything, answer nothing
+		io.Copy(io.Discard, conn)
+	})
+
+	if _, err := PingServer(addr, 200*time.Millisecond); err == nil {
+		t.Fatal("expected the ping to time out")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if conns != 1 {
+		t.Errorf("expected no legacy ping after a timeout, got %d connections", conns)
+	}
+}
+
+func TestSplitServerAddress(t *testing.T) {
+	tests := []struct {
+		in   string
+		host string
+		port int
+	}{
+		{"mc.example.org", "mc.example.org", DefaultServerPort},
+		{"mc.example.org:25570", "mc.example.org", 25570},
+		{"[::1]:25566", "::1", 25566},
+	}
+	for _, tt := range tests {
+		host, port, err := SplitServerAddress(tt.in)
+		if err != nil || host != tt.host || port != tt.port {
+			t.Errorf("SplitServerAddress(%q) = %q, %d, %v", tt.in, host, port, err)
+		}
+	}
+	if _, _, err := SplitServerAddress("host:99999"); err == nil {
+		t.Error("expected error for out of range port")
+	}
+}
+
+func TestResolveServerAddress_SRV(t *testing.T) {
+	lookupSRV = func(service, proto, name string) (string, []*net.SRV, error) {
+		if service != "minecraft" || proto != "tcp" || name != "mc.example.org" {
+			return "", nil, &net.DNSError{Err: "no such host", Name: name}
+		}
+		return "", []*net.SRV{{Target: "node1.example.org.", Port: 25570}}, nil
+	}
+	defer func() { lookupSRV = net.LookupSRV }()
+
+	tests := []struct {
+		in   string
+		host string
+		port int
+	}{
+		{"mc.example.org", "node1.example.org", 25570},
+		{"mc.example.org:25565", "mc.example.org", 25565}, // explicit port wins
+		{"other.example.org", "other.example.org", DefaultServerPort},
+		{"10.0.0.5", "10.0.0.5", DefaultServerPort},
+	}
+	for _, tt := range tests {
+		host, port, err := ResolveServerAddress(tt.in)
+		if err != nil || host != tt.host || port != tt.port {
+			t.Errorf("ResolveServerAddress(%q) = %q, %d, %v", tt.in, host, port, err)
+		}
+	}
+}
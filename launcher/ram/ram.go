@@ -0,0 +1,166 @@
+// Package ram recommends how much memory to give the game, from the
+// machine's total and free memory, the Java in use and the modpack's needs.
+package ram
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/pbnjay/memory"
+)
+
+// Warning codes
+const (
+	WarnJava32Bit        = "java-32bit"        // A 32-bit JVM can't use more than about 1 GiB
+	WarnLowTotalMemory   = "low-total-memory"  // The machine can't fit the pack's minimum
+	WarnLowFreeMemory    = "low-free-memory"   // Other programs use memory the game needs
+	WarnBelowRecommended = "below-recommended" // The default is less than the pack recommends
+)
+
+const (
+	// Java8Min is the least the game starts with on a 64-bit JVM
+	Java8Min = 1024
+	// DefaultRecommended is used when the pack doesn't say
+	DefaultRecommended = 2048
+
+	// 32-bit JVMs can't reserve a larger contiguous heap on most systems
+	max32Bit = 1024
+	min32Bit = 512
+
+	// The OS keeps at least this much, or a quarter of RAM on big machines
+	minHeadroom = 2048
+	// Beyond the heap the JVM needs memory for itself, natives and textures
+	jvmOverhead = 512
+)
+
+// Warning is a structured note about the recommendation
+type Warning struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Inputs describe the machine, the Java and the pack
+type Inputs struct {
+	TotalMB         uint64
+	FreeMB          uint64 // Available right now, including reclaimable cache
+	Java64Bit       bool
+	PackMinMB       int // From the manifest, 0 if not declared
+	PackRecommended int // From the manifest, 0 if not declared
+}
+
+// Recommendation is the allowed range and default heap size in MiB
+type Recommendation struct {
+	MinMB         int
+	MaxMB         int
+	DefaultMB     int
+	RecommendedMB int // What the pack asks for, clamped to the range
+	Warnings      []Warning
+}
+
+// Recommend works out the heap range and default for in
+func Recommend(in Inputs) Recommendation {
+	var r Recommendation
+	warn := func(code, format string, args ...any) {
+		r.Warnings = append(r.Warnings, Warning{Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// Never let the heap push the OS into swapping
+	total := int(in.TotalMB)
+	headroom := total / 4
+	if headroom < minHeadroom {
+		headroom = minHeadroom
+	}
+	r.MaxMB = total - headroom - jvmOverhead
+	r.MinMB = Java8Min
+	if !in.Java64Bit {
+		r.MinMB = min32Bit
+		if r.MaxMB > max32Bit {
+			r.MaxMB = max32Bit
+		}
+		warn(WarnJava32Bit, "Java is 32-bit, so the game can use at most %d MiB. Install 64-bit Java for more.", max32Bit)
+	}
+	if in.PackMinMB > r.MinMB {
+		r.MinMB = in.PackMinMB
+	}
+	if r.MaxMB < r.MinMB {
+		if in.PackMinMB > 0 && total > 0 {
+			warn(WarnLowTotalMemory, "This pack needs at least %d MiB for the game, but this computer only has %d MiB in total.", in.PackMinMB, total)
+		}
+		r.MaxMB = r.MinMB
+	}
+
+	r.RecommendedMB = in.PackRecommended
+	if r.RecommendedMB <= 0 {
+		r.RecommendedMB = DefaultRecommended
+	}
+	r.RecommendedMB = clamp(r.RecommendedMB, r.MinMB, r.MaxMB)
+
+	// Don't default to more than is free now, unless the game can't start with less
+	r.DefaultMB = r.RecommendedMB
+	if in.FreeMB > 0 {
+		fits := int(in.FreeMB) - jvmOverhead
+		if fits < r.RecommendedMB {
+			r.DefaultMB = clamp(fits, r.MinMB, r.MaxMB)
+			warn(WarnLowFreeMemory, "Only %d MiB is free right now. Close other programs to give the game the recommended %d MiB.", in.FreeMB, r.RecommendedMB)
+		}
+	}
+	if in.PackRecommended > 0 && r.DefaultMB < in.PackRecommended {
+		warn(WarnBelowRecommended, "The pack recommends %d MiB, the game will get %d MiB and may run slowly.", in.PackRecommended, r.DefaultMB)
+	}
+	return r
+}
+
+// Clamp fits a requested heap size into the recommendation's range
+func (r Recommendation) Clamp(mb int) int {
+	if mb <= 0 {
+		return r.DefaultMB
+	}
+	return clamp(mb, r.MinMB, r.MaxMB)
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
+
+// TotalMB returns the machine's physical memory in MiB
+func TotalMB() uint64 {
+	return memory.TotalMemory() / (1024 * 1024)
+}
+
+// FreeMB returns the memory available to new programs in MiB. On Linux
+// that includes page cache the kernel would give up, which plain free
+// memory leaves out.
+func FreeMB() uint64 {
+	if available, ok := memAvailable("/proc/meminfo"); ok {
+		return available
+	}
+	return memory.FreeMemory() / (1024 * 1024)
+}
+
+// memAvailable reads MemAvailable from a Linux meminfo file
+func memAvailable(path string) (uint64, bool) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "MemAvailable:" {
+			kb, err := strconv.ParseUint(fields[1], 10, 64)
+			return kb / 1024, err == nil
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,63 @@
+package ram
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func hasWarning(r Recommendation, code string) bool {
+	for _, w := range r.Warnings {
+		if w.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+func TestRecommend(t *testing.T) {
+	// 16 GiB, mostly free: the pack's recommendation fits
+	r := Recommend(Inputs{TotalMB: 16384, FreeMB: 12000, Java64Bit: true, PackMinMB: 3072, PackRecommended: 6144})
+	if r.MinMB != 3072 || r.MaxMB != 16384-4096-512 || r.DefaultMB != 6144 || len(r.Warnings) != 0 {
+		t.Errorf("unexpected recommendation %+v", r)
+	}
+
+	// Little free memory lowers the default but not the range
+	r = Recommend(Inputs{TotalMB: 16384, FreeMB: 3000, Java64Bit: true, PackRecommended: 6144})
+	if r.DefaultMB != 2488 || r.RecommendedMB != 6144 {
+		t.Errorf("expected the default to fit free memory, got %+v", r)
+	}
+	if !hasWarning(r, WarnLowFreeMemory) || !hasWarning(r, WarnBelowRecommended) {
+		t.Errorf("expected low free memory warnings, got %+v", r.Warnings)
+	}
+
+	// A 32-bit JVM is capped whatever the machine has
+	r = Recommend(Inputs{TotalMB: 16384, FreeMB: 12000, Java64Bit: false})
+	if r.MaxMB != 1024 || r.DefaultMB != 1024 || !hasWarning(r, WarnJava32Bit) {
+		t.Errorf("expected a 32-bit cap, got %+v", r)
+	}
+
+	// A machine too small for the pack still gets its minimum, with a warning
+	r = Recommend(Inputs{TotalMB: 4096, FreeMB: 3000, Java64Bit: true, PackMinMB: 4096})
+	if r.MinMB != 4096 || r.MaxMB != 4096 || !hasWarning(r, WarnLowTotalMemory) {
+		t.Errorf("expected the pack minimum with a warning, got %+v", r)
+	}
+
+	if got := r.Clamp(0); got != r.DefaultMB {
+		t.Errorf("expected 0 to mean the default, got %d", got)
+	}
+	if got := r.Clamp(99999); got != r.MaxMB {
+		t.Errorf("expected clamping to the maximum, got %d", got)
+	}
+}
+
+func TestMemAvailable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "meminfo")
+	os.WriteFile(path, []byte("MemTotal:       16315412 kB\nMemFree:          512000 kB\nMemAvailable:    8192000 kB\n"), 0644)
+	if mb, ok := memAvailable(path); !ok || mb != 8000 {
+		t.Errorf("expected 8000 MiB available, got %d, %v", mb, ok)
+	}
+	if _, ok := memAvailable(filepath.Join(t.TempDir(), "missing")); ok {
+		t.Error("expected a missing file to fall back")
+	}
+}
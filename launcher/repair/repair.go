@@ -0,0 +1,394 @@
+// Package repair deep-verifies an instance and the shared files it uses.
+// Anything broken is moved into a timestamped quarantine folder instead of
+// being deleted, then downloaded again.
+package repair
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"craft-launcher/launcher"
+	"craft-launcher/launcher/cache"
+	"craft-launcher/launcher/integrity"
+)
+
+const (
+	// QuarantineDir holds one folder per repair in the game dir
+	QuarantineDir = "quarantine"
+	// ReportFile is written into the repair's quarantine folder
+	ReportFile = "report.json"
+)
+
+// Kinds of files a repair checks
+const (
+	KindPack    = "pack"
+	KindAsset   = launcher.FileAsset
+	KindLibrary = launcher.FileLibrary
+	KindClient  = launcher.FileClient
+	KindNative  = "native"
+	KindJava    = "java"
+)
+
+// ProblemBroken means a file or the JRE is present but doesn't work
+const ProblemBroken = "broken"
+
+// Item is a file that failed verification and what was done about it
+type Item struct {
+	Kind        string `json:"kind"`
+	Path        string `json:"path"`    // Relative to the game dir (pack, native) or shared dir
+	Problem     string `json:"problem"` // missing, modified, unreadable or broken
+	Expected    string `json:"expected,omitempty"`
+	Actual      string `json:"actual,omitempty"`
+	Quarantined string `json:"quarantined,omitempty"` // Where the bad copy was moved
+	Repaired    bool   `json:"repaired"`
+	Error       string `json:"error,omitempty"`
+}
+
+// Report describes a repair
+type Report struct {
+	StartedAt     time.Time      `json:"startedAt"`
+	FinishedAt    time.Time      `json:"finishedAt"`
+	QuarantineDir string         `json:"quarantineDir"` // Also holds the report
+	Checked       map[string]int `json:"checked"`       // Files checked per kind
+	Items         []Item         `json:"items"`
+}
+
+// Failed returns the items that are still broken
+func (r *Report) Failed() []Item {
+	var failed []Item
+	for _, item := range r.Items {
+		if !item.Repaired {
+			failed = append(failed, item)
+		}
+	}
+	return failed
+}
+
+// Options select what to repair
+type Options struct {
+	GameDir          string
+	SharedDir        string // Libraries, assets, versions and JREs; defaults to GameDir
+	VersionID        string
+	UseFabric        bool   // Also checks the Fabric loader and its libraries
+	ServerURL        string // Modpack server, empty for none
+	JavaPath         string // Custom java is checked but can't be repaired; empty uses the bundled JRE
+	IncludeUserFiles bool   // Also checksum pack files the player may edit
+	Cache            *cache.Store
+	StatusCallback   func(string)
+}
+
+// Swapped out in tests
+var (
+	getVersion    = launcher.GetVersion
+	getFabricMeta = launcher.GetFabricMeta
+	mavenSHA1     = launcher.MavenSHA1
+	javaVersion   = launcher.JavaVersion
+	ensureJava    = launcher.EnsureJava
+)
+
+type repairer struct {
+	opts      Options
+	sharedDir string
+	report    *Report
+}
+
+// Run repairs an instance and writes the report into its quarantine
+// folder. Files it couldn't check or fix are in Report.Failed; an error
+// means the report couldn't be written.
+func Run(opts Options) (*Report, error) {
+	if opts.StatusCallback == nil {
+		opts.StatusCallback = func(string) {}
+	}
+	r := &repairer{
+		opts:      opts,
+		sharedDir: opts.SharedDir,
+		report: &Report{
+			StartedAt: time.Now(),
+			Checked:   map[string]int{},
+		},
+	}
+	if r.sharedDir == "" {
+		r.sharedDir = opts.GameDir
+	}
+	r.report.QuarantineDir = filepath.Join(opts.GameDir, QuarantineDir, r.report.StartedAt.Format("2006-01-02_15-04-05"))
+
+	r.checkJava()
+
+	// The JRE may already be replaced, so the report is written regardless
+	opts.StatusCallback("Fetching Package Info...")
+	pkg, err := getVersion(opts.VersionID)
+	if err != nil {
+		r.report.Items = append(r.report.Items, Item{
+			Kind:    KindClient,
+			Path:    path.Join("versions", opts.VersionID),
+			Problem: ProblemBroken,
+			Error:   fmt.Sprintf("can't check the game files: %v", err),
+		})
+		return r.finish()
+	}
+
+	// The asset index comes first, it lists the assets
+	opts.StatusCallback("Verifying client, libraries and asset index...")
+	for _, f := range launcher.VersionFiles(pkg) {
+		r.checkFile(f)
+	}
+	if opts.UseFabric {
+		opts.StatusCallback("Verifying Fabric libraries...")
+		r.checkFabric()
+	}
+	opts.StatusCallback("Verifying assets...")
+	assets, err := launcher.AssetFiles(pkg, r.sharedDir)
+	if err != nil {
+		r.report.Items = append(r.report.Items, Item{Kind: KindAsset, Path: "assets/indexes", Problem: ProblemBroken, Error: err.Error()})
+	}
+	for _, f := range assets {
+		r.checkFile(f)
+	}
+
+	opts.StatusCallback("Verifying natives...")
+	r.checkNatives(pkg)
+
+	if opts.ServerURL != "" {
+		r.checkPack()
+	}
+	return r.finish()
+}
+
+// finish writes the report and returns it
+func (r *repairer) finish() (*Report, error) {
+	if r.opts.Cache != nil {
+		if err := r.opts.Cache.Flush(); err != nil {
+			fmt.Printf("Warning: Failed to save cache references: %v\n", err)
+		}
+	}
+	r.report.FinishedAt = time.Now()
+	if err := r.writeReport(); err != nil {
+		return r.report, fmt.Errorf("failed to write repair report: %w", err)
+	}
+	r.opts.StatusCallback(fmt.Sprintf("Repair finished: %d problems, %d left.", len(r.report.Items), len(r.report.Failed())))
+	return r.report, nil
+}
+
+// quarantine moves a file or dir under base into the quarantine folder,
+// keeping its path, and returns where it went
+func (r *repairer) quarantine(kind, base, path string) (string, error) {
+	rel, err := filepath.Rel(base, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		rel = filepath.Base(path)
+	}
+	dest := filepath.Join(r.report.QuarantineDir, kind, rel)
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return "", err
+	}
+	if err := os.Rename(path, dest); err != nil {
+		return "", err
+	}
+	return dest, nil
+}
+
+// checkFile verifies a file in the shared dir against its SHA-1
+func (r *repairer) checkFile(f launcher.GameFile) {
+	r.report.Checked[f.Kind]++
+	path := filepath.Join(r.sharedDir, filepath.FromSlash(f.Path))
+
+	item := Item{Kind: f.Kind, Path: f.Path, Expected: f.SHA1}
+	sum, err := launcher.FileSHA1(path)
+	switch {
+	case os.IsNotExist(err):
+		item.Problem = integrity.ProblemMissing
+	case err != nil:
+		item.Problem = integrity.ProblemUnreadable
+		item.Error = err.Error()
+	case f.SHA1 != "" && !strings.EqualFold(sum, f.SHA1):
+		item.Problem = integrity.ProblemModified
+		item.Actual = sum
+	default:
+		return
+	}
+
+	if item.Problem != integrity.ProblemMissing {
+		dest, err := r.quarantine(f.Kind, r.sharedDir, path)
+		if err != nil {
+			item.Error = fmt.Sprintf("quarantine failed: %v", err)
+			r.report.Items = append(r.report.Items, item)
+			return
+		}
+		item.Quarantined = dest
+	}
+
+	r.opts.StatusCallback(fmt.Sprintf("Downloading: %s", f.Path))
+	if err := launcher.RestoreFile(f, r.sharedDir, r.opts.Cache); err != nil {
+		item.Error = err.Error()
+	} else {
+		item.Repaired = true
+		item.Error = ""
+	}
+	r.report.Items = append(r.report.Items, item)
+}
+
+// checkFabric verifies the jars a Fabric launch adds to the classpath
+func (r *repairer) checkFabric() {
+	meta, err := getFabricMeta(r.opts.VersionID)
+	var files []launcher.GameFile
+	if err == nil {
+		files, err = launcher.FabricFiles(meta)
+	}
+	if err != nil {
+		r.report.Items = append(r.report.Items, Item{
+			Kind:    KindLibrary,
+			Path:    "libraries",
+			Problem: ProblemBroken,
+			Error:   fmt.Sprintf("can't check the Fabric libraries: %v", err),
+		})
+		return
+	}
+	for _, f := range files {
+		// The loader and intermediary are only hashed by their repository;
+		// without it they are checked for being present
+		if f.SHA1 == "" {
+			if sum, err := mavenSHA1(f.URL); err == nil {
+				f.SHA1 = sum
+			}
+		}
+		r.checkFile(f)
+	}
+}
+
+// checkNatives compares extracted natives with their (already repaired)
+// jars and extracts them again if any differ
+func (r *repairer) checkNatives(pkg *launcher.Package) {
+	nativesDir := filepath.Join(r.opts.GameDir, "natives")
+	bad, checked, err := launcher.CheckNatives(pkg, r.sharedDir, nativesDir)
+	r.report.Checked[KindNative] += checked
+	if err != nil {
+		r.report.Items = append(r.report.Items, Item{Kind: KindNative, Path: "natives", Problem: ProblemBroken, Error: err.Error()})
+		return
+	}
+	if len(bad) == 0 {
+		return
+	}
+
+	items := make([]Item, len(bad))
+	for i, name := range bad {
+		path := filepath.Join(nativesDir, name)
+		items[i] = Item{Kind: KindNative, Path: filepath.ToSlash(filepath.Join("natives", name)), Problem: integrity.ProblemModified}
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			items[i].Problem = integrity.ProblemMissing
+		} else if dest, err := r.quarantine(KindNative, r.opts.GameDir, path); err != nil {
+			items[i].Error = fmt.Sprintf("quarantine failed: %v", err)
+		} else {
+			items[i].Quarantined = dest
+		}
+	}
+
+	r.opts.StatusCallback("Extracting natives...")
+	extractErr := launcher.ExtractNatives(pkg, r.sharedDir, nativesDir)
+	still, _, _ := launcher.CheckNatives(pkg, r.sharedDir, nativesDir)
+	broken := map[string]bool{}
+	for _, name := range still {
+		broken[name] = true
+	}
+	for i, name := range bad {
+		if items[i].Error == "" && !broken[name] {
+			items[i].Repaired = true
+		} else if items[i].Error == "" && extractErr != nil {
+			items[i].Error = extractErr.Error()
+		} else if items[i].Error == "" {
+			items[i].Error = "still differs after extracting"
+		}
+	}
+	r.report.Items = append(r.report.Items, items...)
+}
+
+// checkJava makes sure the game's Java starts, reinstalling the bundled JRE
+// if it doesn't
+func (r *repairer) checkJava() {
+	r.opts.StatusCallback("Checking Java...")
+	r.report.Checked[KindJava]++
+
+	if r.opts.JavaPath != "" {
+		if _, err := javaVersion(r.opts.JavaPath); err != nil {
+			r.report.Items = append(r.report.Items, Item{
+				Kind:    KindJava,
+				Path:    r.opts.JavaPath,
+				Problem: ProblemBroken,
+				Error:   fmt.Sprintf("custom Java can't be repaired, pick another: %v", err),
+			})
+		}
+		return
+	}
+
+	jreDir := launcher.JREDir(r.sharedDir)
+	item := Item{Kind: KindJava, Path: filepath.Base(jreDir)}
+	if javaPath := launcher.FindJava(r.sharedDir); javaPath == "" {
+		item.Problem = integrity.ProblemMissing
+	} else if _, err := javaVersion(javaPath); err != nil {
+		item.Problem = ProblemBroken
+		item.Error = err.Error()
+	} else {
+		return
+	}
+
+	if _, err := os.Stat(jreDir); err == nil {
+		dest, err := r.quarantine(KindJava, r.sharedDir, jreDir)
+		if err != nil {
+			item.Error = fmt.Sprintf("quarantine failed: %v", err)
+			r.report.Items = append(r.report.Items, item)
+			return
+		}
+		item.Quarantined = dest
+	}
+
+	r.opts.StatusCallback("Downloading Java...")
+	javaPath, err := ensureJava(r.sharedDir)
+	if err == nil {
+		_, err = javaVersion(javaPath)
+	}
+	if err != nil {
+		item.Error = err.Error()
+	} else {
+		item.Repaired = true
+		item.Error = ""
+	}
+	r.report.Items = append(r.report.Items, item)
+}
+
+// checkPack deep-verifies the modpack files
+func (r *repairer) checkPack() {
+	quarantine := func(path string) (string, error) {
+		return r.quarantine(KindPack, r.opts.GameDir, path)
+	}
+	repairs, checked, err := integrity.Repair(r.opts.GameDir, r.opts.ServerURL, r.opts.IncludeUserFiles, quarantine, r.opts.StatusCallback)
+	r.report.Checked[KindPack] += checked
+	for _, p := range repairs {
+		r.report.Items = append(r.report.Items, Item{
+			Kind:        KindPack,
+			Path:        p.Path,
+			Problem:     p.Problem,
+			Expected:    p.Expected,
+			Actual:      p.Actual,
+			Quarantined: p.Quarantined,
+			Repaired:    p.Repaired,
+			Error:       p.Error,
+		})
+	}
+	if err != nil {
+		r.report.Items = append(r.report.Items, Item{Kind: KindPack, Path: integrity.LocalManifest, Problem: ProblemBroken, Error: err.Error()})
+	}
+}
+
+func (r *repairer) writeReport() error {
+	if err := os.MkdirAll(r.report.QuarantineDir, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(r.report, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(r.report.QuarantineDir, ReportFile), data, 0644)
+}
@@ -0,0 +1,256 @@
+package repair
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"craft-launcher/launcher"
+	"craft-launcher/launcher/integrity"
+)
+
+func sha1Hex(data []byte) string {
+	sum := sha1.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func writeFile(t *testing.T, path string, data []byte) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	nativeJar := new(bytes.Buffer)
+	zw := zip.NewWriter(nativeJar)
+	w, _ := zw.Create("liblwjgl.so")
+	w.Write([]byte("native code"))
+	zw.Close()
+
+	asset := []byte("sound")
+	index := []byte(`{"objects": {"sound.ogg": {"hash": "` + sha1Hex(asset) + `", "size": 5}}}`)
+	served := map[string][]byte{
+		"/client.jar":            []byte("client"),
+		"/lib.jar":               []byte("library"),
+		"/natives.jar":           nativeJar.Bytes(),
+		"/index.json":            index,
+		"/files/mods/pack.jar":   []byte("pack mod"),
+		"/files/config/user.cfg": []byte("pack default"),
+	}
+	manifest := integrity.Manifest{Version: 1, Files: []integrity.FileInfo{
+		{Path: "mods/pack.jar", Checksum: sha256Hex(served["/files/mods/pack.jar"]), Override: true},
+		{Path: "config/user.cfg", Checksum: sha256Hex(served["/files/config/user.cfg"])},
+	}}
+	served["/manifest.json"], _ = json.Marshal(manifest)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := served[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	defer srv.Close()
+
+	natives := map[string]string{"linux": "natives", "windows": "natives", "osx": "natives"}
+	pkg := &launcher.Package{
+		ID:         "1.8.9",
+		AssetIndex: launcher.AssetIndex{ID: "1.8", Sha1: sha1Hex(index), URL: srv.URL + "/index.json"},
+		Downloads:  launcher.Downloads{Client: launcher.DownloadInfo{Sha1: sha1Hex(served["/client.jar"]), URL: srv.URL + "/client.jar"}},
+		Libraries: []launcher.Library{
+			{Name: "lib", Downloads: launcher.LibDownloads{Artifact: &launcher.Artifact{Path: "org/lib.jar", Sha1: sha1Hex(served["/lib.jar"]), URL: srv.URL + "/lib.jar"}}},
+			{Name: "lwjgl-platform", Natives: natives, Downloads: launcher.LibDownloads{Classifiers: map[string]*launcher.Artifact{
+				"natives": {Path: "org/natives.jar", Sha1: sha1Hex(served["/natives.jar"]), URL: srv.URL + "/natives.jar"},
+			}}},
+		},
+	}
+	getVersion = func(string) (*launcher.Package, error) { return pkg, nil }
+	javaVersion = func(string) (string, error) { return `openjdk version "1.8.0_412"`, nil }
+	defer func() {
+		getVersion, javaVersion = launcher.GetVersion, launcher.JavaVersion
+	}()
+
+	// A tampered library, extracted native and pack mod; the asset index
+	// is missing; the player edited a config file they own
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "versions", "1.8.9", "1.8.9.jar"), served["/client.jar"])
+	writeFile(t, filepath.Join(dir, "libraries", "org", "lib.jar"), []byte("tampered"))
+	writeFile(t, filepath.Join(dir, "libraries", "org", "natives.jar"), served["/natives.jar"])
+	writeFile(t, filepath.Join(dir, "assets", "objects", sha1Hex(asset)[:2], sha1Hex(asset)), asset)
+	writeFile(t, filepath.Join(dir, "natives", "liblwjgl.so"), []byte("patched"))
+	writeFile(t, filepath.Join(dir, "mods", "pack.jar"), []byte("tampered"))
+	writeFile(t, filepath.Join(dir, "config", "user.cfg"), []byte("player edit"))
+
+	opts := Options{GameDir: dir, VersionID: "1.8.9", ServerURL: srv.URL, JavaPath: "java"}
+	report, err := Run(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed := report.Failed(); len(failed) != 0 {
+		t.Fatalf("expected everything to be repaired, got %+v", failed)
+	}
+	found := map[string]Item{}
+	for _, item := range report.Items {
+		found[item.Path] = item
+	}
+	if len(found) != 4 {
+		t.Errorf("expected 4 repaired files, got %+v", report.Items)
+	}
+	if found["assets/indexes/1.8.json"].Problem != integrity.ProblemMissing {
+		t.Errorf("expected the missing asset index to be restored, got %+v", found)
+	}
+	for _, path := range []string{"libraries/org/lib.jar", "natives/liblwjgl.so", "mods/pack.jar"} {
+		item := found[path]
+		if item.Problem != integrity.ProblemModified || item.Quarantined == "" {
+			t.Errorf("expected %s to be quarantined, got %+v", path, item)
+			continue
+		}
+		if data, err := os.ReadFile(item.Quarantined); err != nil || string(data) == "" {
+			t.Errorf("expected the bad copy of %s to be kept: %v", path, err)
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "libraries", "org", "lib.jar")); string(data) != "library" {
+		t.Errorf("expected the library to be downloaded again, got %q", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "natives", "liblwjgl.so")); string(data) != "native code" {
+		t.Errorf("expected the native to be extracted again, got %q", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "config", "user.cfg")); string(data) != "player edit" {
+		t.Errorf("expected the player's file to be left alone, got %q", data)
+	}
+	if report.Checked[KindAsset] != 2 || report.Checked[KindPack] != 2 {
+		t.Errorf("unexpected checked counts %v", report.Checked)
+	}
+	if _, err := os.Stat(filepath.Join(report.QuarantineDir, ReportFile)); err != nil {
+		t.Errorf("expected a report in the quarantine folder: %v", err)
+	}
+
+	// Without version info the report still covers what was done
+	getVersion = func(string) (*launcher.Package, error) { return nil, errors.New("offline") }
+	report, err = Run(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Failed()) != 1 || report.Failed()[0].Kind != KindClient {
+		t.Errorf("expected the version lookup to fail, got %+v", report.Items)
+	}
+	if _, err := os.Stat(filepath.Join(report.QuarantineDir, ReportFile)); err != nil {
+		t.Errorf("expected a report for the partial repair: %v", err)
+	}
+	getVersion = func(string) (*launcher.Package, error) { return pkg, nil }
+
+	// Opting in checks the player's files too
+	opts.IncludeUserFiles = true
+	report, err = Run(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Items) != 1 || report.Items[0].Path != "config/user.cfg" || !report.Items[0].Repaired {
+		t.Errorf("expected only the player's file to be repaired, got %+v", report.Items)
+	}
+}
+
+func TestRun_Fabric(t *testing.T) {
+	index := []byte(`{"objects": {}}`)
+	served := map[string][]byte{
+		"/client.jar": []byte("client"),
+		"/index.json": index,
+		"/maven/net/fabricmc/tiny/1.0/tiny-1.0.jar":                          []byte("tiny mappings"),
+		"/maven/net/fabricmc/fabric-loader/0.14/fabric-loader-0.14.jar":      []byte("loader"),
+		"/legacy/net/legacyfabric/intermediary/1.8.9/intermediary-1.8.9.jar": []byte("intermediary"),
+	}
+	served["/maven/net/fabricmc/fabric-loader/0.14/fabric-loader-0.14.jar.sha1"] = []byte(sha1Hex(served["/maven/net/fabricmc/fabric-loader/0.14/fabric-loader-0.14.jar"]))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := served[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	defer srv.Close()
+
+	pkg := &launcher.Package{
+		ID:         "1.8.9",
+		AssetIndex: launcher.AssetIndex{ID: "1.8", Sha1: sha1Hex(index), URL: srv.URL + "/index.json"},
+		Downloads:  launcher.Downloads{Client: launcher.DownloadInfo{Sha1: sha1Hex(served["/client.jar"]), URL: srv.URL + "/client.jar"}},
+	}
+	meta := &launcher.FabricLoaderResponse{
+		Loader:       launcher.FabricLoaderMeta{Maven: "net.fabricmc:fabric-loader:0.14"},
+		Intermediary: launcher.FabricLoaderMeta{Maven: "net.legacyfabric:intermediary:1.8.9"},
+		LaunchMeta: launcher.FabricLaunchMeta{Libraries: launcher.FabricLibraries{Common: []launcher.FabricLibrary{
+			{Name: "net.fabricmc:tiny:1.0", URL: srv.URL + "/maven/", SHA1: sha1Hex(served["/maven/net/fabricmc/tiny/1.0/tiny-1.0.jar"])},
+		}}},
+	}
+	getVersion = func(string) (*launcher.Package, error) { return pkg, nil }
+	getFabricMeta = func(string) (*launcher.FabricLoaderResponse, error) { return meta, nil }
+	javaVersion = func(string) (string, error) { return `openjdk version "1.8.0_412"`, nil }
+	oldMaven, oldLegacy := launcher.FabricMavenURL, launcher.LegacyFabricMavenURL
+	launcher.FabricMavenURL, launcher.LegacyFabricMavenURL = srv.URL+"/maven/", srv.URL+"/legacy/"
+	defer func() {
+		getVersion, getFabricMeta, javaVersion = launcher.GetVersion, launcher.GetFabricMeta, launcher.JavaVersion
+		launcher.FabricMavenURL, launcher.LegacyFabricMavenURL = oldMaven, oldLegacy
+	}()
+
+	// A corrupted loader and library; the intermediary is missing
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "versions", "1.8.9", "1.8.9.jar"), served["/client.jar"])
+	writeFile(t, filepath.Join(dir, "assets", "indexes", "1.8.json"), index)
+	writeFile(t, filepath.Join(dir, "libraries", "net", "fabricmc", "tiny", "1.0", "tiny-1.0.jar"), []byte("corrupt"))
+	writeFile(t, filepath.Join(dir, "libraries", "net", "fabricmc", "fabric-loader", "0.14", "fabric-loader-0.14.jar"), []byte("truncated"))
+
+	report, err := Run(Options{GameDir: dir, VersionID: "1.8.9", UseFabric: true, JavaPath: "java"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed := report.Failed(); len(failed) != 0 {
+		t.Fatalf("expected everything to be repaired, got %+v", failed)
+	}
+	found := map[string]Item{}
+	for _, item := range report.Items {
+		found[item.Path] = item
+	}
+	want := map[string]string{
+		"libraries/net/fabricmc/tiny/1.0/tiny-1.0.jar":                         integrity.ProblemModified,
+		"libraries/net/fabricmc/fabric-loader/0.14/fabric-loader-0.14.jar":     integrity.ProblemModified,
+		"libraries/net/legacyfabric/intermediary/1.8.9/intermediary-1.8.9.jar": integrity.ProblemMissing,
+	}
+	if len(found) != len(want) {
+		t.Errorf("expected %d repaired files, got %+v", len(want), report.Items)
+	}
+	for path, problem := range want {
+		if found[path].Problem != problem {
+			t.Errorf("expected %s to be %s, got %+v", path, problem, found[path])
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "libraries", "net", "fabricmc", "fabric-loader", "0.14", "fabric-loader-0.14.jar")); string(data) != "loader" {
+		t.Errorf("expected the loader to be downloaded again, got %q", data)
+	}
+
+	// Vanilla instances don't look at Fabric files
+	report, err = Run(Options{GameDir: t.TempDir(), VersionID: "1.8.9", JavaPath: "java"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Checked[KindLibrary] != 0 {
+		t.Errorf("expected no libraries to be checked, got %v", report.Checked)
+	}
+}
@@ -1,28 +1,39 @@
 package launcher
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
 	"strings"
+
+	"craft-launcher/launcher/auth"
+	"craft-launcher/launcher/cache"
+	"craft-launcher/launcher/display"
+	"craft-launcher/launcher/gamelog"
+	"craft-launcher/launcher/jvmargs"
 )
 
 type LaunchOptions struct {
 	Username       string
+	Account        *auth.Account // Signed-in account, nil plays offline as Username
 	GameDir        string
+	SharedDir      string // Libraries, assets, versions and JREs; defaults to GameDir
 	RamMB          int
 	VersionID      string
 	StatusCallback func(string)
-	LogCallback    func(string)
+	LogCallback    func(string)         // Game output, one line per call
+	RecordCallback func(gamelog.Record) // Game output parsed into records, optional
 	UseFabric      bool
-}
-
-// writerFunc adapts a function to io.Writer
-type writerFunc func(p []byte) (n int, err error)
-
-func (f writerFunc) Write(p []byte) (n int, err error) {
-	return f(p)
+	ServerAddress  string         // Join this server on startup (host[:port]), optional
+	JavaPath       string         // Use this java executable instead of the bundled JRE, optional
+	JVMArgs        []string       // Extra JVM arguments, optional
+	GameArgs       []string       // Extra game arguments, optional
+	Window         display.Window // Window size and fullscreen, zero opens the default size
+	Cache          *cache.Store   // Shared object store for libraries and assets, optional
 }
 
 // Launch prepares and executes the Minecraft command
@@ -39,13 +50,67 @@ func Launch(opts LaunchOptions) (*exec.Cmd, error) {
 		}
 	}
 
+	// Launcher messages that show up among the game output
+	reportRecord := func(msg string) {
+		reportLog(msg)
+		if opts.RecordCallback != nil {
+			level := "INFO"
+			if strings.HasPrefix(msg, "Warning:") {
+				level = "WARN"
+			}
+			opts.RecordCallback(gamelog.Record{
+				Stream:  gamelog.StreamLauncher,
+				Level:   level,
+				Message: strings.TrimRight(msg, "\n"),
+			})
+		}
+	}
+
+	// Offline players get the UUID an offline-mode server would give them
+	uuid, accessToken, userType := OfflineUUID(opts.Username), "null", "legacy"
+	if opts.Account != nil {
+		opts.Username = opts.Account.Username
+		uuid, accessToken, userType = opts.Account.UUID(), opts.Account.AccessToken, opts.Account.UserType()
+	} else if err := ValidateUsername(opts.Username); err != nil {
+		return nil, err
+	}
+	if err := opts.Window.Check(); err != nil {
+		return nil, err
+	}
+
+	sharedDir := opts.SharedDir
+	if sharedDir == "" {
+		sharedDir = opts.GameDir
+	}
+
+	// Persist the references taken while downloading, even if launching fails
+	if opts.Cache != nil {
+		defer func() {
+			if err := opts.Cache.Flush(); err != nil {
+				fmt.Printf("Warning: Failed to save cache references: %v\n", err)
+			}
+		}()
+	}
+
 	// 1. Get Java
 	report("Checking Java...")
-	javaPath, err := EnsureJava(opts.GameDir)
-	if err != nil {
-		fmt.Printf("Warning: Could not auto-download Java, trying system java: %v\n", err)
-		reportLog(fmt.Sprintf("Warning: Could not auto-download Java, trying system java: %v\n", err))
-		javaPath = "java"
+	javaPath := opts.JavaPath
+	if javaPath == "" {
+		var err error
+		javaPath, err = EnsureJava(sharedDir)
+		if err != nil {
+			fmt.Printf("Warning: Could not auto-download Java, trying system java: %v\n", err)
+			reportRecord(fmt.Sprintf("Warning: Could not auto-download Java, trying system java: %v\n", err))
+			javaPath = "java"
+		}
+	}
+
+	// Catch bad custom arguments before downloading anything
+	if err := jvmargs.Check(opts.JVMArgs, opts.GameArgs, opts.RamMB); err != nil {
+		return nil, err
+	}
+	if err := ProbeJVMOptions(javaPath, jvmargs.VMOptions(opts.JVMArgs)); err != nil {
+		return nil, err
 	}
 
 	// 2. Load Manifest & Package
@@ -69,13 +134,15 @@ func Launch(opts LaunchOptions) (*exec.Cmd, error) {
 	// 3. Download Everything (Blocking for now, should be progress-reported)
 	// TODO: report progress
 	report("Downloading Assets...")
-	err = DownloadAssets(pkg.AssetIndex, opts.GameDir)
+	err = DownloadAssets(pkg.AssetIndex, sharedDir, opts.Cache)
 	if err != nil {
 		return nil, fmt.Errorf("assets error: %w", err)
 	}
 
+	nativesDir := filepath.Join(opts.GameDir, "natives")
+
 	report("Downloading Libraries...")
-	cp, err := DownloadLibraries(pkg.Libraries, opts.GameDir)
+	cp, err := DownloadLibraries(pkg.Libraries, sharedDir, nativesDir, opts.Cache)
 	if err != nil {
 		return nil, fmt.Errorf("libs error: %w", err)
 	}
@@ -83,19 +150,17 @@ func Launch(opts LaunchOptions) (*exec.Cmd, error) {
 	// 4. Construct Arguments
 	// Add client jar to classpath
 	report("Downloading Client Jar...")
-	clientJarPath := filepath.Join(opts.GameDir, "versions", pkg.ID, pkg.ID+".jar")
-	if err := downloadFile(pkg.Downloads.Client.URL, clientJarPath); err != nil {
+	clientJarPath := filepath.Join(sharedDir, "versions", pkg.ID, pkg.ID+".jar")
+	if err := fetchFile(opts.Cache, pkg.Downloads.Client.Sha1, pkg.Downloads.Client.URL, clientJarPath); err != nil {
 		return nil, fmt.Errorf("client jar download failed: %w", err)
 	}
 	realCp := cp + string(os.PathListSeparator) + clientJarPath
 
-	nativesDir := filepath.Join(opts.GameDir, "natives")
-
 	// Apply M1 Patches if needed
 	report("Checking for Native Patches...")
 	if err := PatchNatives(nativesDir); err != nil {
 		fmt.Printf("Warning: Failed to apply M1 patches: %v\n", err)
-		reportLog(fmt.Sprintf("Warning: Failed to apply M1 patches: %v\n", err))
+		reportRecord(fmt.Sprintf("Warning: Failed to apply M1 patches: %v\n", err))
 	}
 
 	// Arguments construction
@@ -103,13 +168,13 @@ func Launch(opts LaunchOptions) (*exec.Cmd, error) {
 
 	if opts.UseFabric {
 		report("Fetching Fabric Meta...")
-		fabricMeta, err := GetFabricMeta()
+		fabricMeta, err := GetFabricMeta(opts.VersionID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get fabric meta: %w", err)
 		}
 
 		report("Downloading Fabric Libs...")
-		fabricCp, err := DownloadFabricLibraries(fabricMeta, opts.GameDir)
+		fabricCp, err := DownloadFabricLibraries(fabricMeta, sharedDir, opts.Cache)
 		if err != nil {
 			return nil, fmt.Errorf("failed to download fabric libs: %w", err)
 		}
@@ -123,12 +188,29 @@ func Launch(opts LaunchOptions) (*exec.Cmd, error) {
 		pkg.MainClass = fabricMeta.LaunchMeta.MainClass.Client
 	}
 
+	// Community auth servers serve skins and sessions through authlib-injector
+	var authArgs []string
+	if opts.Account != nil && opts.Account.Type == auth.TypeYggdrasil {
+		report("Preparing authlib-injector...")
+		jar, err := EnsureAuthlibInjector(sharedDir)
+		if err != nil {
+			return nil, err
+		}
+		metadata, err := auth.NewYggdrasil().Metadata(context.Background(), opts.Account.Server)
+		if err != nil {
+			// authlib-injector fetches it itself when the game starts
+			reportRecord(fmt.Sprintf("Warning: Could not prefetch auth server metadata: %v\n", err))
+		}
+		authArgs = AuthlibInjectorArgs(jar, opts.Account.Server, metadata)
+	}
+
 	args := []string{
 		fmt.Sprintf("-Xmx%dM", opts.RamMB),
 		fmt.Sprintf("-Djava.library.path=%s", nativesDir),
-		"-cp", realCp,
-		pkg.MainClass,
 	}
+	args = append(args, authArgs...)
+	args = append(args, opts.JVMArgs...)
+	args = append(args, "-cp", realCp, pkg.MainClass)
 
 	// Parse minecraftArguments template
 	// e.g. "--username ${auth_player_name} --version ${version_name} --gameDir ${game_directory} --assetsDir ${assets_root} --assetIndex ${assets_index_name} --uuid ${auth_uuid} --accessToken ${auth_access_token} --userProperties ${user_properties} --userType ${user_type}"
@@ -137,12 +219,12 @@ func Launch(opts LaunchOptions) (*exec.Cmd, error) {
 		"${auth_player_name}":  opts.Username,
 		"${version_name}":      pkg.ID,
 		"${game_directory}":    opts.GameDir,
-		"${assets_root}":       filepath.Join(opts.GameDir, "assets"),
+		"${assets_root}":       filepath.Join(sharedDir, "assets"),
 		"${assets_index_name}": pkg.AssetIndex.ID,
-		"${auth_uuid}":         "00000000-0000-0000-0000-000000000000", // Offline UUID
-		"${auth_access_token}": "null",                                 // Offline Token
+		"${auth_uuid}":         uuid,
+		"${auth_access_token}": accessToken,
 		"${user_properties}":   "{}",
-		"${user_type}":         "legacy",
+		"${user_type}":         userType,
 	}
 
 	for k, v := range replacements {
@@ -151,30 +233,70 @@ func Launch(opts LaunchOptions) (*exec.Cmd, error) {
 
 	args = append(args, strings.Split(mcArgs, " ")...)
 
-	// Force default window size to stabilize startup resize behavior
-	args = append(args, "--width", "854", "--height", "480")
+	// Always pass a size to stabilize startup resize behavior. Versions
+	// without --fullscreen read it from options.txt instead.
+	args = append(args, opts.Window.Args(pkg.ID)...)
+	if !display.FullscreenFlag(pkg.ID) {
+		if err := display.WriteFullscreen(opts.GameDir, opts.Window.Fullscreen); err != nil {
+			reportRecord(fmt.Sprintf("Warning: Could not set fullscreen in options.txt: %v\n", err))
+		}
+	}
+
+	// Connect straight to a server (minecraftArguments accepts --server/--port)
+	if opts.ServerAddress != "" {
+		host, port, err := ResolveServerAddress(opts.ServerAddress)
+		if err != nil {
+			reportRecord(fmt.Sprintf("Warning: Ignoring server address %q: %v\n", opts.ServerAddress, err))
+		} else {
+			args = append(args, "--server", host, "--port", strconv.Itoa(port))
+		}
+	}
+
+	args = append(args, opts.GameArgs...)
 
 	// 5. Execute
 	report("Launching...")
-	fmt.Printf("Executing: %s %v\n", javaPath, args)
-	reportLog(fmt.Sprintf("Executing: %s %v\n", javaPath, args))
+	// Never show the access token in the console or session log
+	executing := fmt.Sprintf("Executing: %s %v\n", javaPath, args)
+	if opts.Account != nil && accessToken != "" {
+		executing = strings.ReplaceAll(executing, accessToken, "<hidden>")
+	}
+	fmt.Print(executing)
+	reportRecord(executing)
 
 	cmd := exec.Command(javaPath, args...)
 	cmd.Dir = opts.GameDir
 
-	// Capture output
-	// We want to write to stdout AND calling callback
-	logWriter := writerFunc(func(p []byte) (n int, err error) {
-		os.Stdout.Write(p) // Echo to real stdout
-		reportLog(string(p))
-		return len(p), nil
-	})
+	// Capture output as whole lines per stream, parsed into records
+	parser := gamelog.NewParser()
+	emitLine := func(stream, line string) {
+		rec, ok := parser.Line(stream, line)
+		if !ok {
+			return // Inside a multi-line XML event
+		}
+		text := rec.Text() + "\n"
+		os.Stdout.WriteString(text) // Echo to real stdout
+		reportLog(text)
+		if opts.RecordCallback != nil {
+			opts.RecordCallback(rec)
+		}
+	}
 
-	cmd.Stdout = logWriter
-	cmd.Stderr = logWriter
+	cmd.Stdout = gamelog.NewLineWriter(gamelog.StreamStdout, emitLine)
+	cmd.Stderr = gamelog.NewLineWriter(gamelog.StreamStderr, emitLine)
 
 	if err := cmd.Start(); err != nil {
 		return nil, err
 	}
 	return cmd, nil
 }
+
+// FlushOutput emits a last line of game output that didn't end in a
+// newline. Call it after cmd.Wait.
+func FlushOutput(cmd *exec.Cmd) {
+	for _, w := range []io.Writer{cmd.Stdout, cmd.Stderr} {
+		if lw, ok := w.(*gamelog.LineWriter); ok {
+			lw.Flush()
+		}
+	}
+}
@@ -0,0 +1,281 @@
+// Package session tracks running game processes so several clients can run
+// side by side, each under its own session ID.
+package session
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxOutput is how much recent game output a session keeps for diagnosis
+const maxOutput = 512 * 1024
+
+const (
+	StateLaunching = "launching"
+	StateRunning   = "running"
+	StateStopping  = "stopping"
+)
+
+// Session is one launch of an instance, from integrity check to exit
+type Session struct {
+	ID         string    `json:"id"`
+	InstanceID string    `json:"instanceId"`
+	Username   string    `json:"username"`
+	State      string    `json:"state"`
+	PID        int       `json:"pid"` // 0 until the process started
+	StartedAt  time.Time `json:"startedAt"`
+	LogFile    string    `json:"logFile"` // Name of the session's log, see SetLog
+
+	cmd      *exec.Cmd
+	done     chan struct{}
+	watchers []chan string
+	output   []byte    // Tail of the game output
+	log      io.Writer // Persistent log, optional
+}
+
+// Output returns the most recent game output of the session
+func (s Session) Output() string {
+	return string(s.output)
+}
+
+// Registry holds the sessions of this launcher process
+type Registry struct {
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{sessions: make(map[string]*Session)}
+}
+
+// Begin registers a new launching session for an instance
+func (r *Registry) Begin(instanceID, username string) Session {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	id := newID()
+	for r.sessions[id] != nil {
+		id = newID()
+	}
+	s := &Session{
+		ID:         id,
+		InstanceID: instanceID,
+		Username:   username,
+		State:      StateLaunching,
+		StartedAt:  time.Now(),
+		done:       make(chan struct{}),
+	}
+	r.sessions[id] = s
+	return *s
+}
+
+// Attach records the started game process of a session
+func (r *Registry) Attach(id string, cmd *exec.Cmd) (Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.sessions[id]
+	if s == nil {
+		return Session{}, fmt.Errorf("session %q not found", id)
+	}
+	s.cmd = cmd
+	s.State = StateRunning
+	if cmd.Process != nil {
+		s.PID = cmd.Process.Pid
+	}
+	return *s, nil
+}
+
+// SetLog makes the session copy its status messages and game output to w,
+// a log file known to the UI by name. w must be safe for concurrent use.
+func (r *Registry) SetLog(id, name string, w io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.sessions[id]
+	if s == nil {
+		return fmt.Errorf("session %q not found", id)
+	}
+	s.LogFile = name
+	s.log = w
+	return nil
+}
+
+// Status writes a launcher status message to the session's log
+func (r *Registry) Status(id, msg string) {
+	if log := r.logWriter(id); log != nil {
+		fmt.Fprintf(log, "[LAUNCHER] %s\n", strings.TrimRight(msg, "\n"))
+	}
+}
+
+// logWriter returns a session's log, if it has one
+func (r *Registry) logWriter(id string) io.Writer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if s := r.sessions[id]; s != nil {
+		return s.log
+	}
+	return nil
+}
+
+// MarkStopping flags a session as being stopped on purpose, so its exit
+// isn't reported as a crash
+func (r *Registry) MarkStopping(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.sessions[id]
+	if s == nil {
+		return fmt.Errorf("session %q not found", id)
+	}
+	s.State = StateStopping
+	return nil
+}
+
+// End removes a session once its process exited or its launch failed and
+// returns its final state
+func (r *Registry) End(id string) (Session, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.sessions[id]
+	if s == nil {
+		return Session{}, false
+	}
+	delete(r.sessions, id)
+	close(s.done)
+	for _, w := range s.watchers {
+		close(w)
+	}
+	s.watchers = nil
+	return *s, true
+}
+
+// Done returns a channel closed when the session ends. Unknown sessions
+// count as ended.
+func (r *Registry) Done(id string) <-chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if s := r.sessions[id]; s != nil {
+		return s.done
+	}
+	done := make(chan struct{})
+	close(done)
+	return done
+}
+
+// Log records a chunk of game output and hands it to the session's
+// watchers. Slow watchers miss output rather than stall the game.
+func (r *Registry) Log(id, data string) {
+	if log := r.record(id, data); log != nil {
+		io.WriteString(log, data)
+	}
+}
+
+// record keeps data in the session's output tail and passes it to the
+// watchers. It returns the session's log, written to without holding the
+// registry lock so slow disks don't block other sessions.
+func (r *Registry) record(id, data string) io.Writer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.sessions[id]
+	if s == nil {
+		return nil
+	}
+	s.output = append(s.output, data...)
+	if over := len(s.output) - maxOutput; over > 0 {
+		s.output = append(s.output[:0], s.output[over:]...)
+	}
+	for _, w := range s.watchers {
+		select {
+		case w <- data:
+		default:
+		}
+	}
+	return s.log
+}
+
+// Watch subscribes to a session's game output until the session ends or
+// the returned cancel func is called
+func (r *Registry) Watch(id string) (<-chan string, func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ch := make(chan string, 64)
+	s := r.sessions[id]
+	if s == nil {
+		close(ch)
+		return ch, func() {}
+	}
+	s.watchers = append(s.watchers, ch)
+
+	cancel := func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		for i, w := range s.watchers {
+			if w == ch {
+				s.watchers = append(s.watchers[:i], s.watchers[i+1:]...)
+				close(ch)
+				return
+			}
+		}
+	}
+	return ch, cancel
+}
+
+// Get returns a session and its process, which is nil while launching
+func (r *Registry) Get(id string) (Session, *exec.Cmd, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.sessions[id]
+	if s == nil {
+		return Session{}, nil, false
+	}
+	return *s, s.cmd, true
+}
+
+// List returns all sessions, oldest first
+func (r *Registry) List() []Session {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := make([]Session, 0, len(r.sessions))
+	for _, s := range r.sessions {
+		list = append(list, *s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].StartedAt.Before(list[j].StartedAt)
+	})
+	return list
+}
+
+// ForInstance returns the sessions of one instance, oldest first
+func (r *Registry) ForInstance(instanceID string) []Session {
+	var list []Session
+	for _, s := range r.List() {
+		if s.InstanceID == instanceID {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+func newID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%08x", time.Now().UnixNano()&0xffffffff)
+	}
+	return hex.EncodeToString(b)
+}
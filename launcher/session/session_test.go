@@ -0,0 +1,81 @@
+package session
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_Lifecycle(t *testing.T) {
+	r := NewRegistry()
+
+	a := r.Begin("default", "Alex")
+	b := r.Begin("default", "Steve")
+	c := r.Begin("pvp", "Alex")
+	if a.ID == b.ID || a.State != StateLaunching {
+		t.Fatalf("unexpected sessions %+v %+v", a, b)
+	}
+
+	if got := r.ForInstance("default"); len(got) != 2 || got[0].ID != a.ID || got[1].ID != b.ID {
+		t.Errorf("expected both default sessions in start order, got %+v", got)
+	}
+
+	if _, cmd, ok := r.Get(a.ID); !ok || cmd != nil {
+		t.Error("expected launching session without a process")
+	}
+	cmd := exec.Command("true")
+	s, err := r.Attach(a.ID, cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.State != StateRunning {
+		t.Errorf("expected running state, got %q", s.State)
+	}
+	if _, got, _ := r.Get(a.ID); got != cmd {
+		t.Error("expected attached process")
+	}
+
+	var log strings.Builder
+	if err := r.SetLog(a.ID, "session.log", &log); err != nil {
+		t.Fatal(err)
+	}
+	r.Status(a.ID, "Running")
+
+	logs, cancel := r.Watch(a.ID)
+	defer cancel()
+	r.Log(a.ID, "Stopping!")
+	if line := <-logs; line != "Stopping!" {
+		t.Errorf("expected watched output, got %q", line)
+	}
+
+	if err := r.MarkStopping(a.ID); err != nil {
+		t.Fatal(err)
+	}
+	final, ok := r.End(a.ID)
+	if !ok || final.State != StateStopping {
+		t.Errorf("expected stopping session to end, got %+v", final)
+	}
+	if final.Output() != "Stopping!" || final.LogFile != "session.log" {
+		t.Errorf("expected recorded output, got %q", final.Output())
+	}
+	if log.String() != "[LAUNCHER] Running\nStopping!" {
+		t.Errorf("unexpected session log %q", log.String())
+	}
+	select {
+	case <-r.Done(a.ID):
+	default:
+		t.Error("expected ended session to be done")
+	}
+	if _, open := <-logs; open {
+		t.Error("expected watchers to be closed when the session ends")
+	}
+	if _, _, ok := r.Get(a.ID); ok {
+		t.Error("expected ended session to be gone")
+	}
+	if _, err := r.Attach(a.ID, cmd); err == nil {
+		t.Error("expected error attaching to an ended session")
+	}
+	if got := r.List(); len(got) != 2 || got[0].ID != b.ID || got[1].ID != c.ID {
+		t.Errorf("unexpected remaining sessions %+v", got)
+	}
+}
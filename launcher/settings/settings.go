@@ -0,0 +1,201 @@
+// Package settings persists launcher preferences as a versioned JSON file.
+package settings
+
+import (
+	"craft-launcher/launcher/integrity"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	// FileName is the settings file inside the settings directory
+	FileName = "launcher_settings.json"
+
+	// CurrentVersion is the schema version written by this build
+	CurrentVersion = 1
+
+	// DefaultServerURL is used when the build doesn't set integrity.ServerURL
+	DefaultServerURL        = "http://127.0.0.1:8090"
+	DefaultStopGraceSeconds = 15
+)
+
+// Settings holds everything the launcher remembers between runs
+type Settings struct {
+	Version   int    `json:"version"`
+	Username  string `json:"username"`
+	RamMB     int    `json:"ramMB"`
+	UseFabric bool   `json:"useFabric"`
+	ServerURL string `json:"serverURL"`
+	AutoJoin  string `json:"autoJoin"` // Overrides the modpack's auto-join server when set
+	ShowLog   bool   `json:"showLog"`
+	Account   string `json:"account"` // Signed-in account to play with, empty plays offline
+
+	StopGraceSeconds int `json:"stopGraceSeconds"` // Time the game gets to quit before it's killed
+}
+
+// Defaults returns the settings used on first run, with the modpack server
+// release builds are made for
+func Defaults() Settings {
+	serverURL := integrity.ServerURL
+	if serverURL == "" {
+		serverURL = DefaultServerURL
+	}
+	return Settings{
+		Version:   CurrentVersion,
+		Username:  "Player",
+		ServerURL: serverURL,
+
+		StopGraceSeconds: DefaultStopGraceSeconds,
+	}
+}
+
+// Dir returns where settings live: the game dir for portable installs,
+// otherwise the user's config dir (XDG_CONFIG_HOME on Linux)
+func Dir(gameDir string) string {
+	if gameDir != "" {
+		return gameDir
+	}
+	if configDir, err := os.UserConfigDir(); err == nil {
+		return filepath.Join(configDir, "craft-launcher")
+	}
+	return "."
+}
+
+// migration upgrades a raw settings document from version N to N+1
+type migration func(doc map[string]any)
+
+// migrations[i] upgrades version i to i+1
+var migrations = []migration{
+	// 0 -> 1: files written before versioning had the same fields
+	func(doc map[string]any) {},
+}
+
+// Store loads and saves settings, serializing access from bound methods
+type Store struct {
+	path    string
+	mu      sync.Mutex
+	loadErr error // Why the last Load failed, Save won't overwrite the file then
+}
+
+// NewStore creates a store backed by dir/FileName
+func NewStore(dir string) *Store {
+	return &Store{path: filepath.Join(dir, FileName)}
+}
+
+// Path returns the settings file location
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Load reads settings, migrating older files. A missing file yields defaults.
+// Defaults are also returned with the error when the file can't be used.
+func (s *Store) Load() (Settings, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	settings, err := s.load()
+	s.loadErr = err
+	return settings, err
+}
+
+func (s *Store) load() (Settings, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return Defaults(), nil
+	}
+	if err != nil {
+		return Defaults(), err
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return Defaults(), fmt.Errorf("corrupt settings file: %w", err)
+	}
+
+	version := 0
+	if v, ok := doc["version"].(float64); ok {
+		version = int(v)
+	}
+	if version > CurrentVersion {
+		return Defaults(), fmt.Errorf("settings file version %d is newer than supported version %d", version, CurrentVersion)
+	}
+	for ; version < CurrentVersion; version++ {
+		migrations[version](doc)
+	}
+	doc["version"] = CurrentVersion
+
+	// Start from defaults so fields missing from the file keep sane values
+	settings := Defaults()
+	migrated, _ := json.Marshal(doc)
+	if err := json.Unmarshal(migrated, &settings); err != nil {
+		return Defaults(), fmt.Errorf("corrupt settings file: %w", err)
+	}
+	return settings, nil
+}
+
+// Save writes settings atomically: a temp file is synced and renamed over
+// the old one, so a crash never leaves a half-written file behind. After a
+// failed Load it refuses, as settings then hold defaults rather than the
+// user's; see Overwrite.
+func (s *Store) Save(settings Settings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.loadErr != nil {
+		return fmt.Errorf("not overwriting unreadable settings: %w", s.loadErr)
+	}
+	return s.save(settings)
+}
+
+// Overwrite saves settings even if the file on disk couldn't be loaded
+func (s *Store) Overwrite(settings Settings) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.save(settings); err != nil {
+		return err
+	}
+	s.loadErr = nil
+	return nil
+}
+
+func (s *Store) save(settings Settings) error {
+	settings.Version = CurrentVersion
+	data, err := json.MarshalIndent(settings, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), FileName+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, s.path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
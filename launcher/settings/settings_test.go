@@ -0,0 +1,90 @@
+package settings
+
+import (
+	"craft-launcher/launcher/integrity"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStore_LoadSaveAndMigrate(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+
+	// Missing file gives defaults
+	s, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != Defaults() {
+		t.Errorf("expected defaults, got %+v", s)
+	}
+
+	// An unversioned file is migrated and keeps defaults for missing fields
+	legacy := `{"username": "Alex", "ramMB": 4096}`
+	if err := os.WriteFile(filepath.Join(dir, FileName), []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s, err = store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Version != CurrentVersion || s.Username != "Alex" || s.RamMB != 4096 || s.ServerURL != Defaults().ServerURL {
+		t.Errorf("unexpected migrated settings %+v", s)
+	}
+
+	s.UseFabric = true
+	if err := store.Save(s); err != nil {
+		t.Fatal(err)
+	}
+	reloaded, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reloaded != s {
+		t.Errorf("round trip mismatch: %+v vs %+v", reloaded, s)
+	}
+
+	// No temp files are left behind
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("expected only the settings file, found %d entries", len(entries))
+	}
+
+	// Files from a newer launcher are not silently overwritten with defaults
+	os.WriteFile(filepath.Join(dir, FileName), []byte(`{"version": 99}`), 0644)
+	if _, err := store.Load(); err == nil {
+		t.Error("expected error for newer settings version")
+	}
+	if err := store.Save(Defaults()); err == nil {
+		t.Error("expected Save to refuse after a failed load")
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, FileName)); string(data) != `{"version": 99}` {
+		t.Errorf("expected the newer file to be kept, got %s", data)
+	}
+
+	// Overwriting is explicit
+	if err := store.Overwrite(Defaults()); err != nil {
+		t.Fatal(err)
+	}
+	if s, err := store.Load(); err != nil || s != Defaults() {
+		t.Errorf("expected overwritten defaults, got %+v, %v", s, err)
+	}
+	if err := store.Save(s); err != nil {
+		t.Errorf("expected Save to work after a good load: %v", err)
+	}
+}
+
+func TestDefaults_ServerURL(t *testing.T) {
+	old := integrity.ServerURL
+	defer func() { integrity.ServerURL = old }()
+
+	integrity.ServerURL = "https://pack.example.com"
+	if got := Defaults().ServerURL; got != "https://pack.example.com" {
+		t.Errorf("expected the build's server, got %q", got)
+	}
+	integrity.ServerURL = ""
+	if got := Defaults().ServerURL; got != DefaultServerURL {
+		t.Errorf("expected the local server without a build setting, got %q", got)
+	}
+}
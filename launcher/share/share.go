@@ -0,0 +1,189 @@
+// Package share redacts session logs and crash files and uploads them as a
+// single zip to the modpack server, which answers with a short ID.
+package share
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"craft-launcher/launcher/crash"
+)
+
+const (
+	// UploadPath is the modpack server endpoint that accepts log bundles
+	UploadPath = "/logs"
+
+	// MaxBundleSize matches the server's upload limit
+	MaxBundleSize = 10 * 1024 * 1024
+)
+
+// Result identifies an uploaded bundle
+type Result struct {
+	ID  string `json:"id"`
+	URL string `json:"url"`
+}
+
+// File is one entry of a bundle
+type File struct {
+	Name string // Path inside the zip
+	Data string
+}
+
+var (
+	// Tokens passed on the command line or printed in JSON
+	tokenArg  = regexp.MustCompile(`(?i)(--(?:accessToken|clientToken|session|uuid)[ =])\S+`)
+	tokenJSON = regexp.MustCompile(`(?i)("(?:accessToken|clientToken|refresh_token|access_token|id_token|password|secret)"\s*:\s*")[^"]*"`)
+	tokenKV   = regexp.MustCompile(`(?i)\b((?:access[_-]?token|token|password|secret|session[_-]?id)\s*[=:]\s*)[^\s,;&"']+`)
+	jwt       = regexp.MustCompile(`\beyJ[\w-]+\.[\w-]+\.[\w-]+\b`)
+
+	ipv4 = regexp.MustCompile(`\b(?:\d{1,3}\.){3}\d{1,3}\b`)
+	ipv6 = regexp.MustCompile(`(?i)\b(?:[0-9a-f]{1,4}:){7}[0-9a-f]{1,4}\b|\b(?:[0-9a-f]{1,4}:){1,6}:(?:[0-9a-f]{1,4}:?){1,6}\b`)
+
+	// Where the launcher and the game print the player name
+	username = regexp.MustCompile(`(?:--username |Username: |Setting user: )(\w+)`)
+
+	// Home directories of any user, with / or \ separators
+	homePath = regexp.MustCompile(`(?i)(/home/|/Users/|[A-Z]:\\Users\\|[A-Z]:/Users/)[^/\\\s:;"']+`)
+)
+
+// Redactor removes personal details from log text
+type Redactor struct {
+	names []*regexp.Regexp
+	home  string
+}
+
+// NewRedactor creates a redactor that also hides the given usernames and
+// the current user's home dir
+func NewRedactor(usernames ...string) *Redactor {
+	r := &Redactor{}
+	for _, name := range usernames {
+		if len(strings.TrimSpace(name)) < 3 {
+			continue // Too short to replace without mangling other text
+		}
+		r.names = append(r.names, regexp.MustCompile(`(?i)\b`+regexp.QuoteMeta(name)+`\b`))
+	}
+	if home, err := os.UserHomeDir(); err == nil && len(home) > 1 {
+		r.home = home
+	}
+	return r
+}
+
+// Redact returns text with tokens, home paths, IPs and usernames replaced
+func (r *Redactor) Redact(text string) string {
+	text = tokenArg.ReplaceAllString(text, "${1}<redacted>")
+	text = tokenJSON.ReplaceAllString(text, `${1}<redacted>"`)
+	text = tokenKV.ReplaceAllString(text, "${1}<redacted>")
+	text = jwt.ReplaceAllString(text, "<redacted>")
+
+	if r.home != "" {
+		text = strings.ReplaceAll(text, r.home, "~")
+		text = strings.ReplaceAll(text, filepath.ToSlash(r.home), "~")
+	}
+	text = homePath.ReplaceAllString(text, "${1}<user>")
+
+	text = ipv4.ReplaceAllStringFunc(text, func(ip string) string {
+		if strings.HasPrefix(ip, "127.") || ip == "0.0.0.0" {
+			return ip
+		}
+		return "<ip>"
+	})
+	text = ipv6.ReplaceAllString(text, "<ip>")
+
+	for _, name := range r.names {
+		text = name.ReplaceAllString(text, "<player>")
+	}
+	return text
+}
+
+// Usernames returns the player names a log mentions
+func Usernames(text string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, m := range username.FindAllStringSubmatch(text, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
+
+// CrashFiles returns the crash reports and JVM error logs in gameDir that
+// were written between from and to
+func CrashFiles(gameDir string, from, to time.Time) []string {
+	var paths []string
+	for _, pattern := range []string{
+		filepath.Join(gameDir, crash.CrashReportsDir, "crash-*.txt"),
+		filepath.Join(gameDir, "hs_err_pid*.log"),
+	} {
+		matches, _ := filepath.Glob(pattern)
+		for _, path := range matches {
+			info, err := os.Stat(path)
+			if err != nil || info.ModTime().Before(from) || info.ModTime().After(to) {
+				continue
+			}
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Bundle redacts files and zips them
+func Bundle(r *Redactor, files []File) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		w, err := zw.Create(f.Name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(w, r.Redact(f.Data)); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	if buf.Len() > MaxBundleSize {
+		return nil, fmt.Errorf("log bundle is too large (%d MiB)", buf.Len()/(1024*1024))
+	}
+	return buf.Bytes(), nil
+}
+
+// Upload posts a bundle to the modpack server
+func Upload(serverURL string, bundle []byte) (Result, error) {
+	endpoint := strings.TrimRight(serverURL, "/") + UploadPath
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Post(endpoint, "application/zip", bytes.NewReader(bundle))
+	if err != nil {
+		return Result{}, fmt.Errorf("upload failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return Result{}, fmt.Errorf("upload failed: %s", resp.Status)
+	}
+
+	var result Result
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&result); err != nil {
+		return Result{}, fmt.Errorf("invalid upload response: %w", err)
+	}
+	if result.ID == "" {
+		return Result{}, fmt.Errorf("invalid upload response: missing id")
+	}
+	if result.URL == "" {
+		result.URL = endpoint + "/" + result.ID
+	}
+	return result, nil
+}
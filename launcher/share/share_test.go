@@ -0,0 +1,97 @@
+package share
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRedactor_Redact(t *testing.T) {
+	log := strings.Join([]string{
+		"[LAUNCHER] Username: Steve",
+		"Executing: java [-Xmx2048M -cp /home/steve/craft/lib.jar net.minecraft.client.main.Main --username Steve --accessToken abc123 --gameDir C:\\Users\\Steve\\data]",
+		`{"accessToken": "eyJhbGciOi.eyJzdWIiOi.c2lnbmF0dXJl", "name": "x"}`,
+		"[12:00:00] [Client thread/INFO]: Connecting to 203.0.113.7, 25565",
+		"Bound to 127.0.0.1 and fe80::1ff:fe23:4567:890a",
+		"token=s3cr3t&next=1",
+	}, "\n")
+
+	r := NewRedactor(Usernames(log)...)
+	got := r.Redact(log)
+
+	for _, leak := range []string{"Steve", "steve", "abc123", "eyJhbGciOi", "203.0.113.7", "fe80::1ff", "s3cr3t"} {
+		if strings.Contains(got, leak) {
+			t.Errorf("redacted log still contains %q:\n%s", leak, got)
+		}
+	}
+	for _, keep := range []string{"--accessToken <redacted>", "/home/<user>/craft/lib.jar", "<ip>, 25565", "127.0.0.1", "net.minecraft.client.main.Main"} {
+		if !strings.Contains(got, keep) {
+			t.Errorf("expected redacted log to contain %q:\n%s", keep, got)
+		}
+	}
+}
+
+func TestCrashFiles(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "crash-reports"), 0755)
+	report := filepath.Join(dir, "crash-reports", "crash-2024-01-01_12.00.00-client.txt")
+	old := filepath.Join(dir, "crash-reports", "crash-2023-01-01_12.00.00-client.txt")
+	jvm := filepath.Join(dir, "hs_err_pid42.log")
+	for _, path := range []string{report, old, jvm} {
+		os.WriteFile(path, []byte("crash"), 0644)
+	}
+	start := time.Now().Add(-time.Minute)
+	os.Chtimes(old, start.Add(-time.Hour), start.Add(-time.Hour))
+
+	got := CrashFiles(dir, start, time.Now().Add(time.Minute))
+	if len(got) != 2 || got[0] != report || got[1] != jvm {
+		t.Errorf("unexpected crash files %v", got)
+	}
+}
+
+func TestBundleAndUpload(t *testing.T) {
+	var received []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != UploadPath {
+			http.NotFound(w, r)
+			return
+		}
+		received, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(Result{ID: "abc12345"})
+	}))
+	defer srv.Close()
+
+	bundle, err := Bundle(NewRedactor("Steve"), []File{{Name: "session.log", Data: "Setting user: Steve\n"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Upload(srv.URL+"/", bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.ID != "abc12345" || result.URL != srv.URL+"/logs/abc12345" {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(received), int64(len(received)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := zr.File[0].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(f)
+	if string(data) != "Setting user: <player>\n" {
+		t.Errorf("unexpected uploaded log %q", data)
+	}
+}
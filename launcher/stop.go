@@ -0,0 +1,81 @@
+package launcher
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"craft-launcher/launcher/settings"
+)
+
+// ShutdownMarkers are log lines the client prints while shutting down cleanly
+var ShutdownMarkers = []string{
+	"Stopping!",
+	"Stopping server",
+	"Saving worlds",
+	"Saving chunks for level",
+}
+
+// StopOptions configures a graceful stop
+type StopOptions struct {
+	Grace          time.Duration   // Defaults to settings.DefaultStopGraceSeconds
+	Exited         <-chan struct{} // Closed once the process has exited
+	Logs           <-chan string   // Game output while stopping, optional
+	StatusCallback func(string)
+}
+
+// Stop asks the game to quit, waits for the grace period while watching the
+// log for shutdown markers, then kills it. It reports whether a kill was
+// needed.
+func Stop(p *os.Process, opts StopOptions) (bool, error) {
+	report := func(msg string) {
+		if opts.StatusCallback != nil {
+			opts.StatusCallback(msg)
+		}
+	}
+
+	grace := opts.Grace
+	if grace <= 0 {
+		grace = settings.DefaultStopGraceSeconds * time.Second
+	}
+
+	report("Stopping...")
+	if err := terminate(p); err != nil {
+		// Nothing to wait for if the request can't be delivered
+		report("Stopping: shutdown request failed, force stopping...")
+		return true, p.Kill()
+	}
+
+	timer := time.NewTimer(grace)
+	defer timer.Stop()
+
+	logs := opts.Logs
+	saving := false
+	for {
+		select {
+		case <-opts.Exited:
+			return false, nil
+		case line, ok := <-logs:
+			if !ok {
+				logs = nil
+				continue
+			}
+			if !saving && isShutdownLine(line) {
+				saving = true
+				report("Stopping: saving world...")
+			}
+		case <-timer.C:
+			report("Stopping: not responding, force stopping...")
+			return true, p.Kill()
+		}
+	}
+}
+
+func isShutdownLine(line string) bool {
+	for _, marker := range ShutdownMarkers {
+		if strings.Contains(line, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+//go:build !windows
+
+package launcher
+
+import (
+	"bufio"
+	"io"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+// startStoppable runs script, which must print "ready" once its trap is
+// installed, and waits for that
+func startStoppable(t *testing.T, script string) (*exec.Cmd, chan struct{}) {
+	cmd := exec.Command("sh", "-c", script)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	ready := bufio.NewReader(stdout)
+	if line, err := ready.ReadString('\n'); err != nil || line != "ready\n" {
+		t.Fatalf("process didn't start: %q, %v", line, err)
+	}
+
+	// Wait must not run before the output is read
+	exited := make(chan struct{})
+	go func() {
+		io.Copy(io.Discard, ready)
+		cmd.Wait()
+		close(exited)
+	}()
+	return cmd, exited
+}
+
+func TestStop_GracefulAndForced(t *testing.T) {
+	// A process that shuts down on SIGTERM isn't killed
+	cmd, exited := startStoppable(t, `trap 'echo "Stopping!"; exit 0' TERM; echo ready; while true; do sleep 0.05; done`)
+	logs := make(chan string, 1)
+	logs <- "[Client thread/INFO]: Stopping!"
+	var stages []string
+	killed, err := Stop(cmd.Process, StopOptions{
+		Grace:          5 * time.Second,
+		Exited:         exited,
+		Logs:           logs,
+		StatusCallback: func(msg string) { stages = append(stages, msg) },
+	})
+	if err != nil || killed {
+		t.Fatalf("expected graceful stop, killed=%v err=%v", killed, err)
+	}
+	if len(stages) != 2 || stages[1] != "Stopping: saving world..." {
+		t.Errorf("unexpected stages %v", stages)
+	}
+
+	// One that ignores it is killed after the grace period
+	cmd, exited = startStoppable(t, `trap '' TERM; echo ready; while true; do sleep 0.05; done`)
+	killed, err = Stop(cmd.Process, StopOptions{Grace: 200 * time.Millisecond, Exited: exited})
+	if err != nil || !killed {
+		t.Fatalf("expected forced stop, killed=%v err=%v", killed, err)
+	}
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("process survived the kill")
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package launcher
+
+import (
+	"os"
+	"syscall"
+)
+
+// terminate sends SIGTERM, which runs the JVM's shutdown hooks
+func terminate(p *os.Process) error {
+	return p.Signal(syscall.SIGTERM)
+}
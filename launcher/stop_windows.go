@@ -0,0 +1,17 @@
+//go:build windows
+
+package launcher
+
+import (
+	"os"
+	"os/exec"
+	"strconv"
+)
+
+// terminate asks the game window to close (taskkill without /F posts
+// WM_CLOSE), the closest Windows has to SIGTERM
+func terminate(p *os.Process) error {
+	cmd := exec.Command("taskkill", "/PID", strconv.Itoa(p.Pid))
+	hideConsole(cmd)
+	return cmd.Run()
+}